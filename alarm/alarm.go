@@ -9,6 +9,7 @@ const (
 	ALARM_API_PATH        = "/alarm/alarms"
 	ALARM_TYPE            = "application/vnd.com.nsn.cumulocity.alarm+json"
 	ALARM_COLLECTION_TYPE = "application/vnd.com.nsn.cumulocity.alarmCollection+json"
+	ALARM_COUNT_TYPE      = "text/plain"
 )
 
 type Status string
@@ -19,6 +20,24 @@ const (
 	CLEARED      Status = "CLEARED"
 )
 
+// Returns whether the status is one of ACTIVE, ACKNOWLEDGED and CLEARED.
+func (status Status) IsValid() bool {
+	return status == ACTIVE || status == ACKNOWLEDGED || status == CLEARED
+}
+
+// Returns whether an alarm with this status may be changed to the target status.
+// Active and acknowledged alarms may change into each other or be cleared, cleared alarms are final.
+func (status Status) CanTransitionTo(target Status) bool {
+	switch status {
+	case ACTIVE:
+		return target == ACKNOWLEDGED || target == CLEARED
+	case ACKNOWLEDGED:
+		return target == ACTIVE || target == CLEARED
+	default:
+		return false
+	}
+}
+
 type Severity string
 
 const (
@@ -67,6 +86,7 @@ type Alarm struct {
 
 	Count               int        `json:"count,omitempty"`
 	FirstOccurrenceTime *time.Time `json:"firstOccurrenceTime,omitempty"`
+	LastUpdated         *time.Time `json:"lastUpdated,omitempty"`
 
 	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
@@ -1,12 +1,13 @@
 package alarm
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/tarent/gomulocity/generic"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 type AlarmApi interface {
@@ -19,9 +20,22 @@ type AlarmApi interface {
 	// Updates an exiting alarm and returns the updated alarm entity.
 	Update(alarmId string, alarm *UpdateAlarm) (*Alarm, *generic.Error)
 
-	// Updates status of many alarms.
+	// Updates status of many alarms. Returns an error for an unknown status.
 	BulkStatusUpdate(query *UpdateAlarmsFilter, newStatus Status) *generic.Error
 
+	// Updates the status of many alarms after validating the filter and returns the estimated number of affected alarms.
+	// See UpdateAlarmsFilter.Validate for the rejected filters.
+	ValidatedBulkStatusUpdate(query *UpdateAlarmsFilter, newStatus Status) (int, *generic.Error)
+
+	// Acknowledges an active alarm. Returns an error, if the alarm is not active.
+	Acknowledge(alarmId string) (*Alarm, *generic.Error)
+
+	// Clears an active or acknowledged alarm. Returns an error, if the alarm is already cleared.
+	Clear(alarmId string) (*Alarm, *generic.Error)
+
+	// Reactivates an acknowledged alarm. Returns an error, if the alarm is not acknowledged.
+	Reactivate(alarmId string) (*Alarm, *generic.Error)
+
 	// Deletion by alarm id is not supported/allowed by cumulocity.
 	// Deletes alarms by filter. If error is nil, alarms were deleted successfully.
 	// ATTENTION: at least one filter should be set otherwise an error will be thrown.
@@ -38,8 +52,17 @@ type AlarmApi interface {
 
 	// Returns an alarm collection, found by the given alarm query parameters.
 	// All query parameters are AND concatenated.
+	// Deprecated: Use FindByQuery, which supports all query parameters of the platform.
 	Find(query *AlarmFilter, pageSize int) (*AlarmCollection, *generic.Error)
 
+	// Returns an alarm collection, found by the given alarm query parameters.
+	// All query parameters are AND concatenated.
+	FindByQuery(query *AlarmQuery, pageSize int) (*AlarmCollection, *generic.Error)
+
+	// Returns the number of alarms matching the given alarm query parameters without fetching them.
+	// All query parameters are AND concatenated.
+	Count(query *AlarmQuery) (int, *generic.Error)
+
 	// Gets the next page from an existing alarm collection.
 	// If there is no next page, nil is returned.
 	NextPage(c *AlarmCollection) (*AlarmCollection, *generic.Error)
@@ -118,6 +141,9 @@ func (alarmApi *alarmApi) Update(alarmId string, alarm *UpdateAlarm) (*Alarm, *g
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the update alarm: %s", err.Error()), "UpdateAlarm")
 	}
+	if len(alarm.Status) > 0 && !alarm.Status.IsValid() {
+		return nil, generic.ClientError(fmt.Sprintf("Error while updating an alarm: unknown status '%s'", alarm.Status), "UpdateAlarm")
+	}
 
 	path := fmt.Sprintf("%s/%s", alarmApi.basePath, url.QueryEscape(alarmId))
 	headers := generic.AcceptAndContentTypeHeader(ALARM_TYPE, ALARM_TYPE)
@@ -139,9 +165,12 @@ Updates the status of many alarms at once searching by filter.
 See: https://cumulocity.com/guides/reference/alarms/#put-bulk-update-of-alarm-collection
 */
 func (alarmApi *alarmApi) BulkStatusUpdate(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status) *generic.Error {
+	if !newStatus.IsValid() {
+		return generic.ClientError(fmt.Sprintf("Error while updating alarms: unknown status '%s'", newStatus), "BulkStatusUpdate")
+	}
 	alarmStatus := UpdateAlarm{Status: newStatus}
 
-	bytes, err := json.Marshal(alarmStatus)
+	bytes, err := generic.JsonFromObject(&alarmStatus)
 	if err != nil {
 		return generic.ClientError(fmt.Sprintf("Error while marshalling the update of alarms: %s", err.Error()), "BulkStatusUpdate")
 	}
@@ -172,6 +201,83 @@ func (alarmApi *alarmApi) BulkStatusUpdate(updateAlarmsFilter *UpdateAlarmsFilte
 	return nil
 }
 
+/*
+Validates the filter and updates the status of the alarms it selects. No update is sent, if no alarm is affected.
+
+Returns the number of affected alarms as an estimate: The platform does not report the number of updated alarms,
+so they are counted right before the update. Alarms created or changed between counting and updating are missing
+in or wrongly added to the count.
+
+See: https://cumulocity.com/guides/reference/alarms/#put-bulk-update-of-alarm-collection
+*/
+func (alarmApi *alarmApi) ValidatedBulkStatusUpdate(updateAlarmsFilter *UpdateAlarmsFilter, newStatus Status) (int, *generic.Error) {
+	if updateAlarmsFilter == nil {
+		return 0, generic.ClientError("Error while validating the update of alarms: the filter must not be nil", "ValidatedBulkStatusUpdate")
+	}
+	if err := updateAlarmsFilter.Validate(newStatus); err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while validating the update of alarms: %s", err.Error()), "ValidatedBulkStatusUpdate")
+	}
+
+	count, genErr := alarmApi.Count(updateAlarmsFilter.query())
+	if genErr != nil {
+		return 0, genErr
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	if genErr := alarmApi.BulkStatusUpdate(updateAlarmsFilter, newStatus); genErr != nil {
+		return 0, genErr
+	}
+	return count, nil
+}
+
+/*
+Acknowledges an active alarm.
+
+See: https://cumulocity.com/guides/reference/alarms/#update-an-alarm
+*/
+func (alarmApi *alarmApi) Acknowledge(alarmId string) (*Alarm, *generic.Error) {
+	return alarmApi.transition(alarmId, ACKNOWLEDGED, "AcknowledgeAlarm")
+}
+
+/*
+Clears an active or acknowledged alarm.
+
+See: https://cumulocity.com/guides/reference/alarms/#update-an-alarm
+*/
+func (alarmApi *alarmApi) Clear(alarmId string) (*Alarm, *generic.Error) {
+	return alarmApi.transition(alarmId, CLEARED, "ClearAlarm")
+}
+
+/*
+Reactivates an acknowledged alarm.
+
+See: https://cumulocity.com/guides/reference/alarms/#update-an-alarm
+*/
+func (alarmApi *alarmApi) Reactivate(alarmId string) (*Alarm, *generic.Error) {
+	return alarmApi.transition(alarmId, ACTIVE, "ReactivateAlarm")
+}
+
+func (alarmApi *alarmApi) transition(alarmId string, target Status, info string) (*Alarm, *generic.Error) {
+	if len(alarmId) == 0 {
+		return nil, generic.ClientError("Changing the status of an alarm without alarmId is not allowed", info)
+	}
+
+	current, genErr := alarmApi.Get(alarmId)
+	if genErr != nil {
+		return nil, genErr
+	}
+	if current == nil {
+		return nil, generic.ClientError(fmt.Sprintf("Alarm %s does not exist", alarmId), info)
+	}
+	if !current.Status.CanTransitionTo(target) {
+		return nil, generic.ClientError(fmt.Sprintf("Alarm %s with status %s can not be changed to %s", alarmId, current.Status, target), info)
+	}
+
+	return alarmApi.Update(alarmId, &UpdateAlarm{Status: target})
+}
+
 /*
 Deletes alarms by filter.
 
@@ -220,7 +326,7 @@ func (alarmApi *alarmApi) DeleteAll() *generic.Error {
 }
 
 func (alarmApi *alarmApi) GetForDevice(sourceId string, pageSize int) (*AlarmCollection, *generic.Error) {
-	return alarmApi.Find(&AlarmFilter{SourceId: sourceId}, pageSize)
+	return alarmApi.FindByQuery(&AlarmQuery{SourceId: sourceId}, pageSize)
 }
 
 func (alarmApi *alarmApi) Find(alarmFilter *AlarmFilter, pageSize int) (*AlarmCollection, *generic.Error) {
@@ -238,6 +344,60 @@ func (alarmApi *alarmApi) Find(alarmFilter *AlarmFilter, pageSize int) (*AlarmCo
 	return alarmApi.getCommon(fmt.Sprintf("%s?%s", alarmApi.basePath, queryParamsValues.Encode()))
 }
 
+func (alarmApi *alarmApi) FindByQuery(alarmQuery *AlarmQuery, pageSize int) (*AlarmCollection, *generic.Error) {
+	if alarmQuery == nil {
+		alarmQuery = &AlarmQuery{}
+	}
+	queryParamsValues := &url.Values{}
+	err := alarmQuery.QueryParams(queryParamsValues)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while building query parameters to search for alarms: %s", err.Error()), "FindAlarms")
+	}
+
+	err = generic.PageSizeParameter(pageSize, queryParamsValues)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while building pageSize parameter to fetch alarms: %s", err.Error()), "FindAlarms")
+	}
+
+	return alarmApi.getCommon(fmt.Sprintf("%s?%s", alarmApi.basePath, queryParamsValues.Encode()))
+}
+
+/*
+Counts the alarms matching the query.
+
+See: https://cumulocity.com/guides/reference/alarms/#alarm-api
+*/
+func (alarmApi *alarmApi) Count(alarmQuery *AlarmQuery) (int, *generic.Error) {
+	if alarmQuery == nil {
+		alarmQuery = &AlarmQuery{}
+	}
+	queryParamsValues := &url.Values{}
+	err := alarmQuery.QueryParams(queryParamsValues)
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while building query parameters to count alarms: %s", err.Error()), "CountAlarms")
+	}
+
+	path := fmt.Sprintf("%s/count", alarmApi.basePath)
+	if len(*queryParamsValues) > 0 {
+		path = fmt.Sprintf("%s?%s", path, queryParamsValues.Encode())
+	}
+
+	body, status, err := alarmApi.client.Get(path, generic.AcceptHeader(ALARM_COUNT_TYPE))
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while counting alarms: %s", err.Error()), "CountAlarms")
+	}
+	if status != http.StatusOK {
+		return 0, generic.CreateErrorFromResponse(body, status)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil {
+		return 0, generic.ClientError(fmt.Sprintf("Error while parsing alarm count [%s]: %s", body, err.Error()), "CountAlarms")
+	}
+
+	return count, nil
+}
+
 func (alarmApi *alarmApi) NextPage(c *AlarmCollection) (*AlarmCollection, *generic.Error) {
 	return alarmApi.getPage(c.Next)
 }
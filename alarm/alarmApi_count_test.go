@@ -0,0 +1,88 @@
+package alarm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAlarmApi_Count(t *testing.T) {
+	// given: A test server
+	var capturedUrl string
+	var capturedAccept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		capturedAccept = r.Header.Get("Accept")
+		_, _ = w.Write([]byte("42"))
+	}))
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildAlarmApi(ts.URL)
+
+	tests := []struct {
+		name        string
+		query       *AlarmQuery
+		expectedUrl string
+	}{
+		{"NoQuery", nil, "/alarm/alarms/count"},
+		{"EmptyQuery", &AlarmQuery{}, "/alarm/alarms/count"},
+		{
+			"ActiveCritical",
+			&AlarmQuery{Status: []Status{ACTIVE}, Severity: CRITICAL, SourceId: deviceId},
+			"/alarm/alarms/count?severity=CRITICAL&source=1111111&status=ACTIVE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := api.Count(tt.query)
+
+			if err != nil {
+				t.Fatalf("Count() got an unexpected error: %s", err.Error())
+			}
+			if count != 42 {
+				t.Errorf("Count() = %d, want %d", count, 42)
+			}
+			if capturedUrl != tt.expectedUrl {
+				t.Errorf("Count() url = %s, want %s", capturedUrl, tt.expectedUrl)
+			}
+			if capturedAccept != ALARM_COUNT_TYPE {
+				t.Errorf("Count() accept header = %s, want %s", capturedAccept, ALARM_COUNT_TYPE)
+			}
+		})
+	}
+}
+
+func TestAlarmApi_Count_InvalidQuery(t *testing.T) {
+	api := buildAlarmApi("test.url")
+
+	_, err := api.Count(&AlarmQuery{WithSourceDevices: true})
+
+	if err == nil || !strings.Contains(err.Message, "when 'WithSourceDevices' parameter is defined also SourceID must be set") {
+		t.Errorf("Count() error = %v, want invalid query error", err)
+	}
+}
+
+func TestAlarmApi_Count_InvalidResponse(t *testing.T) {
+	ts := buildHttpServer(200, "not a number")
+	defer ts.Close()
+
+	_, err := buildAlarmApi(ts.URL).Count(nil)
+
+	if err == nil || !strings.Contains(err.Message, "Error while parsing alarm count") {
+		t.Errorf("Count() error = %v, want parse error", err)
+	}
+}
+
+func TestAlarmApi_Count_ReturnsError(t *testing.T) {
+	ts := buildHttpServer(403, `{"error": "security/Forbidden", "message": "Access is denied"}`)
+	defer ts.Close()
+
+	_, err := buildAlarmApi(ts.URL).Count(nil)
+
+	if err == nil || err.ErrorType != "403: security/Forbidden" {
+		t.Errorf("Count() error = %v, want 403 error", err)
+	}
+}
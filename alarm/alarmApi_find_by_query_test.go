@@ -0,0 +1,125 @@
+package alarm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	url "net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlarmApi_FindByQuery(t *testing.T) {
+	var capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		_, _ = w.Write([]byte(fmt.Sprintf(alarmCollectionTemplate, alarm)))
+	}))
+	defer ts.Close()
+
+	dateFrom, _ := time.Parse(time.RFC3339, "2020-06-01T01:00:00.00Z")
+	dateTo, _ := time.Parse(time.RFC3339, "2020-06-30T01:00:00.00Z")
+
+	tests := []struct {
+		name          string
+		query         *AlarmQuery
+		expectedQuery string
+	}{
+		{
+			"Nil",
+			nil,
+			"pageSize=1",
+		},
+		{
+			"Status",
+			&AlarmQuery{Status: []Status{ACTIVE, ACKNOWLEDGED}},
+			"pageSize=1&status=ACTIVE%2CACKNOWLEDGED",
+		},
+		{
+			"Types",
+			&AlarmQuery{Type: []string{"testAlarm", "otherAlarm"}},
+			"pageSize=1&type=testAlarm%2CotherAlarm",
+		},
+		{
+			"CreatedAndLastUpdated",
+			&AlarmQuery{CreatedFrom: &dateFrom, CreatedTo: &dateTo, LastUpdatedFrom: &dateFrom, LastUpdatedTo: &dateTo},
+			"createdFrom=2020-06-01T01%3A00%3A00Z&createdTo=2020-06-30T01%3A00%3A00Z&lastUpdatedFrom=2020-06-01T01%3A00%3A00Z&lastUpdatedTo=2020-06-30T01%3A00%3A00Z&pageSize=1",
+		},
+		{
+			"All",
+			&AlarmQuery{
+				SourceId:          "123",
+				WithSourceAssets:  true,
+				WithSourceDevices: true,
+				Resolved:          "false",
+				Severity:          MAJOR,
+				DateFrom:          &dateFrom,
+				DateTo:            &dateTo,
+				CreatedFrom:       &dateFrom,
+				LastUpdatedTo:     &dateTo,
+				Type:              []string{"testAlarm"},
+			},
+			"createdFrom=2020-06-01T01%3A00%3A00Z&dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&lastUpdatedTo=2020-06-30T01%3A00%3A00Z&pageSize=1&resolved=false&severity=MAJOR&source=123&type=testAlarm&withSourceAssets=true&withSourceDevices=true",
+		},
+	}
+
+	api := buildAlarmApi(ts.URL)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.FindByQuery(tt.query, 1)
+			if err != nil {
+				t.Fatalf("FindByQuery() got an unexpected error: %s", err.Message)
+			}
+
+			cUrl, urlErr := url.Parse(capturedUrl)
+			if urlErr != nil {
+				t.Fatalf("FindByQuery() - The captured URL is invalid - URL: %s, error: %s", capturedUrl, urlErr.Error())
+			}
+
+			if cUrl.RawQuery != tt.expectedQuery {
+				t.Errorf("FindByQuery() = %v, want %v", cUrl.RawQuery, tt.expectedQuery)
+			}
+		})
+	}
+}
+
+func TestAlarmApi_FindByQuery_WithInvalidQuery(t *testing.T) {
+	dateFrom, _ := time.Parse(time.RFC3339, "2020-06-01T01:00:00.00Z")
+	dateTo, _ := time.Parse(time.RFC3339, "2020-06-30T01:00:00.00Z")
+
+	tests := []struct {
+		name          string
+		query         AlarmQuery
+		expectedError string
+	}{
+		{
+			"Resolved",
+			AlarmQuery{Resolved: "CLEARED"},
+			"if 'Resolved' parameter is set, only 'true' and 'false' values are accepted",
+		},
+		{
+			"StatusAndResolved",
+			AlarmQuery{Status: []Status{ACTIVE}, Resolved: "true"},
+			"'Status' and 'Resolved' must not be set both",
+		},
+		{
+			"DateToBeforeDateFrom",
+			AlarmQuery{DateFrom: &dateTo, DateTo: &dateFrom},
+			"'DateTo' must not be before 'DateFrom'",
+		},
+	}
+
+	api := buildAlarmApi("test.url")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.FindByQuery(&tt.query, 1)
+
+			if err == nil || !strings.Contains(err.Message, tt.expectedError) {
+				t.Errorf("Error in FindByQuery(): [%v], expected: [%v]", err, tt.expectedError)
+			}
+		})
+	}
+}
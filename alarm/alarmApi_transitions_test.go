@@ -0,0 +1,189 @@
+package alarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Serves an alarm with the given status and records the requests and the sent status.
+func buildTransitionHttpServer(status Status, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*requests = append(*requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+
+		switch {
+		case r.URL.Path == ALARM_API_PATH+"/count":
+			_, _ = w.Write([]byte("3"))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(strings.Replace(alarm, `"status": "ACTIVE"`, fmt.Sprintf(`"status": "%s"`, status), 1)))
+		default:
+			var update UpdateAlarm
+			_ = json.Unmarshal(body, &update)
+			_, _ = w.Write([]byte(strings.Replace(alarm, `"status": "ACTIVE"`, fmt.Sprintf(`"status": "%s"`, update.Status), 1)))
+		}
+	}))
+}
+
+func TestAlarmApi_Transitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  Status
+		call     func(api AlarmApi) (*Alarm, error)
+		expected Status
+	}{
+		{"Acknowledge active", ACTIVE, func(api AlarmApi) (*Alarm, error) { return wrap(api.Acknowledge(alarmId)) }, ACKNOWLEDGED},
+		{"Clear active", ACTIVE, func(api AlarmApi) (*Alarm, error) { return wrap(api.Clear(alarmId)) }, CLEARED},
+		{"Clear acknowledged", ACKNOWLEDGED, func(api AlarmApi) (*Alarm, error) { return wrap(api.Clear(alarmId)) }, CLEARED},
+		{"Reactivate acknowledged", ACKNOWLEDGED, func(api AlarmApi) (*Alarm, error) { return wrap(api.Reactivate(alarmId)) }, ACTIVE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			ts := buildTransitionHttpServer(tt.current, &requests)
+			defer ts.Close()
+
+			updated, err := tt.call(buildAlarmApi(ts.URL))
+
+			if err != nil {
+				t.Fatalf("got an unexpected error: %s", err)
+			}
+			if updated.Status != tt.expected {
+				t.Errorf("status = %s, want %s", updated.Status, tt.expected)
+			}
+			expectedUpdate := fmt.Sprintf(`PUT /alarm/alarms/2222222 {"status":"%s"}`, tt.expected)
+			if len(requests) != 2 || requests[1] != expectedUpdate {
+				t.Errorf("requests = %v, want the update %s", requests, expectedUpdate)
+			}
+		})
+	}
+}
+
+func TestAlarmApi_Transitions_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		current Status
+		call    func(api AlarmApi) (*Alarm, error)
+	}{
+		{"Acknowledge acknowledged", ACKNOWLEDGED, func(api AlarmApi) (*Alarm, error) { return wrap(api.Acknowledge(alarmId)) }},
+		{"Acknowledge cleared", CLEARED, func(api AlarmApi) (*Alarm, error) { return wrap(api.Acknowledge(alarmId)) }},
+		{"Clear cleared", CLEARED, func(api AlarmApi) (*Alarm, error) { return wrap(api.Clear(alarmId)) }},
+		{"Reactivate active", ACTIVE, func(api AlarmApi) (*Alarm, error) { return wrap(api.Reactivate(alarmId)) }},
+		{"Reactivate cleared", CLEARED, func(api AlarmApi) (*Alarm, error) { return wrap(api.Reactivate(alarmId)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			ts := buildTransitionHttpServer(tt.current, &requests)
+			defer ts.Close()
+
+			_, err := tt.call(buildAlarmApi(ts.URL))
+
+			if err == nil || !strings.Contains(err.Error(), "can not be changed") {
+				t.Errorf("error = %v, want an invalid transition", err)
+			}
+			if len(requests) != 1 {
+				t.Errorf("requests = %v, want no update", requests)
+			}
+		})
+	}
+}
+
+func TestAlarmApi_Transitions_NotFound(t *testing.T) {
+	ts := buildHttpServer(404, "")
+	defer ts.Close()
+
+	_, err := buildAlarmApi(ts.URL).Acknowledge(alarmId)
+
+	if err == nil || !strings.Contains(err.Message, "does not exist") {
+		t.Errorf("Acknowledge() error = %v, want not found", err)
+	}
+}
+
+func TestAlarmApi_Update_UnknownStatus(t *testing.T) {
+	_, err := buildAlarmApi("http://localhost").Update(alarmId, &UpdateAlarm{Status: "DONE"})
+
+	if err == nil || !strings.Contains(err.Message, "unknown status 'DONE'") {
+		t.Errorf("Update() error = %v, want unknown status", err)
+	}
+}
+
+func TestAlarmApi_ValidatedBulkStatusUpdate(t *testing.T) {
+	var requests []string
+	ts := buildTransitionHttpServer(ACTIVE, &requests)
+	defer ts.Close()
+
+	count, err := buildAlarmApi(ts.URL).ValidatedBulkStatusUpdate(&UpdateAlarmsFilter{Status: ACTIVE, SourceId: "123"}, ACKNOWLEDGED)
+
+	if err != nil {
+		t.Fatalf("ValidatedBulkStatusUpdate() got an unexpected error: %s", err.Error())
+	}
+	if count != 3 {
+		t.Errorf("ValidatedBulkStatusUpdate() = %d, want 3", count)
+	}
+	expected := []string{
+		"GET /alarm/alarms/count?source=123&status=ACTIVE",
+		`PUT /alarm/alarms?source=123&status=ACTIVE {"status":"ACKNOWLEDGED"}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("requests = %v, want %v", requests, expected)
+	}
+}
+
+func TestAlarmApi_ValidatedBulkStatusUpdate_NoneAffected(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method)
+		_, _ = w.Write([]byte("0"))
+	}))
+	defer ts.Close()
+
+	count, err := buildAlarmApi(ts.URL).ValidatedBulkStatusUpdate(&UpdateAlarmsFilter{Resolved: "false"}, CLEARED)
+
+	if err != nil || count != 0 || len(requests) != 1 {
+		t.Errorf("ValidatedBulkStatusUpdate() = %d, %v with requests %v, want 0 without update", count, err, requests)
+	}
+}
+
+func TestAlarmApi_ValidatedBulkStatusUpdate_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    *UpdateAlarmsFilter
+		newStatus Status
+		expected  string
+	}{
+		{"Nil filter", nil, CLEARED, "the filter must not be nil"},
+		{"Status and resolved", &UpdateAlarmsFilter{Status: ACTIVE, Resolved: "false"}, CLEARED, "'Status' and 'Resolved' must not be set both"},
+		{"Empty filter", &UpdateAlarmsFilter{SourceId: "123"}, CLEARED, "cleared alarms can not be changed"},
+		{"Resolved alarms", &UpdateAlarmsFilter{Resolved: "true"}, ACTIVE, "cleared alarms can not be changed"},
+		{"Cleared alarms", &UpdateAlarmsFilter{Status: CLEARED}, ACTIVE, "alarms with status CLEARED can not be changed to ACTIVE"},
+		{"Same status", &UpdateAlarmsFilter{Status: ACTIVE}, ACTIVE, "alarms with status ACTIVE can not be changed to ACTIVE"},
+		{"Unknown status", &UpdateAlarmsFilter{Status: ACTIVE}, "DONE", "unknown new status 'DONE'"},
+	}
+
+	api := buildAlarmApi("http://localhost")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.ValidatedBulkStatusUpdate(tt.filter, tt.newStatus)
+
+			if err == nil || !strings.Contains(err.Message, tt.expected) {
+				t.Errorf("ValidatedBulkStatusUpdate() error = %v, want %s", err, tt.expected)
+			}
+		})
+	}
+}
+
+// Avoids a non-nil error interface holding a nil *generic.Error.
+func wrap(a *Alarm, err *generic.Error) (*Alarm, error) {
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
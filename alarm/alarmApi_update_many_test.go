@@ -84,3 +84,21 @@ func TestAlarmApi_BulkStatusUpdate_Alarm_BadRequest(t *testing.T) {
 		return
 	}
 }
+
+func TestAlarmApi_BulkStatusUpdate_UnknownStatus(t *testing.T) {
+	// given: A test server
+	ts := updateAlarmHttpServer(200)
+	defer ts.Close()
+	requestCapture = nil
+
+	// when
+	err := buildAlarmApi(ts.URL).BulkStatusUpdate(updateAlarmsFilter, Status("RESOLVED"))
+
+	// then
+	if err == nil || err.Message != "Error while updating alarms: unknown status 'RESOLVED'" {
+		t.Errorf("BulkStatusUpdate() got an unexpected error: %v", err)
+	}
+	if requestCapture != nil {
+		t.Errorf("BulkStatusUpdate() sent a request with an unknown status")
+	}
+}
@@ -130,3 +130,13 @@ func TestAlarmApi_Update_Alarm_BadRequest(t *testing.T) {
 		return
 	}
 }
+
+func TestAlarmApi_Update_Alarm_Nil(t *testing.T) {
+	api := buildAlarmApi("http://localhost")
+
+	_, err := api.Update(alarmId, nil)
+
+	if err == nil {
+		t.Errorf("UpdateAlarm() expected error on nil alarm")
+	}
+}
@@ -137,3 +137,44 @@ func (updateAlarmsFilter UpdateAlarmsFilter) QueryParams(params *url.Values) (er
 
 	return nil
 }
+
+// Validates the filter for a bulk status update to the given status. The filter is rejected, when
+// - 'Status' and 'Resolved' are set both, since it is ambiguous which one is applied,
+// - it selects cleared alarms, which can not be changed, i.e. neither 'Status' nor 'Resolved=false' is set,
+// - the alarms of the filtered status can not be changed into the new status.
+func (updateAlarmsFilter UpdateAlarmsFilter) Validate(newStatus Status) error {
+	if !newStatus.IsValid() {
+		return fmt.Errorf("invalid filter: unknown new status '%s'", newStatus)
+	}
+
+	if len(updateAlarmsFilter.Status) > 0 {
+		if len(updateAlarmsFilter.Resolved) > 0 {
+			return fmt.Errorf("invalid filter: 'Status' and 'Resolved' must not be set both")
+		}
+		if !updateAlarmsFilter.Status.CanTransitionTo(newStatus) {
+			return fmt.Errorf("invalid filter: alarms with status %s can not be changed to %s", updateAlarmsFilter.Status, newStatus)
+		}
+		return nil
+	}
+
+	resolved, err := strconv.ParseBool(updateAlarmsFilter.Resolved)
+	if err != nil || resolved {
+		return fmt.Errorf("invalid filter: either 'Status' must be set or 'Resolved' must be false, cleared alarms can not be changed")
+	}
+	return nil
+}
+
+// Returns the query counting the alarms selected by the filter.
+func (updateAlarmsFilter UpdateAlarmsFilter) query() *AlarmQuery {
+	query := &AlarmQuery{
+		SourceId: updateAlarmsFilter.SourceId,
+		Resolved: updateAlarmsFilter.Resolved,
+		Severity: updateAlarmsFilter.Severity,
+		DateFrom: updateAlarmsFilter.DateFrom,
+		DateTo:   updateAlarmsFilter.DateTo,
+	}
+	if len(updateAlarmsFilter.Status) > 0 {
+		query.Status = []Status{updateAlarmsFilter.Status}
+	}
+	return query
+}
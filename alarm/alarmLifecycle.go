@@ -0,0 +1,247 @@
+package alarm
+
+import (
+	"github.com/tarent/gomulocity/generic"
+	"sort"
+	"sync"
+	"time"
+)
+
+const lifecyclePageSize = 100
+
+/*
+AlarmLifecycle raises and clears alarms by type and source and keeps an in-memory view
+of the alarms it knows to be raised.
+
+Raising is idempotent: an alarm which is already raised with the same severity and text
+is not sent again. Known alarms are read from the platform first, so that an alarm cleared
+by someone else is raised again. Otherwise the platform deduplicates alarms by type and source
+itself, i.e. creating an alarm for an existing active alarm just increments its count.
+It is safe for concurrent use. The local view is not locked during requests to the platform.
+*/
+type AlarmLifecycle struct {
+	api   AlarmApi
+	now   func() time.Time
+	mutex sync.Mutex
+	// source id -> alarm type -> alarm
+	raised map[string]map[string]Alarm
+}
+
+// Creates a new alarm lifecycle helper with an empty view.
+// api - The alarm api used to create, update and find alarms.
+func NewAlarmLifecycle(api AlarmApi) *AlarmLifecycle {
+	return &AlarmLifecycle{
+		api:    api,
+		now:    time.Now,
+		raised: map[string]map[string]Alarm{},
+	}
+}
+
+/*
+Ensures that an alarm of the given type is raised for the source.
+
+Nothing is created or updated, if the alarm is still raised on the platform with the same severity and text.
+An alarm raised with a different severity or text is updated. An alarm of the local view, which was cleared
+or deleted on the platform in the meantime, is created again.
+Returns the raised alarm.
+*/
+func (l *AlarmLifecycle) Raise(sourceId string, alarmType string, severity Severity, text string) (*Alarm, *generic.Error) {
+	if len(sourceId) == 0 || len(alarmType) == 0 {
+		return nil, generic.ClientError("sourceId and alarmType must not be empty", "RaiseAlarm")
+	}
+
+	if existing, ok := l.lookup(sourceId, alarmType); ok {
+		current, err := l.api.Get(existing.Id)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.Status != CLEARED {
+			if current.Severity == severity && current.Text == text {
+				l.store(*current)
+				return current, nil
+			}
+			return l.update(*current, severity, text)
+		}
+		l.drop(sourceId, alarmType)
+	}
+
+	created, err := l.api.Create(&NewAlarm{
+		Type:     alarmType,
+		Time:     l.now(),
+		Text:     text,
+		Source:   Source{Id: sourceId},
+		Status:   ACTIVE,
+		Severity: severity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A deduplicated alarm keeps severity and text of the first occurrence.
+	if created.Severity != severity || created.Text != text {
+		return l.update(*created, severity, text)
+	}
+
+	l.store(*created)
+	return created, nil
+}
+
+/*
+Clears the alarm of the given type for the source.
+
+Alarms which are not in the local view are searched on the platform, so that alarms
+raised by a previous run are cleared as well. Clearing an alarm which is not raised is no error.
+*/
+func (l *AlarmLifecycle) Clear(sourceId string, alarmType string) *generic.Error {
+	if len(sourceId) == 0 || len(alarmType) == 0 {
+		return generic.ClientError("sourceId and alarmType must not be empty", "ClearAlarm")
+	}
+
+	if existing, ok := l.lookup(sourceId, alarmType); ok {
+		if _, err := l.api.Update(existing.Id, &UpdateAlarm{Status: CLEARED}); err != nil {
+			return err
+		}
+		l.drop(sourceId, alarmType)
+		return nil
+	}
+
+	alarms, err := l.find(&AlarmQuery{SourceId: sourceId, Type: []string{alarmType}, Resolved: "false"})
+	if err != nil {
+		return err
+	}
+	for _, alarm := range alarms {
+		if _, err := l.api.Update(alarm.Id, &UpdateAlarm{Status: CLEARED}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replaces the local view of the source with its active and acknowledged alarms on the platform.
+func (l *AlarmLifecycle) Sync(sourceId string) *generic.Error {
+	if len(sourceId) == 0 {
+		return generic.ClientError("sourceId must not be empty", "SyncAlarms")
+	}
+
+	alarms, err := l.find(&AlarmQuery{SourceId: sourceId, Resolved: "false"})
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.raised, sourceId)
+	for _, alarm := range alarms {
+		l.remember(alarm)
+	}
+	return nil
+}
+
+// Returns whether an alarm of the given type is raised for the source according to the local view.
+func (l *AlarmLifecycle) IsRaised(sourceId string, alarmType string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, ok := l.raised[sourceId][alarmType]
+	return ok
+}
+
+// Returns the alarms raised for the source according to the local view, ordered by type.
+func (l *AlarmLifecycle) Raised(sourceId string) []Alarm {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	alarms := make([]Alarm, 0, len(l.raised[sourceId]))
+	for _, alarm := range l.raised[sourceId] {
+		alarms = append(alarms, alarm)
+	}
+	sort.Slice(alarms, func(i, j int) bool {
+		return alarms[i].Type < alarms[j].Type
+	})
+	return alarms
+}
+
+// Returns the ids of all sources with raised alarms according to the local view, ordered by id.
+func (l *AlarmLifecycle) Sources() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	sources := make([]string, 0, len(l.raised))
+	for sourceId := range l.raised {
+		sources = append(sources, sourceId)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// -- internal
+
+func (l *AlarmLifecycle) update(existing Alarm, severity Severity, text string) (*Alarm, *generic.Error) {
+	updated, err := l.api.Update(existing.Id, &UpdateAlarm{Severity: severity, Text: text})
+	if err != nil {
+		return nil, err
+	}
+
+	l.store(*updated)
+	return updated, nil
+}
+
+func (l *AlarmLifecycle) lookup(sourceId string, alarmType string) (Alarm, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	alarm, ok := l.raised[sourceId][alarmType]
+	return alarm, ok
+}
+
+func (l *AlarmLifecycle) store(alarm Alarm) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.remember(alarm)
+}
+
+func (l *AlarmLifecycle) drop(sourceId string, alarmType string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.forget(sourceId, alarmType)
+}
+
+// remember and forget expect the mutex to be held.
+func (l *AlarmLifecycle) remember(alarm Alarm) {
+	if alarm.Status == CLEARED {
+		l.forget(alarm.Source.Id, alarm.Type)
+		return
+	}
+	if _, ok := l.raised[alarm.Source.Id]; !ok {
+		l.raised[alarm.Source.Id] = map[string]Alarm{}
+	}
+	l.raised[alarm.Source.Id][alarm.Type] = alarm
+}
+
+func (l *AlarmLifecycle) forget(sourceId string, alarmType string) {
+	delete(l.raised[sourceId], alarmType)
+	if len(l.raised[sourceId]) == 0 {
+		delete(l.raised, sourceId)
+	}
+}
+
+func (l *AlarmLifecycle) find(query *AlarmQuery) ([]Alarm, *generic.Error) {
+	var alarms []Alarm
+
+	collection, err := l.api.FindByQuery(query, lifecyclePageSize)
+	for ; collection != nil; collection, err = l.api.NextPage(collection) {
+		alarms = append(alarms, collection.Alarms...)
+		if len(collection.Alarms) < lifecyclePageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return alarms, nil
+}
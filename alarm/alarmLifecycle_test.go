@@ -0,0 +1,291 @@
+package alarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A minimal alarm platform which deduplicates active alarms by type and source.
+type alarmPlatform struct {
+	mutex    sync.Mutex
+	alarms   []*Alarm
+	requests []string
+}
+
+func (p *alarmPlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.requests = append(p.requests, r.Method+" "+r.URL.Path)
+
+	body, _ := ioutil.ReadAll(r.Body)
+	var response interface{}
+	status := http.StatusOK
+
+	switch {
+	case r.Method == http.MethodPost:
+		var newAlarm NewAlarm
+		_ = json.Unmarshal(body, &newAlarm)
+		if existing := p.active(newAlarm.Source.Id, newAlarm.Type); existing != nil {
+			existing.Count++
+			response = existing
+			status = http.StatusCreated
+			break
+		}
+		created := &Alarm{
+			Id:       fmt.Sprintf("%d", len(p.alarms)+1),
+			Type:     newAlarm.Type,
+			Text:     newAlarm.Text,
+			Source:   newAlarm.Source,
+			Status:   newAlarm.Status,
+			Severity: newAlarm.Severity,
+			Count:    1,
+		}
+		p.alarms = append(p.alarms, created)
+		response = created
+		status = http.StatusCreated
+	case r.Method == http.MethodPut:
+		var update UpdateAlarm
+		_ = json.Unmarshal(body, &update)
+		id := strings.TrimPrefix(r.URL.Path, ALARM_API_PATH+"/")
+		for _, a := range p.alarms {
+			if a.Id == id {
+				if len(update.Status) > 0 {
+					a.Status = update.Status
+				}
+				if len(update.Severity) > 0 {
+					a.Severity = update.Severity
+				}
+				if len(update.Text) > 0 {
+					a.Text = update.Text
+				}
+				response = a
+			}
+		}
+	case r.URL.Path != ALARM_API_PATH:
+		id := strings.TrimPrefix(r.URL.Path, ALARM_API_PATH+"/")
+		status = http.StatusNotFound
+		for _, a := range p.alarms {
+			if a.Id == id {
+				response = a
+				status = http.StatusOK
+			}
+		}
+	default:
+		found := []Alarm{}
+		for _, a := range p.alarms {
+			if a.Status != CLEARED && a.Source.Id == r.URL.Query().Get("source") &&
+				(len(r.URL.Query().Get("type")) == 0 || a.Type == r.URL.Query().Get("type")) {
+				found = append(found, *a)
+			}
+		}
+		response = AlarmCollection{Alarms: found}
+	}
+
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *alarmPlatform) active(sourceId string, alarmType string) *Alarm {
+	for _, a := range p.alarms {
+		if a.Source.Id == sourceId && a.Type == alarmType && a.Status != CLEARED {
+			return a
+		}
+	}
+	return nil
+}
+
+func buildAlarmLifecycle() (*AlarmLifecycle, *alarmPlatform, *httptest.Server) {
+	platform := &alarmPlatform{}
+	ts := httptest.NewServer(platform)
+	return NewAlarmLifecycle(buildAlarmApi(ts.URL)), platform, ts
+}
+
+func TestAlarmLifecycle_Raise_Idempotent(t *testing.T) {
+	// given: a lifecycle helper
+	lifecycle, platform, ts := buildAlarmLifecycle()
+	defer ts.Close()
+
+	// when: the same alarm is raised twice
+	first, err := lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+	if err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+	second, err := lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+	if err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+
+	// then: the alarm was created once and only re-read afterwards
+	if strings.Join(platform.requests, ", ") != "POST /alarm/alarms, GET /alarm/alarms/1" {
+		t.Errorf("Raise() sent %v, want a single creation", platform.requests)
+	}
+	if first.Id != second.Id || second.Status != ACTIVE {
+		t.Errorf("Raise() = %v, want %v", second, first)
+	}
+	if !lifecycle.IsRaised(deviceId, "c8y_Overheat") {
+		t.Errorf("IsRaised() = false, want true")
+	}
+}
+
+func TestAlarmLifecycle_Raise_UpdatesSeverity(t *testing.T) {
+	lifecycle, platform, ts := buildAlarmLifecycle()
+	defer ts.Close()
+
+	_, _ = lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+	raised, err := lifecycle.Raise(deviceId, "c8y_Overheat", CRITICAL, "Way too hot")
+
+	if err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+	if raised.Severity != CRITICAL || raised.Text != "Way too hot" {
+		t.Errorf("Raise() = %v, want a critical alarm", raised)
+	}
+	if platform.requests[2] != "PUT /alarm/alarms/1" {
+		t.Errorf("Raise() sent %v, want an update", platform.requests)
+	}
+}
+
+func TestAlarmLifecycle_Raise_DeduplicatedByPlatform(t *testing.T) {
+	// given: an alarm raised by someone else
+	platform := &alarmPlatform{alarms: []*Alarm{{Id: "7", Type: "c8y_Overheat", Source: Source{Id: deviceId}, Status: ACTIVE, Severity: MINOR, Text: "Warm", Count: 1}}}
+	ts := httptest.NewServer(platform)
+	defer ts.Close()
+	lifecycle := NewAlarmLifecycle(buildAlarmApi(ts.URL))
+
+	// when
+	raised, err := lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+
+	// then: the existing alarm is taken over and updated
+	if err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+	if raised.Id != "7" || raised.Severity != MAJOR || raised.Count != 2 {
+		t.Errorf("Raise() = %v, want the updated existing alarm", raised)
+	}
+}
+
+func TestAlarmLifecycle_Raise_ClearedOnPlatform(t *testing.T) {
+	// given: a raised alarm, which is cleared by someone else
+	lifecycle, platform, ts := buildAlarmLifecycle()
+	defer ts.Close()
+
+	_, _ = lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+	platform.alarms[0].Status = CLEARED
+
+	// when
+	raised, err := lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+
+	// then: a new alarm is raised
+	if err != nil {
+		t.Fatalf("Raise() got an unexpected error: %s", err.Error())
+	}
+	if raised.Id != "2" || raised.Status != ACTIVE || len(platform.alarms) != 2 {
+		t.Errorf("Raise() = %v, want a new active alarm", raised)
+	}
+	if alarms := lifecycle.Raised(deviceId); len(alarms) != 1 || alarms[0].Id != "2" {
+		t.Errorf("Raised() = %v, want the new alarm", alarms)
+	}
+}
+
+func TestAlarmLifecycle_Clear(t *testing.T) {
+	lifecycle, platform, ts := buildAlarmLifecycle()
+	defer ts.Close()
+
+	_, _ = lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+	_, _ = lifecycle.Raise(deviceId, "c8y_Unavailable", MINOR, "Offline")
+
+	err := lifecycle.Clear(deviceId, "c8y_Overheat")
+
+	if err != nil {
+		t.Fatalf("Clear() got an unexpected error: %s", err.Error())
+	}
+	if platform.alarms[0].Status != CLEARED || platform.alarms[1].Status != ACTIVE {
+		t.Errorf("Clear() cleared the wrong alarms: %v, %v", platform.alarms[0], platform.alarms[1])
+	}
+	if raised := lifecycle.Raised(deviceId); len(raised) != 1 || raised[0].Type != "c8y_Unavailable" {
+		t.Errorf("Raised() = %v, want only c8y_Unavailable", raised)
+	}
+}
+
+func TestAlarmLifecycle_Clear_Unknown(t *testing.T) {
+	// given: an alarm raised by a previous run
+	platform := &alarmPlatform{alarms: []*Alarm{{Id: "7", Type: "c8y_Overheat", Source: Source{Id: deviceId}, Status: ACKNOWLEDGED}}}
+	ts := httptest.NewServer(platform)
+	defer ts.Close()
+	lifecycle := NewAlarmLifecycle(buildAlarmApi(ts.URL))
+
+	// when
+	err := lifecycle.Clear(deviceId, "c8y_Overheat")
+
+	// then
+	if err != nil {
+		t.Fatalf("Clear() got an unexpected error: %s", err.Error())
+	}
+	if platform.alarms[0].Status != CLEARED {
+		t.Errorf("Clear() did not clear the alarm on the platform: %v", platform.requests)
+	}
+
+	// and: clearing again does nothing
+	if err := lifecycle.Clear(deviceId, "c8y_Overheat"); err != nil || len(platform.requests) != 3 {
+		t.Errorf("Clear() = %v, requests %v", err, platform.requests)
+	}
+}
+
+func TestAlarmLifecycle_Sync(t *testing.T) {
+	platform := &alarmPlatform{alarms: []*Alarm{
+		{Id: "7", Type: "c8y_Overheat", Source: Source{Id: deviceId}, Status: ACTIVE, Severity: MAJOR, Text: "Too hot"},
+		{Id: "8", Type: "c8y_Unavailable", Source: Source{Id: deviceId}, Status: CLEARED},
+	}}
+	ts := httptest.NewServer(platform)
+	defer ts.Close()
+	lifecycle := NewAlarmLifecycle(buildAlarmApi(ts.URL))
+
+	if err := lifecycle.Sync(deviceId); err != nil {
+		t.Fatalf("Sync() got an unexpected error: %s", err.Error())
+	}
+
+	if !lifecycle.IsRaised(deviceId, "c8y_Overheat") || lifecycle.IsRaised(deviceId, "c8y_Unavailable") {
+		t.Errorf("Raised() = %v, want only c8y_Overheat", lifecycle.Raised(deviceId))
+	}
+	if sources := lifecycle.Sources(); len(sources) != 1 || sources[0] != deviceId {
+		t.Errorf("Sources() = %v, want [%s]", sources, deviceId)
+	}
+
+	// and: raising the synced alarm only re-reads it
+	_, _ = lifecycle.Raise(deviceId, "c8y_Overheat", MAJOR, "Too hot")
+	if len(platform.requests) != 2 || platform.requests[1] != "GET /alarm/alarms/7" {
+		t.Errorf("Raise() sent %v, want no modification", platform.requests)
+	}
+}
+
+func TestAlarmLifecycle_Raise_Invalid(t *testing.T) {
+	lifecycle := NewAlarmLifecycle(buildAlarmApi("http://localhost"))
+
+	if _, err := lifecycle.Raise("", "c8y_Overheat", MAJOR, ""); err == nil {
+		t.Errorf("Raise() without source got no error")
+	}
+	if err := lifecycle.Clear(deviceId, ""); err == nil {
+		t.Errorf("Clear() without type got no error")
+	}
+}
+
+func TestAlarmLifecycle_Clear_FindError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": "security/Forbidden", "message": "Access denied"}`))
+	}))
+	defer ts.Close()
+	lifecycle := NewAlarmLifecycle(buildAlarmApi(ts.URL))
+
+	err := lifecycle.Clear(deviceId, "c8y_Overheat")
+
+	if err == nil || !strings.HasPrefix(err.ErrorType, "403") {
+		t.Errorf("Clear() error = %v, want the error of the platform", err)
+	}
+}
@@ -0,0 +1,105 @@
+package alarm
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Query parameters to search for and count alarms.
+See: https://cumulocity.com/guides/reference/alarms/#alarm-collection
+*/
+type AlarmQuery struct {
+	Status   []Status // Alarm statuses, for example ACTIVE and CLEARED.
+	SourceId string   // Source device id.
+
+	// When set to true also alarms for related source assets will be included.
+	// When this parameter is provided also source must be defined.
+	WithSourceAssets bool
+
+	// When set to true also alarms for related source devices will be included.
+	// When this parameter is provided also source must be defined.
+	WithSourceDevices bool
+
+	// When set to true only resolved alarms (the ones with status CLEARED) will be found,
+	// false means alarms with status ACTIVE or ACKNOWLEDGED.
+	// PLEASE NOTE: Resolved and Status must not be set both.
+	Resolved string
+
+	Severity        Severity   // Alarm severity, for example MINOR.
+	Type            []string   // Alarm types.
+	DateFrom        *time.Time // Start date or date and time of alarm occurrence.
+	DateTo          *time.Time // End date or date and time of alarm occurrence.
+	CreatedFrom     *time.Time // Start date or date and time of the alarm creation.
+	CreatedTo       *time.Time // End date or date and time of the alarm creation.
+	LastUpdatedFrom *time.Time // Start date or date and time of the last update of the alarm.
+	LastUpdatedTo   *time.Time // End date or date and time of the last update of the alarm.
+}
+
+// Appends the query parameters to the provided parameter values for a request.
+// When provided values is nil an error will be created
+func (alarmQuery AlarmQuery) QueryParams(params *url.Values) error {
+	if params == nil {
+		return fmt.Errorf("The provided parameter values must not be nil!")
+	}
+
+	if len(alarmQuery.Status) > 0 {
+		if len(alarmQuery.Resolved) > 0 {
+			return fmt.Errorf("failed to build query: 'Status' and 'Resolved' must not be set both.")
+		}
+
+		var statusesAsString []string
+		for _, status := range alarmQuery.Status {
+			statusesAsString = append(statusesAsString, string(status))
+		}
+		params.Add("status", strings.Join(statusesAsString, ","))
+	}
+
+	if len(alarmQuery.SourceId) > 0 {
+		params.Add("source", alarmQuery.SourceId)
+	}
+
+	if alarmQuery.WithSourceAssets {
+		if len(alarmQuery.SourceId) == 0 {
+			return fmt.Errorf("failed to build query: when 'WithSourceAssets' parameter is defined also SourceID must be set.")
+		}
+		params.Add("withSourceAssets", "true")
+	}
+
+	if alarmQuery.WithSourceDevices {
+		if len(alarmQuery.SourceId) == 0 {
+			return fmt.Errorf("failed to build query: when 'WithSourceDevices' parameter is defined also SourceID must be set.")
+		}
+		params.Add("withSourceDevices", "true")
+	}
+
+	if len(alarmQuery.Resolved) > 0 {
+		resolved, err := strconv.ParseBool(alarmQuery.Resolved)
+		if err != nil {
+			return fmt.Errorf("failed to build query: if 'Resolved' parameter is set, only 'true' and 'false' values are accepted.")
+		}
+		params.Add("resolved", strconv.FormatBool(resolved))
+	}
+
+	if len(alarmQuery.Severity) > 0 {
+		params.Add("severity", string(alarmQuery.Severity))
+	}
+
+	if len(alarmQuery.Type) > 0 {
+		params.Add("type", strings.Join(alarmQuery.Type, ","))
+	}
+
+	generic.TimeRangeParameters("dateFrom", alarmQuery.DateFrom, "dateTo", alarmQuery.DateTo, params)
+	generic.TimeRangeParameters("createdFrom", alarmQuery.CreatedFrom, "createdTo", alarmQuery.CreatedTo, params)
+	generic.TimeRangeParameters("lastUpdatedFrom", alarmQuery.LastUpdatedFrom, "lastUpdatedTo", alarmQuery.LastUpdatedTo, params)
+
+	if alarmQuery.DateFrom != nil && alarmQuery.DateTo != nil && alarmQuery.DateTo.Before(*alarmQuery.DateFrom) {
+		return fmt.Errorf("failed to build query: 'DateTo' must not be before 'DateFrom'.")
+	}
+
+	return nil
+}
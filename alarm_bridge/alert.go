@@ -0,0 +1,81 @@
+package alarm_bridge
+
+import (
+	"github.com/tarent/gomulocity/alarm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FIRING   = "firing"
+	RESOLVED = "resolved"
+)
+
+/*
+Alert is an alarm in the format of Prometheus Alertmanager.
+See: https://github.com/prometheus/alertmanager/blob/master/api/v2/openapi.yaml
+*/
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+/*
+Maps an alarm to an alert.
+
+Labels: `alertname` (alarm type), `alarm_id`, `source_id`, `source_name` and the given static labels.
+Annotations: `summary` (alarm text), `severity` (lower case), `status`, `count` and `first_occurrence`.
+The labels identify an alert in Alertmanager. Severity is an annotation, so that an escalated alarm
+updates its alert instead of firing a second one.
+A cleared alarm is resolved at the given time.
+*/
+func NewAlert(a alarm.Alarm, staticLabels map[string]string, generatorURL string, resolvedAt time.Time) Alert {
+	labels := map[string]string{}
+	for name, value := range staticLabels {
+		labels[name] = value
+	}
+	labels["alertname"] = a.Type
+	labels["alarm_id"] = a.Id
+	labels["source_id"] = a.Source.Id
+	if len(a.Source.Name) > 0 {
+		labels["source_name"] = a.Source.Name
+	}
+
+	annotations := map[string]string{
+		"summary":  a.Text,
+		"severity": strings.ToLower(string(a.Severity)),
+		"status":   string(a.Status),
+	}
+	if a.Count > 0 {
+		annotations["count"] = strconv.Itoa(a.Count)
+	}
+
+	var startsAt time.Time
+	switch {
+	case a.FirstOccurrenceTime != nil:
+		startsAt = *a.FirstOccurrenceTime
+		annotations["first_occurrence"] = a.FirstOccurrenceTime.Format(time.RFC3339)
+	case a.Time != nil:
+		startsAt = *a.Time
+	case a.CreationTime != nil:
+		startsAt = *a.CreationTime
+	}
+
+	alert := Alert{
+		Status:       FIRING,
+		Labels:       labels,
+		Annotations:  annotations,
+		StartsAt:     startsAt,
+		GeneratorURL: generatorURL,
+	}
+	if a.Status == alarm.CLEARED {
+		alert.Status = RESOLVED
+		alert.EndsAt = &resolvedAt
+	}
+	return alert
+}
@@ -0,0 +1,244 @@
+package alarm_bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/realtimenotification"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_INTERVAL = 30 * time.Second
+	// Below the default `resolve_timeout` of Alertmanager of 5 minutes.
+	DEFAULT_RESEND_INTERVAL = time.Minute
+
+	// The realtime channel of all alarms. See Bridge.RunRealtime
+	ALARMS_CHANNEL = "/alarms/*"
+
+	pageSize = 2000
+	// Alarms updated shortly before a poll may become visible only after it.
+	pollOverlap = 5 * time.Second
+)
+
+type Config struct {
+	Query        alarm.AlarmQuery  // Selects the alarms to forward, ex. by source or severity. Status, Resolved and LastUpdatedFrom are set by the bridge.
+	Interval     time.Duration     // Time between two polls. Defaults to DEFAULT_INTERVAL.
+	Labels       map[string]string // Static labels added to each alert, ex. the tenant.
+	GeneratorURL string            // Optional link added to each alert, ex. the url of the Cumulocity tenant.
+
+	// Firing alerts are sent again after this interval, even if the alarm did not change.
+	// Alertmanager resolves alerts, which are not sent again within its `resolve_timeout`.
+	// Defaults to DEFAULT_RESEND_INTERVAL, a negative interval disables resending.
+	ResendInterval time.Duration
+}
+
+/*
+Bridge watches alarms and forwards their transitions to a sink, ex. an Alertmanager.
+
+A new ACTIVE alarm and changes of severity, text or count are forwarded as firing alert,
+a CLEARED alarm as resolved alert. Acknowledging an alarm keeps the alert firing.
+*/
+type Bridge struct {
+	api    alarm.AlarmApi
+	sink   Sink
+	config Config
+	now    func() time.Time
+
+	mutex    sync.Mutex
+	lastPoll *time.Time
+	firing   map[string]*forwarded // alarm id -> last forwarded state
+}
+
+type forwarded struct {
+	alarm  alarm.Alarm
+	sentAt time.Time
+}
+
+// Creates a new bridge.
+// api - used to poll the alarms.
+// sink - receives the alerts.
+// config - selects the alarms and configures the alerts.
+func NewBridge(api alarm.AlarmApi, sink Sink, config Config) *Bridge {
+	if config.Interval <= 0 {
+		config.Interval = DEFAULT_INTERVAL
+	}
+	if config.ResendInterval == 0 {
+		config.ResendInterval = DEFAULT_RESEND_INTERVAL
+	}
+
+	return &Bridge{
+		api:    api,
+		sink:   sink,
+		config: config,
+		now:    time.Now,
+		firing: map[string]*forwarded{},
+	}
+}
+
+/*
+Polls the alarms once and forwards the transitions.
+The first poll finds all unresolved alarms, the following ones all alarms updated since the previous poll.
+*/
+func (b *Bridge) Poll(ctx context.Context) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	pollTime := b.now()
+	query := b.config.Query
+	query.Status = nil
+	if b.lastPoll == nil {
+		query.Resolved = "false"
+	} else {
+		query.Resolved = ""
+		since := b.lastPoll.Add(-pollOverlap)
+		query.LastUpdatedFrom = &since
+	}
+
+	alarms, err := b.find(&query)
+	if err != nil {
+		return err
+	}
+
+	if err := b.forward(ctx, alarms, pollTime); err != nil {
+		return err
+	}
+	b.lastPoll = &pollTime
+	return nil
+}
+
+// Polls the alarms in the configured interval until the context is done. Failed polls are logged and repeated.
+func (b *Bridge) Run(ctx context.Context) error {
+	ticker := time.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Poll(ctx); err != nil {
+			log.Printf("Error while forwarding alarms: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+Forwards the alarms of realtime notifications until the context is done or the channel is closed.
+The notifications must be received from a subscription to ALARMS_CHANNEL or the channel of single devices.
+The query of the config is not applied to notifications.
+Notifications which can not be parsed or forwarded are logged and skipped.
+*/
+func (b *Bridge) RunRealtime(ctx context.Context, notifications <-chan json.RawMessage) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message, ok := <-notifications:
+			if !ok {
+				return nil
+			}
+			if err := b.forwardNotification(ctx, message); err != nil {
+				log.Printf("Skipping realtime notification: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Forwards the transitions of the given alarms.
+func (b *Bridge) Forward(ctx context.Context, alarms ...alarm.Alarm) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.forward(ctx, alarms, b.now())
+}
+
+// -- internal
+
+func (b *Bridge) forwardNotification(ctx context.Context, message json.RawMessage) error {
+	notification, err := realtimenotification.ParseNotification(message)
+	if err != nil {
+		return err
+	}
+	if notification.RealtimeAction != "CREATE" && notification.RealtimeAction != "UPDATE" {
+		return nil
+	}
+
+	var a alarm.Alarm
+	if err := generic.ObjectFromJson(notification.Data, &a); err != nil {
+		return fmt.Errorf("error while parsing alarm: %s", err.Error())
+	}
+	return b.Forward(ctx, a)
+}
+
+func (b *Bridge) forward(ctx context.Context, alarms []alarm.Alarm, now time.Time) error {
+	var alerts []Alert
+	sent := map[string]alarm.Alarm{}
+
+	for _, a := range alarms {
+		if b.changed(a) {
+			alerts = append(alerts, NewAlert(a, b.config.Labels, b.config.GeneratorURL, now))
+			sent[a.Id] = a
+		}
+	}
+
+	if b.config.ResendInterval > 0 {
+		for id, f := range b.firing {
+			if _, ok := sent[id]; !ok && now.Sub(f.sentAt) >= b.config.ResendInterval {
+				alerts = append(alerts, NewAlert(f.alarm, b.config.Labels, b.config.GeneratorURL, now))
+				sent[id] = f.alarm
+			}
+		}
+	}
+
+	if len(alerts) == 0 {
+		return nil
+	}
+	if err := b.sink.Send(ctx, alerts); err != nil {
+		return err
+	}
+
+	for id, a := range sent {
+		if a.Status == alarm.CLEARED {
+			delete(b.firing, id)
+		} else {
+			b.firing[id] = &forwarded{alarm: a, sentAt: now}
+		}
+	}
+	return nil
+}
+
+func (b *Bridge) changed(a alarm.Alarm) bool {
+	previous, ok := b.firing[a.Id]
+	if !ok {
+		// Alarms cleared before they were seen never fired.
+		return a.Status != alarm.CLEARED
+	}
+
+	return a.Status == alarm.CLEARED ||
+		a.Severity != previous.alarm.Severity ||
+		a.Text != previous.alarm.Text ||
+		a.Count != previous.alarm.Count
+}
+
+func (b *Bridge) find(query *alarm.AlarmQuery) ([]alarm.Alarm, error) {
+	var alarms []alarm.Alarm
+
+	collection, err := b.api.FindByQuery(query, pageSize)
+	for ; collection != nil; collection, err = b.api.NextPage(collection) {
+		alarms = append(alarms, collection.Alarms...)
+		if len(collection.Alarms) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while polling alarms: %s", err.Error())
+	}
+	return alarms, nil
+}
@@ -0,0 +1,216 @@
+package alarm_bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pollTime, _ = time.Parse(time.RFC3339, "2020-06-30T08:00:00Z")
+var firstOccurrence, _ = time.Parse(time.RFC3339, "2020-06-30T07:00:00Z")
+
+var alarmTemplate = `{
+	"id": "%s",
+	"type": "c8y_Overheat",
+	"text": "Too hot",
+	"status": "%s",
+	"severity": "%s",
+	"count": %d,
+	"firstOccurrenceTime": "2020-06-30T07:00:00Z",
+	"source": {"id": "1111111", "name": "Thermometer"}
+}`
+
+func alarmJson(id string, status alarm.Status, severity alarm.Severity, count int) string {
+	return fmt.Sprintf(alarmTemplate, id, status, severity, count)
+}
+
+// Serves the given alarm collections one after another and records the queries.
+func buildAlarmServer(collections ...string) (*httptest.Server, *[]string) {
+	var queries []string
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		body := `{"alarms": []}`
+		if len(collections) > 0 {
+			body = fmt.Sprintf(`{"alarms": [%s]}`, collections[0])
+			collections = collections[1:]
+		}
+		_, _ = w.Write([]byte(body))
+	})), &queries
+}
+
+// Records the bodies posted to the alerts api.
+func buildAlertmanager(status int) (*httptest.Server, *[][]postableAlert) {
+	var posted [][]postableAlert
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alerts []postableAlert
+		if r.URL.Path == ALERTMANAGER_ALERTS_PATH {
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &alerts)
+			posted = append(posted, alerts)
+		}
+		w.WriteHeader(status)
+	})), &posted
+}
+
+func buildBridge(alarmUrl string, sink Sink, config Config) *Bridge {
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: alarmUrl, Username: "foo", Password: "bar"}
+	bridge := NewBridge(alarm.NewAlarmApi(client), sink, config)
+	bridge.now = func() time.Time { return pollTime }
+	return bridge
+}
+
+func TestBridge_Poll(t *testing.T) {
+	// given: an active alarm, which is escalated and cleared afterwards
+	alarms, queries := buildAlarmServer(
+		alarmJson("1", alarm.ACTIVE, alarm.MAJOR, 1),
+		alarmJson("1", alarm.ACTIVE, alarm.MAJOR, 1),
+		alarmJson("1", alarm.ACKNOWLEDGED, alarm.CRITICAL, 2),
+		alarmJson("1", alarm.CLEARED, alarm.CRITICAL, 2),
+	)
+	defer alarms.Close()
+	alertmanager, posted := buildAlertmanager(http.StatusOK)
+	defer alertmanager.Close()
+
+	bridge := buildBridge(alarms.URL, NewAlertmanagerSink(alertmanager.URL, nil), Config{
+		Query:  alarm.AlarmQuery{SourceId: "1111111"},
+		Labels: map[string]string{"tenant": "t42"},
+	})
+
+	// when
+	for i := 0; i < 4; i++ {
+		if err := bridge.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() got an unexpected error: %s", err)
+		}
+	}
+
+	// then: the unchanged alarm was not forwarded again
+	if len(*posted) != 3 {
+		t.Fatalf("Poll() posted %d times, want 3: %v", len(*posted), *posted)
+	}
+
+	firing := (*posted)[0][0]
+	expectedLabels := map[string]string{"alertname": "c8y_Overheat", "alarm_id": "1",
+		"source_id": "1111111", "source_name": "Thermometer", "tenant": "t42"}
+	if fmt.Sprint(firing.Labels) != fmt.Sprint(expectedLabels) {
+		t.Errorf("Labels = %v, want %v", firing.Labels, expectedLabels)
+	}
+	if firing.Annotations["severity"] != "major" || firing.Annotations["count"] != "1" || firing.Annotations["first_occurrence"] != "2020-06-30T07:00:00Z" || firing.Annotations["summary"] != "Too hot" {
+		t.Errorf("Annotations = %v", firing.Annotations)
+	}
+	if firing.StartsAt != "2020-06-30T07:00:00.000Z" || len(firing.EndsAt) > 0 {
+		t.Errorf("Alert = %v, want a firing alert since the first occurrence", firing)
+	}
+
+	// and: the escalated alarm updated the alert with the same labels
+	if escalated := (*posted)[1][0]; escalated.Annotations["severity"] != "critical" || len(escalated.EndsAt) > 0 {
+		t.Errorf("Alert = %v, want a firing critical alert", escalated)
+	} else if fmt.Sprint(escalated.Labels) != fmt.Sprint(expectedLabels) {
+		t.Errorf("Labels = %v, want %v", escalated.Labels, expectedLabels)
+	}
+	if resolved := (*posted)[2][0]; resolved.EndsAt != "2020-06-30T08:00:00.000Z" {
+		t.Errorf("Alert = %v, want a resolved alert", resolved)
+	}
+
+	// and: the first poll found the unresolved alarms, the following ones the updated
+	if (*queries)[0] != "pageSize=2000&resolved=false&source=1111111" {
+		t.Errorf("First query = %s", (*queries)[0])
+	}
+	if (*queries)[1] != "lastUpdatedFrom=2020-06-30T07%3A59%3A55Z&pageSize=2000&source=1111111" {
+		t.Errorf("Second query = %s", (*queries)[1])
+	}
+}
+
+func TestBridge_Poll_Resend(t *testing.T) {
+	alarms, _ := buildAlarmServer(alarmJson("1", alarm.ACTIVE, alarm.MAJOR, 1))
+	defer alarms.Close()
+	alertmanager, posted := buildAlertmanager(http.StatusOK)
+	defer alertmanager.Close()
+
+	bridge := buildBridge(alarms.URL, NewAlertmanagerSink(alertmanager.URL, nil), Config{ResendInterval: time.Minute})
+
+	_ = bridge.Poll(context.Background())
+	_ = bridge.Poll(context.Background())
+	bridge.now = func() time.Time { return pollTime.Add(time.Minute) }
+	_ = bridge.Poll(context.Background())
+
+	if len(*posted) != 2 || (*posted)[1][0].Labels["alarm_id"] != "1" {
+		t.Errorf("Poll() posted %v, want the alert twice", *posted)
+	}
+}
+
+func TestBridge_Poll_ResendByDefault(t *testing.T) {
+	alarms, _ := buildAlarmServer(alarmJson("1", alarm.ACTIVE, alarm.MAJOR, 1))
+	defer alarms.Close()
+	alertmanager, posted := buildAlertmanager(http.StatusOK)
+	defer alertmanager.Close()
+
+	bridge := buildBridge(alarms.URL, NewAlertmanagerSink(alertmanager.URL, nil), Config{})
+
+	_ = bridge.Poll(context.Background())
+	bridge.now = func() time.Time { return pollTime.Add(DEFAULT_RESEND_INTERVAL) }
+	_ = bridge.Poll(context.Background())
+
+	if len(*posted) != 2 {
+		t.Errorf("Poll() posted %v, want the alert twice", *posted)
+	}
+}
+
+func TestBridge_Poll_SinkFails(t *testing.T) {
+	alarms, _ := buildAlarmServer(alarmJson("1", alarm.ACTIVE, alarm.MAJOR, 1), alarmJson("1", alarm.ACTIVE, alarm.MAJOR, 1))
+	defer alarms.Close()
+	alertmanager, _ := buildAlertmanager(http.StatusBadRequest)
+	defer alertmanager.Close()
+
+	bridge := buildBridge(alarms.URL, NewAlertmanagerSink(alertmanager.URL, nil), Config{})
+
+	err := bridge.Poll(context.Background())
+
+	if err == nil || !strings.Contains(err.Error(), "failed with status 400") {
+		t.Errorf("Poll() error = %v, want status 400", err)
+	}
+	if bridge.lastPoll != nil || len(bridge.firing) != 0 {
+		t.Errorf("Poll() remembered alarms which were not forwarded")
+	}
+}
+
+func TestBridge_RunRealtime_Webhook(t *testing.T) {
+	// given: a generic webhook
+	var messages []WebhookMessage
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message WebhookMessage
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &message)
+		messages = append(messages, message)
+	}))
+	defer webhook.Close()
+
+	bridge := buildBridge("http://localhost", NewWebhookSink(webhook.URL, nil), Config{})
+
+	notifications := make(chan json.RawMessage, 3)
+	notifications <- json.RawMessage(`{"realtimeAction":"CREATE","data":` + alarmJson("1", alarm.ACTIVE, alarm.MINOR, 1) + `}`)
+	notifications <- json.RawMessage(`{"realtimeAction":"UPDATE","data":` + alarmJson("1", alarm.CLEARED, alarm.MINOR, 1) + `}`)
+	notifications <- json.RawMessage(`{"realtimeAction":"DELETE","data":"1"}`)
+	close(notifications)
+
+	// when
+	err := bridge.RunRealtime(context.Background(), notifications)
+
+	// then
+	if err != nil {
+		t.Fatalf("RunRealtime() got an unexpected error: %s", err)
+	}
+	if len(messages) != 2 || messages[0].Status != FIRING || messages[1].Status != RESOLVED {
+		t.Fatalf("RunRealtime() sent %v, want a firing and a resolved message", messages)
+	}
+	if messages[1].Alerts[0].Annotations["severity"] != "minor" || !messages[0].Alerts[0].StartsAt.Equal(firstOccurrence) {
+		t.Errorf("Alert = %v", messages[1].Alerts[0])
+	}
+}
@@ -0,0 +1,139 @@
+package alarm_bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	ALERTMANAGER_ALERTS_PATH = "/api/v2/alerts"
+	WEBHOOK_RECEIVER         = "gomulocity"
+)
+
+// Sink receives the alerts forwarded by a bridge.
+type Sink interface {
+	Send(ctx context.Context, alerts []Alert) error
+}
+
+type alertmanagerSink struct {
+	httpClient *http.Client
+	url        string
+}
+
+type postableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Creates a sink posting to the alerts api of an Alertmanager.
+// baseURL - The url of the Alertmanager, ex. "http://alertmanager:9093"
+// httpClient - The http client to use. If nil, http.DefaultClient is used.
+func NewAlertmanagerSink(baseURL string, httpClient *http.Client) Sink {
+	return &alertmanagerSink{
+		httpClient: orDefault(httpClient),
+		url:        strings.TrimSuffix(baseURL, "/") + ALERTMANAGER_ALERTS_PATH,
+	}
+}
+
+func (s *alertmanagerSink) Send(ctx context.Context, alerts []Alert) error {
+	postable := make([]postableAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		p := postableAlert{
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			GeneratorURL: alert.GeneratorURL,
+		}
+		if !alert.StartsAt.IsZero() {
+			p.StartsAt = alert.StartsAt.Format(timeFormat)
+		}
+		if alert.EndsAt != nil {
+			p.EndsAt = alert.EndsAt.Format(timeFormat)
+		}
+		postable = append(postable, p)
+	}
+
+	return post(ctx, s.httpClient, s.url, postable)
+}
+
+type webhookSink struct {
+	httpClient *http.Client
+	url        string
+}
+
+/*
+WebhookMessage is the body posted by a webhook sink. It follows the format of the webhooks sent by Alertmanager.
+See: https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+*/
+type WebhookMessage struct {
+	Version  string  `json:"version"`
+	Receiver string  `json:"receiver"`
+	Status   string  `json:"status"` // FIRING, if at least one alert is firing.
+	Alerts   []Alert `json:"alerts"`
+}
+
+// Creates a sink posting a WebhookMessage to any url.
+// url - The url of the webhook.
+// httpClient - The http client to use. If nil, http.DefaultClient is used.
+func NewWebhookSink(url string, httpClient *http.Client) Sink {
+	return &webhookSink{httpClient: orDefault(httpClient), url: url}
+}
+
+func (s *webhookSink) Send(ctx context.Context, alerts []Alert) error {
+	message := WebhookMessage{
+		Version:  "4",
+		Receiver: WEBHOOK_RECEIVER,
+		Status:   RESOLVED,
+		Alerts:   alerts,
+	}
+	for _, alert := range alerts {
+		if alert.Status == FIRING {
+			message.Status = FIRING
+		}
+	}
+
+	return post(ctx, s.httpClient, s.url, message)
+}
+
+// -- internal
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+func orDefault(httpClient *http.Client) *http.Client {
+	if httpClient == nil {
+		return http.DefaultClient
+	}
+	return httpClient
+}
+
+func post(ctx context.Context, httpClient *http.Client, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error while marshalling alerts: %s", err.Error())
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %s", err.Error())
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error while posting alerts to %s: %s", url, err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("posting alerts to %s failed with status %d: %s", url, response.StatusCode, string(responseBody))
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package alarm_escalation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/audit"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_INTERVAL = time.Minute
+
+	AUDIT_TYPE     = "Alarm"
+	AUDIT_SEVERITY = "information"
+
+	ACTIVITY_ESCALATED  = "Alarm escalated"
+	ACTIVITY_RENOTIFIED = "Alarm re-notified"
+	ACTIVITY_CLEARED    = "Alarm cleared"
+
+	// The fragment the escalator marks alarms with: {"gomulocity_Escalation": {"severity": "MAJOR", "escalationTime": "...", "lastNotificationTime": "..."}}
+	ESCALATION_FRAGMENT = "gomulocity_Escalation"
+
+	pageSize = 2000
+)
+
+/*
+Policy defines how alarms of a type and severity are escalated.
+Empty AlarmType or Severity match all alarms. Durations which are not set disable the according action.
+*/
+type Policy struct {
+	AlarmType string
+	Severity  alarm.Severity
+
+	EscalateAfter time.Duration  // Time in ACTIVE state with this severity, after which the severity is raised.
+	EscalateTo    alarm.Severity // The raised severity.
+
+	RenotifyEvery time.Duration // Interval in which still ACTIVE alarms are notified again.
+
+	ClearAcknowledgedAfter time.Duration // Time in ACKNOWLEDGED state, after which the alarm is cleared.
+}
+
+func (p *Policy) matches(a alarm.Alarm) bool {
+	return (len(p.AlarmType) == 0 || p.AlarmType == a.Type) &&
+		(len(p.Severity) == 0 || p.Severity == a.Severity)
+}
+
+/*
+Notifier notifies about an alarm, which is still active.
+The default notifier creates the alarm again, which increments its count and triggers the notifications of the platform.
+*/
+type Notifier func(a alarm.Alarm) error
+
+type Config struct {
+	Policies []Policy         // The first matching policy applies to an alarm.
+	Query    alarm.AlarmQuery // Selects the alarms to escalate, ex. by source. Status and Resolved are set by the escalator.
+	Interval time.Duration    // Time between two checks. Defaults to DEFAULT_INTERVAL.
+	Notify   Notifier         // Optional notifier used for re-notifications.
+}
+
+/*
+Escalator periodically checks the unresolved alarms and applies the escalation policies.
+
+The escalator keeps no state of its own, so it can be restarted or run in several instances.
+Escalations and re-notifications are marked in the ESCALATION_FRAGMENT of the alarm. The time an
+alarm spent with its severity is measured from the escalation to this severity or otherwise from
+its first occurrence. The time in ACKNOWLEDGED state is measured from the latest acknowledgement
+in the audit records of the alarm, or from its last update, if there is none. All actions are
+recorded as audit records of type AUDIT_TYPE.
+*/
+type Escalator struct {
+	alarmApi alarm.AlarmApi
+	auditApi audit.AuditApi
+	config   Config
+	now      func() time.Time
+
+	mutex sync.Mutex
+}
+
+// The content of the ESCALATION_FRAGMENT.
+type escalationMarker struct {
+	Severity             alarm.Severity `json:"severity,omitempty"` // The severity the alarm was escalated to
+	EscalationTime       *time.Time     `json:"escalationTime,omitempty"`
+	LastNotificationTime *time.Time     `json:"lastNotificationTime,omitempty"`
+}
+
+type alarmState struct {
+	marker       escalationMarker
+	since        time.Time // start of the current status and severity
+	lastNotified time.Time
+}
+
+// Creates a new escalator.
+// alarmApi - used to find, update and notify alarms.
+// auditApi - used to record the actions.
+// config - the escalation policies.
+func NewEscalator(alarmApi alarm.AlarmApi, auditApi audit.AuditApi, config Config) *Escalator {
+	if config.Interval <= 0 {
+		config.Interval = DEFAULT_INTERVAL
+	}
+
+	e := &Escalator{
+		alarmApi: alarmApi,
+		auditApi: auditApi,
+		config:   config,
+		now:      time.Now,
+	}
+	if e.config.Notify == nil {
+		e.config.Notify = e.raiseAgain
+	}
+	return e
+}
+
+/*
+Checks all unresolved alarms once and applies the policies.
+Failing alarms are logged and skipped, the remaining alarms are still checked. The returned
+error counts the failed alarms and contains the first failure.
+*/
+func (e *Escalator) Check() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	query := e.config.Query
+	query.Status = nil
+	query.Resolved = "false"
+
+	alarms, err := e.find(&query)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	failed := 0
+	for _, a := range alarms {
+		if err := e.apply(a); err != nil {
+			log.Printf("Skipping alarm %s: %s", a.Id, err.Error())
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to escalate %d of %d alarms, first error: %s", failed, len(alarms), firstErr.Error())
+	}
+	return nil
+}
+
+// Checks the alarms in the configured interval until the context is done. Failed checks are logged and repeated.
+func (e *Escalator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Check(); err != nil {
+			log.Printf("Error while escalating alarms: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// -- internal
+
+func (e *Escalator) apply(a alarm.Alarm) error {
+	policy := e.policy(a)
+	if policy == nil {
+		return nil
+	}
+
+	now := e.now()
+	s := state(a)
+
+	switch a.Status {
+	case alarm.ACKNOWLEDGED:
+		if policy.ClearAcknowledgedAfter <= 0 {
+			return nil
+		}
+		acknowledged, err := e.acknowledgementTime(a)
+		if err != nil {
+			return err
+		}
+		if now.Sub(acknowledged) >= policy.ClearAcknowledgedAfter {
+			if _, err := e.alarmApi.Update(a.Id, &alarm.UpdateAlarm{Status: alarm.CLEARED}); err != nil {
+				return fmt.Errorf("error while clearing alarm %s: %s", a.Id, err.Error())
+			}
+			return e.audit(a, ACTIVITY_CLEARED,
+				fmt.Sprintf("Alarm '%s' cleared after being acknowledged for %s", a.Text, now.Sub(acknowledged)),
+				audit.Changes{Attribute: "status", PreviousValue: string(a.Status), NewValue: string(alarm.CLEARED)})
+		}
+	case alarm.ACTIVE:
+		if policy.EscalateAfter > 0 && len(policy.EscalateTo) > 0 && policy.EscalateTo != a.Severity && now.Sub(s.since) >= policy.EscalateAfter {
+			marker := escalationMarker{Severity: policy.EscalateTo, EscalationTime: &now, LastNotificationTime: &now}
+			update := &alarm.UpdateAlarm{Severity: policy.EscalateTo, AdditionalFields: map[string]interface{}{ESCALATION_FRAGMENT: marker}}
+			if _, err := e.alarmApi.Update(a.Id, update); err != nil {
+				return fmt.Errorf("error while escalating alarm %s: %s", a.Id, err.Error())
+			}
+			return e.audit(a, ACTIVITY_ESCALATED,
+				fmt.Sprintf("Alarm '%s' escalated to %s after being active for %s", a.Text, policy.EscalateTo, now.Sub(s.since)),
+				audit.Changes{Attribute: "severity", PreviousValue: string(a.Severity), NewValue: string(policy.EscalateTo)})
+		}
+
+		if policy.RenotifyEvery > 0 && now.Sub(s.lastNotified) >= policy.RenotifyEvery {
+			if err := e.config.Notify(a); err != nil {
+				return fmt.Errorf("error while notifying alarm %s: %s", a.Id, err.Error())
+			}
+			marker := s.marker
+			marker.LastNotificationTime = &now
+			update := &alarm.UpdateAlarm{AdditionalFields: map[string]interface{}{ESCALATION_FRAGMENT: marker}}
+			if _, err := e.alarmApi.Update(a.Id, update); err != nil {
+				return fmt.Errorf("error while marking the notification of alarm %s: %s", a.Id, err.Error())
+			}
+			return e.audit(a, ACTIVITY_RENOTIFIED,
+				fmt.Sprintf("Alarm '%s' notified again, active since %s", a.Text, s.since.Format(time.RFC3339)))
+		}
+	}
+	return nil
+}
+
+// Derives the state of the alarm from its times and its ESCALATION_FRAGMENT.
+func state(a alarm.Alarm) alarmState {
+	var s alarmState
+	if fragment, ok := a.AdditionalFields[ESCALATION_FRAGMENT]; ok {
+		if bytes, err := json.Marshal(fragment); err == nil {
+			_ = json.Unmarshal(bytes, &s.marker)
+		}
+	}
+
+	switch {
+	case s.marker.Severity == a.Severity && s.marker.EscalationTime != nil:
+		s.since = *s.marker.EscalationTime
+	case a.FirstOccurrenceTime != nil:
+		s.since = *a.FirstOccurrenceTime
+	case a.Time != nil:
+		s.since = *a.Time
+	}
+
+	s.lastNotified = s.since
+	if s.marker.LastNotificationTime != nil && s.marker.LastNotificationTime.After(s.since) {
+		s.lastNotified = *s.marker.LastNotificationTime
+	}
+	return s
+}
+
+/*
+Returns the time of the latest audit record, which changed the status of the alarm to ACKNOWLEDGED.
+Unlike the last update of the alarm, it is not moved by later changes of the alarm, ex. its count.
+Falls back to the last update or the time of the alarm, if there is no such audit record.
+*/
+func (e *Escalator) acknowledgementTime(a alarm.Alarm) (time.Time, error) {
+	var acknowledged time.Time
+
+	collection, err := e.auditApi.GetAuditRecords(&audit.AuditQuery{Type: AUDIT_TYPE, Source: a.Id}, pageSize)
+	for ; collection != nil; collection, err = e.auditApi.NextPage(collection) {
+		for _, record := range collection.AuditRecords {
+			for _, change := range record.Changes {
+				if change.Attribute == "status" && change.NewValue == string(alarm.ACKNOWLEDGED) && record.Time.After(acknowledged) {
+					acknowledged = record.Time
+				}
+			}
+		}
+		if len(collection.AuditRecords) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return acknowledged, fmt.Errorf("error while finding the audit records of alarm %s: %s", a.Id, err.Error())
+	}
+
+	if !acknowledged.IsZero() {
+		return acknowledged, nil
+	}
+	if a.LastUpdated != nil {
+		return *a.LastUpdated, nil
+	}
+	if a.Time != nil {
+		return *a.Time, nil
+	}
+	return acknowledged, nil
+}
+
+func (e *Escalator) policy(a alarm.Alarm) *Policy {
+	for i := range e.config.Policies {
+		if e.config.Policies[i].matches(a) {
+			return &e.config.Policies[i]
+		}
+	}
+	return nil
+}
+
+func (e *Escalator) raiseAgain(a alarm.Alarm) error {
+	_, err := e.alarmApi.Create(&alarm.NewAlarm{
+		Type:     a.Type,
+		Time:     e.now(),
+		Text:     a.Text,
+		Source:   alarm.Source{Id: a.Source.Id},
+		Status:   alarm.ACTIVE,
+		Severity: a.Severity,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Escalator) audit(a alarm.Alarm, activity string, text string, changes ...audit.Changes) error {
+	now := e.now()
+	record := &audit.AuditRecord{
+		Type:         AUDIT_TYPE,
+		Severity:     AUDIT_SEVERITY,
+		Activity:     activity,
+		Text:         text,
+		Time:         now,
+		CreationTime: now,
+		Changes:      changes,
+	}
+	record.Source.ID = a.Id
+	record.AuditSourceDevice.ID = a.Source.Id
+
+	if _, err := e.auditApi.CreateAuditRecord(record); err != nil {
+		return fmt.Errorf("error while recording '%s' of alarm %s: %s", activity, a.Id, err.Error())
+	}
+	return nil
+}
+
+func (e *Escalator) find(query *alarm.AlarmQuery) ([]alarm.Alarm, error) {
+	var alarms []alarm.Alarm
+
+	collection, err := e.alarmApi.FindByQuery(query, pageSize)
+	for ; collection != nil; collection, err = e.alarmApi.NextPage(collection) {
+		alarms = append(alarms, collection.Alarms...)
+		if len(collection.Alarms) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding alarms: %s", err.Error())
+	}
+	return alarms, nil
+}
@@ -0,0 +1,312 @@
+package alarm_escalation
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/audit"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start, _ = time.Parse(time.RFC3339, "2020-06-30T08:00:00Z")
+
+// Serves the alarms for the escalator, applies updates and records all changing requests.
+type platform struct {
+	alarms   []*alarm.Alarm
+	requests []string
+	audits   []audit.AuditRecord // created by the escalator
+	history  []audit.AuditRecord // created before, ex. by acknowledging an alarm
+	failing  string              // id of an alarm, which can not be updated
+}
+
+func (p *platform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case r.URL.Path == "/audit/auditRecords" && r.Method == http.MethodGet:
+		var records []string
+		for _, record := range append(p.history, p.audits...) {
+			if record.Source.ID == r.URL.Query().Get("source") {
+				bytes, _ := json.Marshal(record)
+				records = append(records, string(bytes))
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"auditRecords": [%s]}`, strings.Join(records, ","))
+	case r.URL.Path == "/audit/auditRecords":
+		var record audit.AuditRecord
+		_ = json.Unmarshal(body, &record)
+		p.audits = append(p.audits, record)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	case r.Method == http.MethodPost:
+		var newAlarm alarm.NewAlarm
+		_ = json.Unmarshal(body, &newAlarm)
+		p.requests = append(p.requests, "CREATE "+newAlarm.Type)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	case r.Method == http.MethodPut:
+		var update alarm.UpdateAlarm
+		_ = generic.ObjectFromJson(body, &update)
+		id := strings.TrimPrefix(r.URL.Path, alarm.ALARM_API_PATH+"/")
+		if id == p.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		request := fmt.Sprintf("UPDATE %s %s%s", id, update.Status, update.Severity)
+		if _, ok := update.AdditionalFields[ESCALATION_FRAGMENT]; ok {
+			request = strings.TrimSpace(request) + " marked"
+		}
+		p.requests = append(p.requests, request)
+		for _, a := range p.alarms {
+			if a.Id == id {
+				if len(update.Status) > 0 {
+					a.Status = update.Status
+				}
+				if len(update.Severity) > 0 {
+					a.Severity = update.Severity
+				}
+				if a.AdditionalFields == nil {
+					a.AdditionalFields = map[string]interface{}{}
+				}
+				for name, value := range update.AdditionalFields {
+					a.AdditionalFields[name] = value
+				}
+			}
+		}
+		_, _ = w.Write(body)
+	default:
+		var unresolved []string
+		for _, a := range p.alarms {
+			if a.Status != alarm.CLEARED {
+				bytes, _ := generic.JsonFromObject(a)
+				unresolved = append(unresolved, string(bytes))
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"alarms": [%s]}`, strings.Join(unresolved, ","))
+	}
+}
+
+func buildEscalator(p *platform, config Config) (*Escalator, *httptest.Server) {
+	ts := httptest.NewServer(p)
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+
+	escalator := NewEscalator(alarm.NewAlarmApi(client), audit.NewAuditApi(client), config)
+	escalator.now = func() time.Time { return start }
+	return escalator, ts
+}
+
+func check(t *testing.T, escalator *Escalator, at time.Duration) {
+	escalator.now = func() time.Time { return start.Add(at) }
+	if err := escalator.Check(); err != nil {
+		t.Fatalf("Check() got an unexpected error: %s", err)
+	}
+}
+
+func assertRequests(t *testing.T, p *platform, expected ...string) {
+	if strings.Join(p.requests, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Requests = %v, want %v", p.requests, expected)
+	}
+}
+
+func TestEscalator_Escalate(t *testing.T) {
+	// given: a new minor alarm and policies escalating it to critical
+	p := &platform{alarms: []*alarm.Alarm{
+		{Id: "1", Type: "c8y_Overheat", Status: alarm.ACTIVE, Severity: alarm.MINOR, FirstOccurrenceTime: &start, Source: alarm.Source{Id: "4711"}},
+	}}
+	escalator, ts := buildEscalator(p, Config{Policies: []Policy{
+		{AlarmType: "c8y_Overheat", Severity: alarm.MINOR, EscalateAfter: 10 * time.Minute, EscalateTo: alarm.MAJOR},
+		{AlarmType: "c8y_Overheat", Severity: alarm.MAJOR, EscalateAfter: 30 * time.Minute, EscalateTo: alarm.CRITICAL},
+	}})
+	defer ts.Close()
+
+	// when
+	check(t, escalator, 5*time.Minute)
+	check(t, escalator, 10*time.Minute)
+	check(t, escalator, 30*time.Minute)
+	check(t, escalator, 40*time.Minute)
+
+	// then: the time with the major severity is counted from the escalation
+	assertRequests(t, p, "UPDATE 1 MAJOR marked", "UPDATE 1 CRITICAL marked")
+
+	// and: the escalations were audited
+	if len(p.audits) != 2 {
+		t.Fatalf("Audit records = %v, want 2", p.audits)
+	}
+	record := p.audits[0]
+	if record.Type != AUDIT_TYPE || record.Activity != ACTIVITY_ESCALATED || record.Source.ID != "1" || record.AuditSourceDevice.ID != "4711" {
+		t.Errorf("Audit record = %v", record)
+	}
+	if len(record.Changes) != 1 || record.Changes[0].PreviousValue != "MINOR" || record.Changes[0].NewValue != "MAJOR" {
+		t.Errorf("Audit changes = %v", record.Changes)
+	}
+	if p.audits[1].Text != "Alarm '' escalated to CRITICAL after being active for 30m0s" {
+		t.Errorf("Audit text = %s, want the time since the first escalation", p.audits[1].Text)
+	}
+}
+
+func TestEscalator_Escalate_StateFromAlarm(t *testing.T) {
+	// given: an alarm escalated to major by a previous escalator
+	escalated := start.Add(time.Hour)
+	p := &platform{alarms: []*alarm.Alarm{{
+		Id: "1", Status: alarm.ACTIVE, Severity: alarm.MAJOR, FirstOccurrenceTime: &start,
+		AdditionalFields: map[string]interface{}{ESCALATION_FRAGMENT: map[string]interface{}{"severity": "MAJOR", "escalationTime": escalated.Format(time.RFC3339)}},
+	}}}
+	config := Config{Policies: []Policy{{Severity: alarm.MAJOR, EscalateAfter: 30 * time.Minute, EscalateTo: alarm.CRITICAL}}}
+
+	// when: a new escalator checks before and after the escalation period
+	escalator, ts := buildEscalator(p, config)
+	defer ts.Close()
+	check(t, escalator, 80*time.Minute)
+	assertRequests(t, p)
+
+	escalator, _ = buildEscalator(p, config)
+	check(t, escalator, 100*time.Minute)
+
+	// then: the time with the major severity is counted from the marked escalation
+	assertRequests(t, p, "UPDATE 1 CRITICAL marked")
+	if len(p.audits) != 1 || !strings.HasSuffix(p.audits[0].Text, "after being active for 40m0s") {
+		t.Errorf("Audit records = %v, want the escalation after 40 minutes", p.audits)
+	}
+}
+
+func TestEscalator_Renotify(t *testing.T) {
+	p := &platform{alarms: []*alarm.Alarm{
+		{Id: "1", Type: "c8y_Unavailable", Status: alarm.ACTIVE, Severity: alarm.MAJOR, Time: &start},
+		{Id: "2", Type: "c8y_Other", Status: alarm.ACTIVE, Severity: alarm.MAJOR, Time: &start},
+	}}
+	escalator, ts := buildEscalator(p, Config{Policies: []Policy{{AlarmType: "c8y_Unavailable", RenotifyEvery: time.Hour}}})
+	defer ts.Close()
+
+	check(t, escalator, 30*time.Minute)
+	check(t, escalator, time.Hour)
+	check(t, escalator, 90*time.Minute)
+	check(t, escalator, 2*time.Hour)
+
+	assertRequests(t, p, "CREATE c8y_Unavailable", "UPDATE 1 marked", "CREATE c8y_Unavailable", "UPDATE 1 marked")
+	if len(p.audits) != 2 || p.audits[1].Activity != ACTIVITY_RENOTIFIED {
+		t.Errorf("Audit records = %v, want 2 re-notifications", p.audits)
+	}
+}
+
+func TestEscalator_Renotify_CustomNotifier(t *testing.T) {
+	p := &platform{alarms: []*alarm.Alarm{{Id: "1", Type: "c8y_Unavailable", Status: alarm.ACTIVE, Severity: alarm.MAJOR, Time: &start}}}
+	var notified []string
+	escalator, ts := buildEscalator(p, Config{
+		Policies: []Policy{{RenotifyEvery: time.Hour}},
+		Notify: func(a alarm.Alarm) error {
+			notified = append(notified, a.Id)
+			return nil
+		},
+	})
+	defer ts.Close()
+
+	check(t, escalator, time.Hour)
+
+	assertRequests(t, p, "UPDATE 1 marked")
+	if len(notified) != 1 || notified[0] != "1" {
+		t.Errorf("Notified = %v, want [1]", notified)
+	}
+}
+
+func TestEscalator_ClearAcknowledged(t *testing.T) {
+	// given: an alarm acknowledged after an hour and updated later on
+	acknowledged := start.Add(time.Hour)
+	updated := start.Add(20 * time.Hour)
+	p := &platform{
+		alarms: []*alarm.Alarm{
+			{Id: "1", Type: "c8y_Overheat", Status: alarm.ACKNOWLEDGED, Severity: alarm.MAJOR, Time: &start, LastUpdated: &updated},
+			{Id: "2", Type: "c8y_Overheat", Status: alarm.ACTIVE, Severity: alarm.MAJOR, Time: &start},
+		},
+		history: []audit.AuditRecord{
+			auditRecord("1", start, "status", "ACTIVE"),
+			auditRecord("1", acknowledged, "status", "ACKNOWLEDGED"),
+			auditRecord("1", updated, "count", "2"),
+		},
+	}
+	escalator, ts := buildEscalator(p, Config{Policies: []Policy{{ClearAcknowledgedAfter: 24 * time.Hour}}})
+	defer ts.Close()
+
+	// when
+	check(t, escalator, 24*time.Hour)
+	assertRequests(t, p)
+
+	check(t, escalator, 25*time.Hour)
+
+	// then: the time in ACKNOWLEDGED state is counted from the acknowledgement
+	assertRequests(t, p, "UPDATE 1 CLEARED")
+	if len(p.audits) != 1 || p.audits[0].Activity != ACTIVITY_CLEARED || p.audits[0].Changes[0].NewValue != "CLEARED" {
+		t.Errorf("Audit records = %v, want a clearing", p.audits)
+	}
+	if !strings.HasSuffix(p.audits[0].Text, "after being acknowledged for 24h0m0s") {
+		t.Errorf("Audit text = %s, want the time since the acknowledgement", p.audits[0].Text)
+	}
+}
+
+func TestEscalator_ClearAcknowledged_WithoutAuditRecord(t *testing.T) {
+	updated := start.Add(time.Hour)
+	p := &platform{alarms: []*alarm.Alarm{{Id: "1", Status: alarm.ACKNOWLEDGED, Severity: alarm.MAJOR, Time: &start, LastUpdated: &updated}}}
+	escalator, ts := buildEscalator(p, Config{Policies: []Policy{{ClearAcknowledgedAfter: 24 * time.Hour}}})
+	defer ts.Close()
+
+	check(t, escalator, 24*time.Hour)
+	assertRequests(t, p)
+
+	check(t, escalator, 25*time.Hour)
+	assertRequests(t, p, "UPDATE 1 CLEARED")
+}
+
+func auditRecord(alarmId string, at time.Time, attribute string, newValue string) audit.AuditRecord {
+	record := audit.AuditRecord{Type: AUDIT_TYPE, Time: at, Changes: []audit.Changes{{Attribute: attribute, NewValue: newValue}}}
+	record.Source.ID = alarmId
+	return record
+}
+
+func TestEscalator_Check_ContinuesAfterFailingAlarm(t *testing.T) {
+	// given: two alarms to escalate, the first can not be updated
+	p := &platform{
+		alarms: []*alarm.Alarm{
+			{Id: "1", Status: alarm.ACTIVE, Severity: alarm.MINOR, Time: &start},
+			{Id: "2", Status: alarm.ACTIVE, Severity: alarm.MINOR, Time: &start},
+		},
+		failing: "1",
+	}
+	escalator, ts := buildEscalator(p, Config{Policies: []Policy{{EscalateAfter: time.Minute, EscalateTo: alarm.MAJOR}}})
+	defer ts.Close()
+	escalator.now = func() time.Time { return start.Add(time.Hour) }
+
+	// when
+	err := escalator.Check()
+
+	// then: the second alarm was escalated nevertheless
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to escalate 1 of 2 alarms, first error: error while escalating alarm 1") {
+		t.Errorf("Check() error = %v, want the failure of alarm 1", err)
+	}
+	assertRequests(t, p, "UPDATE 2 MAJOR marked")
+}
+
+func TestEscalator_Check_AuditFails(t *testing.T) {
+	p := &platform{alarms: []*alarm.Alarm{{Id: "1", Status: alarm.ACTIVE, Severity: alarm.MINOR, Time: &start}}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/audit/auditRecords" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		p.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	escalator := NewEscalator(alarm.NewAlarmApi(client), audit.NewAuditApi(client), Config{Policies: []Policy{{EscalateAfter: time.Minute, EscalateTo: alarm.MAJOR}}})
+	escalator.now = func() time.Time { return start.Add(time.Hour) }
+
+	err := escalator.Check()
+
+	if err == nil || !strings.Contains(err.Error(), "error while recording 'Alarm escalated' of alarm 1") {
+		t.Errorf("Check() error = %v, want audit error", err)
+	}
+}
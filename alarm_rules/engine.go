@@ -0,0 +1,212 @@
+package alarm_rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"github.com/tarent/gomulocity/realtimenotification"
+	"log"
+	"sync"
+	"time"
+)
+
+// The realtime channel of all created measurements. See Engine.Run
+const MEASUREMENTS_CHANNEL = "/measurements/*"
+
+/*
+Engine evaluates measurements against a set of rules and raises or clears the alarms of
+the measurement sources accordingly.
+
+Measurements of a source must be evaluated in chronological order. Measurements without
+the series of a rule leave the state of this rule untouched.
+Alarms are raised and cleared through an alarm.AlarmLifecycle, so unchanged alarms are not sent again.
+*/
+type Engine struct {
+	lifecycle *alarm.AlarmLifecycle
+	rules     []Rule
+
+	mutex  sync.Mutex
+	states map[stateKey]*state
+}
+
+type stateKey struct {
+	sourceId string
+	rule     int
+}
+
+type state struct {
+	active       int       // index of the raised threshold, -1 when no alarm is raised
+	pending      int       // index of the threshold waiting for the duration to pass
+	pendingSince time.Time // time of the first measurement crossing the pending threshold
+}
+
+// Creates a new rule engine.
+// alarmApi - used to raise and clear alarms.
+// rules - the rules to evaluate. An error is returned, when a rule is invalid.
+func NewEngine(alarmApi alarm.AlarmApi, rules ...Rule) (*Engine, error) {
+	return NewEngineWithLifecycle(alarm.NewAlarmLifecycle(alarmApi), rules...)
+}
+
+// Creates a new rule engine sharing an alarm lifecycle, ex. with other engines for the same sources.
+func NewEngineWithLifecycle(lifecycle *alarm.AlarmLifecycle, rules ...Rule) (*Engine, error) {
+	validated := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.Direction) == 0 {
+			rule.Direction = ABOVE
+		}
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		rule.sortThresholds()
+		validated = append(validated, rule)
+	}
+
+	return &Engine{
+		lifecycle: lifecycle,
+		rules:     validated,
+		states:    map[stateKey]*state{},
+	}, nil
+}
+
+// Returns the alarm lifecycle used to raise and clear alarms.
+func (e *Engine) Lifecycle() *alarm.AlarmLifecycle {
+	return e.lifecycle
+}
+
+// Evaluates a single measurement against all rules and raises or clears alarms of its source.
+func (e *Engine) Evaluate(m *measurement.Measurement) *generic.Error {
+	if m == nil || len(m.Source.Id) == 0 {
+		return generic.ClientError("The measurement must have a source", "EvaluateMeasurement")
+	}
+
+	measurementTime := time.Now()
+	if m.Time != nil {
+		measurementTime = *m.Time
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for i, rule := range e.rules {
+		valueFragment, ok := m.ValueFragment(rule.Fragment, rule.Series)
+		if !ok {
+			continue
+		}
+
+		key := stateKey{sourceId: m.Source.Id, rule: i}
+		s, ok := e.states[key]
+		if !ok {
+			s = &state{active: -1, pending: -1}
+			e.states[key] = s
+		}
+
+		if err := e.apply(m.Source.Id, rule, s, valueFragment, measurementTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluates all measurements of the iterator. The query of the iterator must not be reverted.
+func (e *Engine) EvaluateAll(it *measurement.MeasurementIterator) *generic.Error {
+	for it.Next() {
+		if err := e.Evaluate(it.Measurement()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+/*
+Evaluates the measurements of realtime notifications until the context is done or the channel is closed.
+The notifications must be received from a subscription to MEASUREMENTS_CHANNEL or the channel of
+single devices, ex.:
+
+	api, err := realtimenotification.StartRealtimeNotificationsAPI(ctx, credentials, host)
+	err = api.DoSubscribe(alarm_rules.MEASUREMENTS_CHANNEL)
+	err = engine.Run(ctx, api.ResponseFromPolling)
+
+Notifications which can not be parsed and failed alarm requests are logged and skipped.
+*/
+func (e *Engine) Run(ctx context.Context, notifications <-chan json.RawMessage) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message, ok := <-notifications:
+			if !ok {
+				return nil
+			}
+			if err := e.evaluateNotification(message); err != nil {
+				log.Printf("Skipping realtime notification: %s", err.Error())
+			}
+		}
+	}
+}
+
+// -- internal
+
+func (e *Engine) evaluateNotification(message json.RawMessage) error {
+	notification, err := realtimenotification.ParseNotification(message)
+	if err != nil {
+		return err
+	}
+	if notification.RealtimeAction != "CREATE" && notification.RealtimeAction != "UPDATE" {
+		return nil
+	}
+
+	var m measurement.Measurement
+	if err := generic.ObjectFromJson(notification.Data, &m); err != nil {
+		return fmt.Errorf("error while parsing measurement: %s", err.Error())
+	}
+
+	if err := e.Evaluate(&m); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Engine) apply(sourceId string, rule Rule, s *state, value *measurement.ValueFragment, at time.Time) *generic.Error {
+	level := rule.level(value.Value, s.active)
+
+	if level <= s.active {
+		s.pending = -1
+		if level == s.active {
+			return nil
+		}
+		return e.transition(sourceId, rule, s, level, value)
+	}
+
+	if rule.Duration > 0 {
+		if s.pending < 0 {
+			s.pendingSince = at
+		}
+		s.pending = level
+		if at.Sub(s.pendingSince) < rule.Duration {
+			return nil
+		}
+	}
+
+	s.pending = -1
+	return e.transition(sourceId, rule, s, level, value)
+}
+
+func (e *Engine) transition(sourceId string, rule Rule, s *state, level int, value *measurement.ValueFragment) *generic.Error {
+	if level < 0 {
+		if err := e.lifecycle.Clear(sourceId, rule.AlarmType); err != nil {
+			return err
+		}
+		s.active = level
+		return nil
+	}
+
+	threshold := rule.Thresholds[level]
+	if _, err := e.lifecycle.Raise(sourceId, rule.AlarmType, threshold.Severity, rule.text(value.Value, value.Unit, threshold)); err != nil {
+		return err
+	}
+	s.active = level
+	return nil
+}
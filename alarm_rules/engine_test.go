@@ -0,0 +1,228 @@
+package alarm_rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/measurement"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var deviceId = "1111111"
+var start, _ = time.Parse(time.RFC3339, "2020-06-30T08:00:00Z")
+
+// Records the alarm requests and answers them with a single alarm per type.
+type alarmServer struct {
+	mutex    sync.Mutex
+	requests []string
+	alarms   map[string]map[string]interface{} // alarm id -> alarm
+}
+
+func (s *alarmServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	var sent map[string]interface{}
+	_ = json.Unmarshal(body, &sent)
+
+	switch r.Method {
+	case http.MethodPost:
+		s.requests = append(s.requests, fmt.Sprintf("RAISE %s %s", sent["type"], sent["severity"]))
+		w.WriteHeader(http.StatusCreated)
+		sent["id"] = sent["type"]
+		s.alarms[sent["type"].(string)] = sent
+		response, _ := json.Marshal(sent)
+		_, _ = w.Write(response)
+	case http.MethodPut:
+		id := strings.TrimPrefix(r.URL.Path, alarm.ALARM_API_PATH+"/")
+		if sent["status"] == "CLEARED" {
+			s.requests = append(s.requests, "CLEAR "+id)
+		} else {
+			s.requests = append(s.requests, fmt.Sprintf("UPDATE %s %s", id, sent["severity"]))
+		}
+		sent["id"] = id
+		sent["type"] = id
+		sent["source"] = map[string]string{"id": deviceId}
+		if existing, ok := s.alarms[id]; ok {
+			for name, value := range sent {
+				existing[name] = value
+			}
+		}
+		response, _ := json.Marshal(sent)
+		_, _ = w.Write(response)
+	case http.MethodGet:
+		if r.URL.Path == alarm.ALARM_API_PATH {
+			_, _ = w.Write([]byte(`{"alarms": []}`))
+			return
+		}
+		existing, ok := s.alarms[strings.TrimPrefix(r.URL.Path, alarm.ALARM_API_PATH+"/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response, _ := json.Marshal(existing)
+		_, _ = w.Write(response)
+	default:
+		_, _ = w.Write([]byte(`{"alarms": []}`))
+	}
+}
+
+func buildEngine(t *testing.T, rules ...Rule) (*Engine, *alarmServer, *httptest.Server) {
+	server := &alarmServer{alarms: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+
+	engine, err := NewEngine(alarm.NewAlarmApi(client), rules...)
+	if err != nil {
+		t.Fatalf("NewEngine() got an unexpected error: %s", err)
+	}
+	return engine, server, ts
+}
+
+func temperature(offset time.Duration, value float64) *measurement.Measurement {
+	at := start.Add(offset)
+	return &measurement.Measurement{
+		Time:    &at,
+		Source:  measurement.Source{Id: deviceId},
+		Metrics: map[string]interface{}{"c8y_TemperatureMeasurement": map[string]interface{}{"T": map[string]interface{}{"value": value, "unit": "C"}}},
+	}
+}
+
+func evaluate(t *testing.T, engine *Engine, measurements ...*measurement.Measurement) {
+	for _, m := range measurements {
+		if err := engine.Evaluate(m); err != nil {
+			t.Fatalf("Evaluate() got an unexpected error: %s", err.Error())
+		}
+	}
+}
+
+func assertRequests(t *testing.T, server *alarmServer, expected ...string) {
+	if strings.Join(server.requests, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Requests = %v, want %v", server.requests, expected)
+	}
+}
+
+var overheat = Rule{
+	Fragment:   "c8y_TemperatureMeasurement",
+	Series:     "T",
+	AlarmType:  "c8y_Overheat",
+	Thresholds: []Threshold{{alarm.CRITICAL, 95}, {alarm.MAJOR, 80}},
+	Hysteresis: 2,
+}
+
+func TestEngine_Thresholds(t *testing.T) {
+	engine, server, ts := buildEngine(t, overheat)
+	defer ts.Close()
+
+	evaluate(t, engine,
+		temperature(0, 70),
+		temperature(1*time.Minute, 81),   // raise MAJOR
+		temperature(2*time.Minute, 85),   // no change
+		temperature(3*time.Minute, 96),   // escalate to CRITICAL
+		temperature(4*time.Minute, 94),   // within hysteresis
+		temperature(5*time.Minute, 92),   // back to MAJOR
+		temperature(6*time.Minute, 79),   // within hysteresis
+		temperature(7*time.Minute, 77.9), // clear
+	)
+
+	assertRequests(t, server,
+		"RAISE c8y_Overheat MAJOR",
+		"UPDATE c8y_Overheat CRITICAL",
+		"UPDATE c8y_Overheat MAJOR",
+		"CLEAR c8y_Overheat",
+	)
+}
+
+func TestEngine_Below(t *testing.T) {
+	rule := Rule{Fragment: "c8y_Battery", Series: "level", AlarmType: "c8y_LowBattery", Direction: BELOW,
+		Thresholds: []Threshold{{alarm.WARNING, 20}}}
+	engine, server, ts := buildEngine(t, rule)
+	defer ts.Close()
+
+	battery := func(value float64) *measurement.Measurement {
+		return &measurement.Measurement{Source: measurement.Source{Id: deviceId},
+			Metrics: map[string]interface{}{"c8y_Battery": map[string]interface{}{"level": map[string]interface{}{"value": value, "unit": "%"}}}}
+	}
+	evaluate(t, engine, battery(50), battery(15), temperature(0, 99), battery(25))
+
+	assertRequests(t, server, "RAISE c8y_LowBattery WARNING", "CLEAR c8y_LowBattery")
+}
+
+func TestEngine_Duration(t *testing.T) {
+	rule := overheat
+	rule.Duration = 5 * time.Minute
+	engine, server, ts := buildEngine(t, rule)
+	defer ts.Close()
+
+	// a short peak does not raise an alarm
+	evaluate(t, engine, temperature(0, 85), temperature(4*time.Minute, 99), temperature(5*time.Minute, 60))
+	assertRequests(t, server)
+
+	// a lasting excess does, with the severity at the end of the duration
+	evaluate(t, engine, temperature(10*time.Minute, 85), temperature(12*time.Minute, 99), temperature(15*time.Minute, 97))
+	assertRequests(t, server, "RAISE c8y_Overheat CRITICAL")
+
+	// clearing is not delayed
+	evaluate(t, engine, temperature(16*time.Minute, 50))
+	assertRequests(t, server, "RAISE c8y_Overheat CRITICAL", "CLEAR c8y_Overheat")
+}
+
+func TestEngine_Run(t *testing.T) {
+	engine, server, ts := buildEngine(t, overheat)
+	defer ts.Close()
+
+	notifications := make(chan json.RawMessage, 3)
+	notifications <- json.RawMessage(`{"realtimeAction":"CREATE","data":{"source":{"id":"1111111"},"time":"2020-06-30T08:00:00Z","c8y_TemperatureMeasurement":{"T":{"value":90,"unit":"C"}}}}`)
+	notifications <- json.RawMessage(`not json`)
+	notifications <- json.RawMessage(`{"realtimeAction":"DELETE","data":"4711"}`)
+	close(notifications)
+
+	err := engine.Run(context.Background(), notifications)
+
+	if err != nil {
+		t.Fatalf("Run() got an unexpected error: %s", err)
+	}
+	assertRequests(t, server, "RAISE c8y_Overheat MAJOR")
+}
+
+func TestEngine_Run_Cancel(t *testing.T) {
+	engine, _, ts := buildEngine(t, overheat)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := engine.Run(ctx, make(chan json.RawMessage)); err != context.Canceled {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewEngine_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"No series", Rule{Fragment: "f", AlarmType: "a", Thresholds: []Threshold{{alarm.MAJOR, 1}}}},
+		{"No thresholds", Rule{Fragment: "f", Series: "s", AlarmType: "a"}},
+		{"No severity", Rule{Fragment: "f", Series: "s", AlarmType: "a", Thresholds: []Threshold{{Value: 1}}}},
+		{"Unknown direction", Rule{Fragment: "f", Series: "s", AlarmType: "a", Direction: "UP", Thresholds: []Threshold{{alarm.MAJOR, 1}}}},
+		{"Negative hysteresis", Rule{Fragment: "f", Series: "s", AlarmType: "a", Hysteresis: -1, Thresholds: []Threshold{{alarm.MAJOR, 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEngine(nil, tt.rule); err == nil {
+				t.Errorf("NewEngine() got no error for %v", tt.rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package alarm_rules
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"sort"
+	"time"
+)
+
+type Direction string
+
+const (
+	ABOVE Direction = "ABOVE" // Alarm when the value rises above a threshold.
+	BELOW Direction = "BELOW" // Alarm when the value falls below a threshold.
+)
+
+// Threshold assigns an alarm severity to a limit of a series.
+type Threshold struct {
+	Severity alarm.Severity
+	Value    float64
+}
+
+/*
+Rule raises an alarm, when the values of a measurement series cross a threshold.
+
+Thresholds are ordered by value, the one crossed furthest determines the severity.
+A raised alarm is only lowered or cleared when the value has moved back by more than
+Hysteresis, which avoids flapping alarms around a threshold.
+When Duration is set, a threshold must be crossed for at least this long
+(measured by the time of the measurements) before an alarm is raised or escalated.
+
+	alarm_rules.Rule{
+		Fragment:   "c8y_TemperatureMeasurement",
+		Series:     "T",
+		AlarmType:  "c8y_Overheat",
+		Direction:  alarm_rules.ABOVE,
+		Thresholds: []alarm_rules.Threshold{{alarm.MAJOR, 80}, {alarm.CRITICAL, 95}},
+		Hysteresis: 2,
+		Duration:   time.Minute,
+	}
+*/
+type Rule struct {
+	Fragment   string
+	Series     string
+	AlarmType  string
+	Direction  Direction // Defaults to ABOVE.
+	Thresholds []Threshold
+	Hysteresis float64
+	Duration   time.Duration
+
+	// Optional alarm text. It is formatted with the value, the unit and the crossed threshold,
+	// ex. "Temperature %.1f %s exceeds %.1f". Defaults to a text naming fragment and series.
+	Text string
+}
+
+func (r *Rule) validate() error {
+	if len(r.Fragment) == 0 || len(r.Series) == 0 || len(r.AlarmType) == 0 {
+		return fmt.Errorf("invalid rule: 'Fragment', 'Series' and 'AlarmType' must be set")
+	}
+	if r.Direction != ABOVE && r.Direction != BELOW {
+		return fmt.Errorf("invalid rule %s: unknown direction '%s'", r.AlarmType, r.Direction)
+	}
+	if len(r.Thresholds) == 0 {
+		return fmt.Errorf("invalid rule %s: at least one threshold must be set", r.AlarmType)
+	}
+	if r.Hysteresis < 0 || r.Duration < 0 {
+		return fmt.Errorf("invalid rule %s: 'Hysteresis' and 'Duration' must not be negative", r.AlarmType)
+	}
+	for _, threshold := range r.Thresholds {
+		if len(threshold.Severity) == 0 {
+			return fmt.Errorf("invalid rule %s: threshold %v has no severity", r.AlarmType, threshold.Value)
+		}
+	}
+	return nil
+}
+
+// Orders the thresholds from the least to the most exceeded one.
+func (r *Rule) sortThresholds() {
+	thresholds := append([]Threshold{}, r.Thresholds...)
+	sort.SliceStable(thresholds, func(i, j int) bool {
+		if r.Direction == BELOW {
+			return thresholds[i].Value > thresholds[j].Value
+		}
+		return thresholds[i].Value < thresholds[j].Value
+	})
+	r.Thresholds = thresholds
+}
+
+// Returns the index of the furthest crossed threshold or -1. Thresholds up to the active one
+// are shifted by the hysteresis.
+func (r *Rule) level(value float64, active int) int {
+	level := -1
+	for i, threshold := range r.Thresholds {
+		limit := threshold.Value
+		if i <= active {
+			if r.Direction == BELOW {
+				limit += r.Hysteresis
+			} else {
+				limit -= r.Hysteresis
+			}
+		}
+
+		crossed := value > limit
+		if r.Direction == BELOW {
+			crossed = value < limit
+		}
+		if crossed {
+			level = i
+		}
+	}
+	return level
+}
+
+func (r *Rule) text(value float64, unit string, threshold Threshold) string {
+	if len(r.Text) > 0 {
+		return fmt.Sprintf(r.Text, value, unit, threshold.Value)
+	}
+
+	verb := "exceeds"
+	if r.Direction == BELOW {
+		verb = "falls below"
+	}
+	return fmt.Sprintf("%s.%s value %v %s %s threshold %v", r.Fragment, r.Series, value, unit, verb, threshold.Value)
+}
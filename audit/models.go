@@ -59,6 +59,9 @@ type AuditQuery struct {
 	Type        string
 	Application string
 	User        string
+	Source      string     // Id of the source, ex. a managed object.
+	DateFrom    *time.Time // Start date or date and time of the audit record.
+	DateTo      *time.Time // End date or date and time of the audit record.
 }
 
 func (a AuditQuery) QueryParams(params *url.Values) error {
@@ -75,6 +78,18 @@ func (a AuditQuery) QueryParams(params *url.Values) error {
 	if len(a.Application) > 0 {
 		params.Add("application", a.Application)
 	}
+	if len(a.Source) > 0 {
+		params.Add("source", a.Source)
+	}
+	if a.DateFrom != nil {
+		params.Add("dateFrom", a.DateFrom.Format(time.RFC3339))
+	}
+	if a.DateTo != nil {
+		params.Add("dateTo", a.DateTo.Format(time.RFC3339))
+	}
+	if a.DateFrom != nil && a.DateTo != nil && a.DateTo.Before(*a.DateFrom) {
+		return fmt.Errorf("failed to build query: 'DateTo' must not be before 'DateFrom'.")
+	}
 	if a.Revert {
 		params.Add("revert", "true")
 	} else {
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+)
+
+type cachedInventoryApi struct {
+	inventory.InventoryApi
+	cache *Cache
+}
+
+/*
+Returns a copy of the cached managed object or gets and caches it.
+The managed object is not cached, when it is invalidated while it is read.
+*/
+func (api *cachedInventoryApi) Get(managedObjectId string) (*inventory.ManagedObject, *generic.Error) {
+	if value, ok := api.cache.managedObjects.get(managedObjectId); ok {
+		if managedObject, err := copyManagedObject(value.(inventory.ManagedObject)); err == nil {
+			api.cache.count(true)
+			return &managedObject, nil
+		}
+	}
+	api.cache.count(false)
+
+	generation := api.cache.managedObjects.currentGeneration()
+	managedObject, err := api.InventoryApi.Get(managedObjectId)
+	if err == nil && managedObject != nil {
+		if cached, copyErr := copyManagedObject(*managedObject); copyErr == nil {
+			api.cache.managedObjects.putUnlessInvalidated(managedObjectId, cached, generation)
+		}
+	}
+	return managedObject, err
+}
+
+func (api *cachedInventoryApi) Update(managedObjectId string, managedObject *inventory.ManagedObjectUpdate) (*inventory.ManagedObject, *generic.Error) {
+	defer api.cache.managedObjects.remove(managedObjectId)
+	return api.InventoryApi.Update(managedObjectId, managedObject)
+}
+
+// Deletes the managed object and invalidates it with all its external ids, which are deleted by the platform.
+func (api *cachedInventoryApi) Delete(managedObjectId string) *generic.Error {
+	defer api.cache.InvalidateManagedObject(managedObjectId)
+	return api.InventoryApi.Delete(managedObjectId)
+}
+
+type cachedIdentityApi struct {
+	identity.IdentityAPI
+	cache *Cache
+}
+
+/*
+Returns a copy of the cached external id or gets and caches it.
+The external id is not cached, when it is invalidated while it is read.
+*/
+func (api *cachedIdentityApi) GetExternalID(externalIDType string, externalID string) (*identity.ExternalID, *generic.Error) {
+	key := externalIdKey(externalIDType, externalID)
+	if value, ok := api.cache.externalIds.get(key); ok {
+		if result, err := copyExternalId(value.(identity.ExternalID)); err == nil {
+			api.cache.count(true)
+			return &result, nil
+		}
+	}
+	api.cache.count(false)
+
+	generation := api.cache.externalIds.currentGeneration()
+	result, err := api.IdentityAPI.GetExternalID(externalIDType, externalID)
+	if err == nil && result != nil {
+		if cached, copyErr := copyExternalId(*result); copyErr == nil {
+			api.cache.externalIds.putUnlessInvalidated(key, cached, generation)
+		}
+	}
+	return result, err
+}
+
+func (api *cachedIdentityApi) DeleteExternalID(externalIDType string, externalID string) *generic.Error {
+	defer api.cache.externalIds.remove(externalIdKey(externalIDType, externalID))
+	return api.IdentityAPI.DeleteExternalID(externalIDType, externalID)
+}
+
+// Copies the managed object with all its maps and slices by a JSON round trip.
+func copyManagedObject(managedObject inventory.ManagedObject) (inventory.ManagedObject, error) {
+	var copied inventory.ManagedObject
+	bytes, err := generic.JsonFromObject(&managedObject)
+	if err == nil {
+		err = generic.ObjectFromJson(bytes, &copied)
+	}
+	return copied, err
+}
+
+func copyExternalId(externalId identity.ExternalID) (identity.ExternalID, error) {
+	managedObject, err := copyManagedObject(externalId.ManagedObject)
+	externalId.ManagedObject = managedObject
+	return externalId, err
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"github.com/tarent/gomulocity/realtimenotification"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DEFAULT_TTL         = time.Minute
+	DEFAULT_MAX_ENTRIES = 10000
+
+	// The realtime channel of all managed objects. See Cache.Run
+	MANAGED_OBJECTS_CHANNEL = "/managedobjects/*"
+)
+
+type Options struct {
+	TTL        time.Duration // Time after which an entry is read again. Defaults to DEFAULT_TTL.
+	MaxEntries int           // Maximum number of managed objects and of external ids each. Defaults to DEFAULT_MAX_ENTRIES.
+}
+
+type Stats struct {
+	Hits           int64
+	Misses         int64
+	ManagedObjects int
+	ExternalIds    int
+}
+
+/*
+Cache is a read-through cache for managed objects and external ids. Wrap the apis with InventoryApi and
+IdentityAPI, all other methods of the wrapped apis are passed through.
+
+Only existing managed objects and external ids are cached, so newly created ones are found immediately.
+Updates and deletions through the wrapped apis invalidate the cached entries. Changes made by other
+clients are seen after the TTL, or immediately when the cache receives realtime notifications, see Run.
+*/
+type Cache struct {
+	hits   int64
+	misses int64
+
+	managedObjects *store
+	externalIds    *store
+}
+
+// Creates a new cache.
+func NewCache(options Options) *Cache {
+	return newCache(options, time.Now)
+}
+
+func newCache(options Options, now func() time.Time) *Cache {
+	if options.TTL <= 0 {
+		options.TTL = DEFAULT_TTL
+	}
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = DEFAULT_MAX_ENTRIES
+	}
+	return &Cache{
+		managedObjects: newStore(options.TTL, options.MaxEntries, now),
+		externalIds:    newStore(options.TTL, options.MaxEntries, now),
+	}
+}
+
+// Wraps the inventory api. Get is cached, Update and Delete invalidate the managed object.
+func (c *Cache) InventoryApi(inventoryApi inventory.InventoryApi) inventory.InventoryApi {
+	return &cachedInventoryApi{InventoryApi: inventoryApi, cache: c}
+}
+
+// Wraps the identity api. GetExternalID is cached, DeleteExternalID invalidates the external id.
+func (c *Cache) IdentityAPI(identityApi identity.IdentityAPI) identity.IdentityAPI {
+	return &cachedIdentityApi{IdentityAPI: identityApi, cache: c}
+}
+
+// Removes the managed object and all its external ids from the cache.
+func (c *Cache) InvalidateManagedObject(managedObjectId string) {
+	c.managedObjects.remove(managedObjectId)
+	c.externalIds.removeIf(func(value interface{}) bool {
+		return value.(identity.ExternalID).ManagedObject.Id == managedObjectId
+	})
+}
+
+// Removes all entries from the cache.
+func (c *Cache) InvalidateAll() {
+	c.managedObjects.clear()
+	c.externalIds.clear()
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:           atomic.LoadInt64(&c.hits),
+		Misses:         atomic.LoadInt64(&c.misses),
+		ManagedObjects: c.managedObjects.len(),
+		ExternalIds:    c.externalIds.len(),
+	}
+}
+
+/*
+Invalidates the managed objects of the received realtime notifications until the context is done or the
+channel is closed. The notifications must be received from a subscription to MANAGED_OBJECTS_CHANNEL, ex.:
+
+	api, err := realtimenotification.StartRealtimeNotificationsAPI(ctx, credentials, host)
+	err = api.DoSubscribe(cache.MANAGED_OBJECTS_CHANNEL)
+	go c.Run(ctx, api.ResponseFromPolling)
+
+Notifications which can not be parsed are logged and skipped.
+*/
+func (c *Cache) Run(ctx context.Context, notifications <-chan json.RawMessage) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message, ok := <-notifications:
+			if !ok {
+				return nil
+			}
+			if err := c.invalidateNotification(message); err != nil {
+				log.Printf("Skipping realtime notification: %s", err.Error())
+			}
+		}
+	}
+}
+
+// -- internal
+
+// The data of updated managed objects is the managed object, the data of deleted ones its id.
+func (c *Cache) invalidateNotification(message json.RawMessage) error {
+	notification, err := realtimenotification.ParseNotification(message)
+	if err != nil {
+		return err
+	}
+
+	var managedObject struct {
+		Id string `json:"id"`
+	}
+	if err := json.Unmarshal(notification.Data, &managedObject); err == nil && len(managedObject.Id) > 0 {
+		c.InvalidateManagedObject(managedObject.Id)
+		return nil
+	}
+
+	id := strings.Trim(strings.TrimSpace(string(notification.Data)), `"`)
+	if len(id) == 0 {
+		return fmt.Errorf("notification %s has no managed object id", string(message))
+	}
+	c.InvalidateManagedObject(id)
+	return nil
+}
+
+func (c *Cache) count(hit bool) {
+	if hit {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+}
+
+func externalIdKey(externalIdType string, externalId string) string {
+	return externalIdType + "/" + externalId
+}
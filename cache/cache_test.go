@@ -0,0 +1,292 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Counts the requests per path. Managed objects are named by the number of their requests, ex. "v2".
+// The external id "unknown" and the managed object "404" do not exist.
+type testServer struct {
+	mutex    sync.Mutex
+	requests map[string]int
+}
+
+func (s *testServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests[r.Method+" "+r.URL.Path]++
+	count := s.requests[r.Method+" "+r.URL.Path]
+	s.mutex.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		_, _ = fmt.Fprintf(w, `{"id": "%s"}`, path[2])
+	case path[0] == "identity" && path[3] == "unknown", path[0] == "inventory" && path[2] == "404":
+		w.WriteHeader(http.StatusNotFound)
+	case path[0] == "identity":
+		_, _ = fmt.Fprintf(w, `{"externalId": "%s", "type": "%s", "managedObject": {"id": "101"}}`, path[3], path[2])
+	default:
+		_, _ = fmt.Fprintf(w, `{"id": "%s", "name": "v%d", "c8y_Hardware": {"model": "RPi"}}`, path[2], count)
+	}
+}
+
+func (s *testServer) count(request string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[request]
+}
+
+func buildCache(options Options) (*Cache, inventory.InventoryApi, identity.IdentityAPI, *testServer, *testClock, *httptest.Server) {
+	server := &testServer{requests: map[string]int{}}
+	ts := httptest.NewServer(http.HandlerFunc(server.serve))
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+
+	clock := &testClock{now: time.Date(2020, 7, 1, 8, 0, 0, 0, time.UTC)}
+	cache := newCache(options, clock.Now)
+	return cache, cache.InventoryApi(inventory.NewInventoryApi(client)), cache.IdentityAPI(identity.NewIdentityAPI(client)), server, clock, ts
+}
+
+func TestCache_InventoryApi_Get(t *testing.T) {
+	// given
+	cache, inventoryApi, _, server, clock, ts := buildCache(Options{TTL: time.Minute})
+	defer ts.Close()
+
+	// when
+	first, _ := inventoryApi.Get("101")
+	first.Name = "modified"
+	first.AdditionalFields["c8y_Hardware"].(map[string]interface{})["model"] = "modified"
+	second, err := inventoryApi.Get("101")
+	second.AdditionalFields["c8y_Hardware"].(map[string]interface{})["model"] = "modified"
+	third, _ := inventoryApi.Get("101")
+
+	// then: the calls are served from the cache and not affected by the modifications
+	if err != nil || second.Name != "v1" || server.count("GET /inventory/managedObjects/101") != 1 {
+		t.Errorf("Get() = %v, %v after %d requests", second, err, server.count("GET /inventory/managedObjects/101"))
+	}
+	if model := third.AdditionalFields["c8y_Hardware"].(map[string]interface{})["model"]; model != "RPi" {
+		t.Errorf("Get() fragment = %v, want RPi", model)
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.ManagedObjects != 1 {
+		t.Errorf("Stats() = %v", stats)
+	}
+
+	// when: the ttl expired
+	clock.advance(time.Minute)
+	expired, _ := inventoryApi.Get("101")
+
+	// then
+	if expired.Name != "v2" {
+		t.Errorf("Get() after ttl = %v, want v2", expired)
+	}
+}
+
+func TestStore_PutUnlessInvalidated(t *testing.T) {
+	// given: a value is read, while the store is invalidated
+	s := newStore(time.Minute, 10, time.Now)
+	generation := s.currentGeneration()
+	s.remove("101")
+
+	// when
+	put := s.putUnlessInvalidated("101", "outdated", generation)
+
+	// then
+	if _, ok := s.get("101"); put || ok {
+		t.Errorf("putUnlessInvalidated() cached a value read before the invalidation")
+	}
+	if !s.putUnlessInvalidated("101", "current", s.currentGeneration()) {
+		t.Errorf("putUnlessInvalidated() did not cache a current value")
+	}
+}
+
+func TestCache_InventoryApi_GetConcurrentToUpdate(t *testing.T) {
+	// given: an update, which finishes while the managed object is read
+	var cache *Cache
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cache.InvalidateManagedObject("101")
+		_, _ = w.Write([]byte(`{"id": "101", "name": "outdated"}`))
+	}))
+	defer ts.Close()
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	cache = NewCache(Options{})
+
+	// when
+	_, _ = cache.InventoryApi(inventory.NewInventoryApi(client)).Get("101")
+
+	// then
+	if stats := cache.Stats(); stats.ManagedObjects != 0 {
+		t.Errorf("Get() cached a managed object read during its invalidation")
+	}
+}
+
+func TestCache_InventoryApi_GetDoesNotCacheMissingManagedObjects(t *testing.T) {
+	_, inventoryApi, _, server, _, ts := buildCache(Options{})
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		if managedObject, err := inventoryApi.Get("404"); managedObject != nil || err != nil {
+			t.Errorf("Get() = %v, %v, want nil", managedObject, err)
+		}
+	}
+	if server.count("GET /inventory/managedObjects/404") != 2 {
+		t.Errorf("Get() of missing managed object was cached")
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// given: a cache for two managed objects
+	cache, inventoryApi, _, server, _, ts := buildCache(Options{MaxEntries: 2})
+	defer ts.Close()
+
+	// when
+	_, _ = inventoryApi.Get("1")
+	_, _ = inventoryApi.Get("2")
+	_, _ = inventoryApi.Get("1")
+	_, _ = inventoryApi.Get("3")
+	_, _ = inventoryApi.Get("1")
+	_, _ = inventoryApi.Get("2")
+
+	// then: 2 was evicted by 3
+	if server.count("GET /inventory/managedObjects/1") != 1 || server.count("GET /inventory/managedObjects/2") != 2 {
+		t.Errorf("Requests = %v", server.requests)
+	}
+	if cache.Stats().ManagedObjects != 2 {
+		t.Errorf("Stats() = %v", cache.Stats())
+	}
+}
+
+func TestCache_InventoryApi_UpdateAndDeleteInvalidate(t *testing.T) {
+	// given
+	_, inventoryApi, identityApi, server, _, ts := buildCache(Options{})
+	defer ts.Close()
+	_, _ = inventoryApi.Get("101")
+	_, _ = identityApi.GetExternalID("c8y_Serial", "4711")
+
+	// when
+	_, _ = inventoryApi.Update("101", &inventory.ManagedObjectUpdate{Name: "new"})
+	updated, _ := inventoryApi.Get("101")
+
+	// then
+	if updated.Name != "v2" {
+		t.Errorf("Get() after Update() = %v, want v2", updated)
+	}
+
+	// when: the managed object is deleted with its external ids
+	_ = inventoryApi.Delete("101")
+	_, _ = inventoryApi.Get("101")
+	_, _ = identityApi.GetExternalID("c8y_Serial", "4711")
+
+	// then
+	if server.count("GET /inventory/managedObjects/101") != 3 || server.count("GET /identity/externalIds/c8y_Serial/4711") != 2 {
+		t.Errorf("Requests = %v", server.requests)
+	}
+}
+
+func TestCache_IdentityApi(t *testing.T) {
+	// given
+	_, _, identityApi, server, _, ts := buildCache(Options{})
+	defer ts.Close()
+
+	// when
+	first, _ := identityApi.GetExternalID("c8y_Serial", "4711")
+	second, err := identityApi.GetExternalID("c8y_Serial", "4711")
+	unknown, _ := identityApi.GetExternalID("c8y_Serial", "unknown")
+	_, _ = identityApi.GetExternalID("c8y_Serial", "unknown")
+
+	// then
+	if err != nil || first.ManagedObject.Id != "101" || second.ManagedObject.Id != "101" || unknown != nil {
+		t.Errorf("GetExternalID() = %v, %v, %v", first, second, unknown)
+	}
+	if server.count("GET /identity/externalIds/c8y_Serial/4711") != 1 || server.count("GET /identity/externalIds/c8y_Serial/unknown") != 2 {
+		t.Errorf("Requests = %v", server.requests)
+	}
+
+	// when
+	_ = identityApi.DeleteExternalID("c8y_Serial", "4711")
+	_, _ = identityApi.GetExternalID("c8y_Serial", "4711")
+
+	// then
+	if server.count("GET /identity/externalIds/c8y_Serial/4711") != 2 {
+		t.Errorf("GetExternalID() after DeleteExternalID() was cached")
+	}
+}
+
+func TestCache_Run(t *testing.T) {
+	// given
+	cache, inventoryApi, identityApi, _, _, ts := buildCache(Options{})
+	defer ts.Close()
+	for _, id := range []string{"101", "102", "103"} {
+		_, _ = inventoryApi.Get(id)
+	}
+	_, _ = identityApi.GetExternalID("c8y_Serial", "4711")
+
+	notifications := make(chan json.RawMessage, 3)
+	notifications <- json.RawMessage(`{"realtimeAction": "UPDATE", "data": {"id": "101", "name": "changed"}}`)
+	notifications <- json.RawMessage(`{"realtimeAction": "DELETE", "data": "102"}`)
+	notifications <- json.RawMessage(`not json`)
+	close(notifications)
+
+	// when
+	err := cache.Run(context.Background(), notifications)
+
+	// then: 101 and 102 with its external id are invalidated
+	if err != nil {
+		t.Fatalf("Run() got an unexpected error: %s", err)
+	}
+	if stats := cache.Stats(); stats.ManagedObjects != 1 || stats.ExternalIds != 0 {
+		t.Errorf("Stats() = %v, want 103 only", stats)
+	}
+	if _, ok := cache.managedObjects.get("103"); !ok {
+		t.Errorf("Run() invalidated 103")
+	}
+}
+
+func TestCache_ConcurrentAccess(t *testing.T) {
+	cache, inventoryApi, _, _, _, ts := buildCache(Options{MaxEntries: 5})
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("%d", i%10)
+			_, _ = inventoryApi.Get(id)
+			cache.InvalidateManagedObject(fmt.Sprintf("%d", i%3))
+		}(i)
+	}
+	wg.Wait()
+
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 20 || stats.ManagedObjects > 5 {
+		t.Errorf("Stats() = %v", stats)
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+/*
+A size bound map with expiring entries. When full, the least recently used entry is evicted.
+
+Every removal increments the generation of the store. Values read before a removal are put with
+putUnlessInvalidated, so that a read, which overlaps with an invalidation, does not cache an outdated value.
+*/
+type store struct {
+	mutex      sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	entries    map[string]*list.Element
+	order      *list.List // front is the most recently used entry
+	generation uint64
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+func newStore(ttl time.Duration, maxEntries int, now func() time.Time) *store {
+	return &store{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        now,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (s *store) get(key string) (interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if !s.now().Before(e.expires) {
+		s.removeElement(element)
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Returns the current generation, to be passed to putUnlessInvalidated.
+func (s *store) currentGeneration() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.generation
+}
+
+// Puts the value, unless entries were removed since the generation was returned by currentGeneration.
+func (s *store) putUnlessInvalidated(key string, value interface{}, generation uint64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.generation != generation {
+		return false
+	}
+
+	if element, ok := s.entries[key]; ok {
+		s.removeElement(element)
+	}
+	s.entries[key] = s.order.PushFront(&entry{key: key, value: value, expires: s.now().Add(s.ttl)})
+
+	for s.order.Len() > s.maxEntries {
+		s.removeElement(s.order.Back())
+	}
+	return true
+}
+
+func (s *store) remove(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generation++
+	if element, ok := s.entries[key]; ok {
+		s.removeElement(element)
+	}
+}
+
+// Removes all entries, whose value matches.
+func (s *store) removeIf(matches func(value interface{}) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generation++
+	for element := s.order.Front(); element != nil; {
+		next := element.Next()
+		if matches(element.Value.(*entry).value) {
+			s.removeElement(element)
+		}
+		element = next
+	}
+}
+
+func (s *store) clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generation++
+	s.entries = map[string]*list.Element{}
+	s.order.Init()
+}
+
+func (s *store) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *store) removeElement(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*entry).key)
+}
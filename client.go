@@ -5,6 +5,7 @@ import (
 	"github.com/tarent/gomulocity/audit"
 	"github.com/tarent/gomulocity/device_bootstrap"
 	"github.com/tarent/gomulocity/devicecontrol"
+	"github.com/tarent/gomulocity/events"
 	"github.com/tarent/gomulocity/generic"
 	"github.com/tarent/gomulocity/identity"
 	"github.com/tarent/gomulocity/inventory"
@@ -24,6 +25,7 @@ type Gomulocity struct {
 	Identity           identity.IdentityAPI
 	UserApi            user_api.UserApi
 	Audit              audit.AuditApi
+	Events             events.Events
 }
 
 func NewGomulocity(baseURL, username, password string, bootstrapUsername, bootstrapPassword string) Gomulocity {
@@ -55,5 +57,6 @@ func NewGomulocity(baseURL, username, password string, bootstrapUsername, bootst
 		Identity:           identity.NewIdentityAPI(client),
 		UserApi:            user_api.NewUserApi(client),
 		Audit:              audit.NewAuditApi(client),
+		Events:             events.NewEventsApi(client),
 	}
 }
@@ -0,0 +1,160 @@
+package device_history
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/audit"
+	"github.com/tarent/gomulocity/devicecontrol"
+	"github.com/tarent/gomulocity/events"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind of the platform object an entry was created from.
+type Kind string
+
+const (
+	EVENT     Kind = "event"
+	ALARM     Kind = "alarm"
+	OPERATION Kind = "operation"
+	AUDIT     Kind = "audit"
+)
+
+/*
+Entry is one thing that happened to a device.
+
+Type is the event, alarm or audit type. Operations do not have a type, for them the name of
+the first command fragment (ex. c8y_Restart) is used. Fragments holds the custom fragments
+of events, alarms and operations.
+*/
+type Entry struct {
+	Time      time.Time              `json:"time"`
+	Kind      Kind                   `json:"kind"`
+	Id        string                 `json:"id"`
+	Type      string                 `json:"type,omitempty"`
+	Source    string                 `json:"source"`
+	Text      string                 `json:"text,omitempty"`
+	Status    string                 `json:"status,omitempty"`
+	Severity  string                 `json:"severity,omitempty"`
+	User      string                 `json:"user,omitempty"`
+	Fragments map[string]interface{} `json:"fragments,omitempty"`
+}
+
+// Writes the entries as JSON lines, one JSON object per line.
+func WriteJSONLines(w io.Writer, entries []Entry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("error while writing entry %s %s: %s", entry.Kind, entry.Id, err.Error())
+		}
+	}
+	return nil
+}
+
+/*
+Writes the entries as human readable timeline, one line per entry. Ex.:
+
+	2020-06-30T08:00:00Z  ALARM      c8y_Overheat  [MAJOR ACTIVE] Temperature too high (id 4711)
+*/
+func WriteTimeline(w io.Writer, entries []Entry) error {
+	for _, entry := range entries {
+		var details []string
+		for _, detail := range []string{entry.Severity, entry.Status, entry.User} {
+			if len(detail) > 0 {
+				details = append(details, detail)
+			}
+		}
+
+		line := fmt.Sprintf("%s  %-10s %s", entry.Time.UTC().Format(time.RFC3339), strings.ToUpper(string(entry.Kind)), entry.Type)
+		if len(details) > 0 {
+			line += fmt.Sprintf("  [%s]", strings.Join(details, " "))
+		}
+		if len(entry.Text) > 0 {
+			line += " " + entry.Text
+		}
+		line += fmt.Sprintf(" (id %s)\n", entry.Id)
+
+		if _, err := io.WriteString(w, line); err != nil {
+			return fmt.Errorf("error while writing entry %s %s: %s", entry.Kind, entry.Id, err.Error())
+		}
+	}
+	return nil
+}
+
+// -- internal
+
+func fromEvent(e events.Event) Entry {
+	return Entry{
+		Time:      e.Time,
+		Kind:      EVENT,
+		Id:        e.Id,
+		Type:      e.Type,
+		Source:    e.Source.Id,
+		Text:      e.Text,
+		Fragments: e.AdditionalFields,
+	}
+}
+
+func fromAlarm(a alarm.Alarm) Entry {
+	entry := Entry{
+		Kind:      ALARM,
+		Id:        a.Id,
+		Type:      a.Type,
+		Source:    a.Source.Id,
+		Text:      a.Text,
+		Status:    string(a.Status),
+		Severity:  string(a.Severity),
+		Fragments: a.AdditionalFields,
+	}
+	if a.Time != nil {
+		entry.Time = *a.Time
+	}
+	return entry
+}
+
+func fromOperation(o devicecontrol.Operation) Entry {
+	var fragments []string
+	for name := range o.AdditionalFields {
+		if strings.HasPrefix(name, "c8y_") {
+			fragments = append(fragments, name)
+		}
+	}
+	sort.Strings(fragments)
+
+	entry := Entry{
+		Time:      o.CreationTime,
+		Kind:      OPERATION,
+		Id:        o.OperationID,
+		Source:    o.DeviceID,
+		Text:      o.Description,
+		Status:    o.Status,
+		Fragments: o.AdditionalFields,
+	}
+	if len(fragments) > 0 {
+		entry.Type = fragments[0]
+	}
+	if len(o.FailureReason) > 0 {
+		entry.Text = fmt.Sprintf("%s: %s", o.Description, o.FailureReason)
+	}
+	return entry
+}
+
+func fromAuditRecord(r audit.AuditRecord) Entry {
+	text := r.Activity
+	if len(r.Text) > 0 {
+		text = fmt.Sprintf("%s: %s", r.Activity, r.Text)
+	}
+	return Entry{
+		Time:     r.Time,
+		Kind:     AUDIT,
+		Id:       r.ID,
+		Type:     r.Type,
+		Source:   r.Source.ID,
+		Text:     text,
+		Severity: r.Severity,
+		User:     r.User,
+	}
+}
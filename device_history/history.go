@@ -0,0 +1,264 @@
+package device_history
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/audit"
+	"github.com/tarent/gomulocity/devicecontrol"
+	"github.com/tarent/gomulocity/events"
+	"sort"
+	"time"
+)
+
+const pageSize = 2000
+
+// Type of the audit records of alarms.
+const ALARM_AUDIT_TYPE = "Alarm"
+
+/*
+Query selects the history of a source.
+Empty Kinds or Types select all. DateFrom and DateTo are inclusive and optional.
+*/
+type Query struct {
+	Source   string // Id of the device, required.
+	Kinds    []Kind
+	Types    []string
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+func (q *Query) validate() error {
+	if len(q.Source) == 0 {
+		return fmt.Errorf("the source must be set")
+	}
+	if q.DateFrom != nil && q.DateTo != nil && q.DateTo.Before(*q.DateFrom) {
+		return fmt.Errorf("'DateTo' must not be before 'DateFrom'")
+	}
+	for _, kind := range q.Kinds {
+		switch kind {
+		case EVENT, ALARM, OPERATION, AUDIT:
+		default:
+			return fmt.Errorf("unknown kind '%s'", kind)
+		}
+	}
+	return nil
+}
+
+func (q *Query) includesKind(kind Kind) bool {
+	if len(q.Kinds) == 0 {
+		return true
+	}
+	for _, k := range q.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Query) matches(entry Entry) bool {
+	if q.DateFrom != nil && entry.Time.Before(*q.DateFrom) {
+		return false
+	}
+	if q.DateTo != nil && entry.Time.After(*q.DateTo) {
+		return false
+	}
+	return q.includesType(entry.Type)
+}
+
+func (q *Query) includesType(t string) bool {
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, queryType := range q.Types {
+		if queryType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// The type can only be filtered by the platform, when exactly one is given.
+func (q *Query) singleType() string {
+	if len(q.Types) == 1 {
+		return q.Types[0]
+	}
+	return ""
+}
+
+/*
+History merges events, alarms, operations and audit records of a device into one stream
+ordered by time. Entries with the same time keep the order events, alarms, operations, audit records.
+
+The audit records of alarms have the alarm as source. They are searched for each alarm of the device
+and their entries get the device as source. Without an alarm api they are not part of the history.
+*/
+type History struct {
+	eventsApi        events.Events
+	alarmApi         alarm.AlarmApi
+	deviceControlApi devicecontrol.DeviceControlApi
+	auditApi         audit.AuditApi
+}
+
+// Creates a new device history.
+// An api may be nil, the according kind of entries is not part of the history then.
+func NewHistory(eventsApi events.Events, alarmApi alarm.AlarmApi, deviceControlApi devicecontrol.DeviceControlApi, auditApi audit.AuditApi) *History {
+	return &History{
+		eventsApi:        eventsApi,
+		alarmApi:         alarmApi,
+		deviceControlApi: deviceControlApi,
+		auditApi:         auditApi,
+	}
+}
+
+// Returns all entries selected by the query, the oldest first.
+func (h *History) Replay(query Query) ([]Entry, error) {
+	if err := query.validate(); err != nil {
+		return nil, fmt.Errorf("invalid history query: %s", err.Error())
+	}
+
+	var entries []Entry
+	collect := func(kind Kind, find func(*Query) ([]Entry, error)) error {
+		if !query.includesKind(kind) {
+			return nil
+		}
+		found, err := find(&query)
+		if err != nil {
+			return err
+		}
+		for _, entry := range found {
+			if query.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	}
+
+	if h.eventsApi != nil {
+		if err := collect(EVENT, h.findEvents); err != nil {
+			return nil, err
+		}
+	}
+	if h.alarmApi != nil {
+		if err := collect(ALARM, h.findAlarms); err != nil {
+			return nil, err
+		}
+	}
+	if h.deviceControlApi != nil {
+		if err := collect(OPERATION, h.findOperations); err != nil {
+			return nil, err
+		}
+	}
+	if h.auditApi != nil {
+		if err := collect(AUDIT, h.findAuditRecords); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// -- internal
+
+func (h *History) findEvents(query *Query) ([]Entry, error) {
+	var entries []Entry
+
+	eventQuery := &events.EventQuery{Source: query.Source, Type: query.singleType(), DateFrom: query.DateFrom, DateTo: query.DateTo}
+	collection, err := h.eventsApi.Find(eventQuery, pageSize)
+	for ; collection != nil; collection, err = h.eventsApi.NextPage(collection) {
+		for _, e := range collection.Events {
+			entries = append(entries, fromEvent(e))
+		}
+		if len(collection.Events) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding events: %s", err.Error())
+	}
+	return entries, nil
+}
+
+func (h *History) findAlarms(query *Query) ([]Entry, error) {
+	var entries []Entry
+
+	alarmQuery := &alarm.AlarmQuery{SourceId: query.Source, Type: query.Types, DateFrom: query.DateFrom, DateTo: query.DateTo}
+	collection, err := h.alarmApi.FindByQuery(alarmQuery, pageSize)
+	for ; collection != nil; collection, err = h.alarmApi.NextPage(collection) {
+		for _, a := range collection.Alarms {
+			entries = append(entries, fromAlarm(a))
+		}
+		if len(collection.Alarms) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding alarms: %s", err.Error())
+	}
+	return entries, nil
+}
+
+func (h *History) findOperations(query *Query) ([]Entry, error) {
+	var entries []Entry
+
+	operationQuery := devicecontrol.OperationQuery{DeviceID: query.Source, DateFrom: query.DateFrom, DateTo: query.DateTo}
+	collection, err := h.deviceControlApi.FindOperationCollection(operationQuery, pageSize)
+	for ; collection != nil; collection, err = h.deviceControlApi.NextPage(collection) {
+		for _, o := range collection.Operations {
+			entries = append(entries, fromOperation(o))
+		}
+		if len(collection.Operations) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding operations: %s", err.Error())
+	}
+	return entries, nil
+}
+
+func (h *History) findAuditRecords(query *Query) ([]Entry, error) {
+	entries, err := h.findAuditRecordsOf(query.Source, query)
+	if err != nil || h.alarmApi == nil || !query.includesType(ALARM_AUDIT_TYPE) {
+		return entries, err
+	}
+
+	// Audit records of alarms have the alarm as source, so they are searched per alarm of the device.
+	alarms, err := h.findAlarms(&Query{Source: query.Source})
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alarms {
+		found, err := h.findAuditRecordsOf(a.Id, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range found {
+			entry.Source = query.Source
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (h *History) findAuditRecordsOf(source string, query *Query) ([]Entry, error) {
+	var entries []Entry
+
+	auditQuery := &audit.AuditQuery{Source: source, Type: query.singleType(), DateFrom: query.DateFrom, DateTo: query.DateTo}
+	collection, err := h.auditApi.GetAuditRecords(auditQuery, pageSize)
+	for ; collection != nil; collection, err = h.auditApi.NextPage(collection) {
+		for _, r := range collection.AuditRecords {
+			entries = append(entries, fromAuditRecord(r))
+		}
+		if len(collection.AuditRecords) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding audit records of %s: %s", source, err.Error())
+	}
+	return entries, nil
+}
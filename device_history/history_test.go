@@ -0,0 +1,225 @@
+package device_history
+
+import (
+	"bytes"
+	"github.com/tarent/gomulocity/alarm"
+	"github.com/tarent/gomulocity/audit"
+	"github.com/tarent/gomulocity/devicecontrol"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start, _ = time.Parse(time.RFC3339, "2020-06-30T08:00:00Z")
+
+const (
+	eventCollection = `{"events": [
+		{"id": "10", "type": "c8y_LocationUpdate", "time": "2020-06-30T08:03:00Z", "text": "Moved", "source": {"id": "4711"}, "c8y_Position": {"lat": 52.5}},
+		{"id": "11", "type": "c8y_Restarted", "time": "2020-06-30T08:01:00Z", "text": "Restarted", "source": {"id": "4711"}}
+	]}`
+	alarmCollection = `{"alarms": [
+		{"id": "20", "type": "c8y_Overheat", "time": "2020-06-30T08:02:00Z", "text": "Too hot", "source": {"id": "4711"}, "status": "ACTIVE", "severity": "MAJOR"}
+	]}`
+	operationCollection = `{"operations": [
+		{"id": "30", "deviceId": "4711", "creationTime": "2020-06-30T08:00:00Z", "status": "SUCCESSFUL", "description": "Restart device", "c8y_Restart": {}}
+	]}`
+	auditCollection = `{"auditRecords": [
+		{"id": "40", "type": "Inventory", "time": "2020-06-30T08:04:00Z", "activity": "Device updated", "text": "name changed", "user": "admin", "severity": "information", "source": {"id": "4711"}}
+	]}`
+	alarmAuditCollection = `{"auditRecords": [
+		{"id": "41", "type": "Alarm", "time": "2020-06-30T08:05:00Z", "activity": "Alarm updated", "text": "Too hot", "user": "admin", "severity": "MAJOR", "source": {"id": "20"}}
+	]}`
+)
+
+// Serves one collection per api and records the requested urls.
+func buildHistory(requests *[]string) (*History, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.String())
+		switch r.URL.Path {
+		case "/event/events":
+			_, _ = w.Write([]byte(eventCollection))
+		case "/alarm/alarms":
+			_, _ = w.Write([]byte(alarmCollection))
+		case "/devicecontrol/operations":
+			_, _ = w.Write([]byte(operationCollection))
+		case "/audit/auditRecords":
+			if r.URL.Query().Get("source") == "20" {
+				_, _ = w.Write([]byte(alarmAuditCollection))
+			} else {
+				_, _ = w.Write([]byte(auditCollection))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	history := NewHistory(events.NewEventsApi(client), alarm.NewAlarmApi(client), devicecontrol.NewDeviceControlApi(client), audit.NewAuditApi(client))
+	return history, ts
+}
+
+func ids(entries []Entry) string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, string(entry.Kind)+":"+entry.Id)
+	}
+	return strings.Join(result, ", ")
+}
+
+func TestHistory_Replay(t *testing.T) {
+	// given: a platform with events, alarms, operations and audit records of a device
+	var requests []string
+	history, ts := buildHistory(&requests)
+	defer ts.Close()
+
+	// when
+	entries, err := history.Replay(Query{Source: "4711"})
+
+	// then: all entries are merged in time order
+	if err != nil {
+		t.Fatalf("Replay() got an unexpected error: %s", err)
+	}
+	expected := "operation:30, event:11, alarm:20, event:10, audit:40, audit:41"
+	if ids(entries) != expected {
+		t.Errorf("Replay() = %s, want %s", ids(entries), expected)
+	}
+
+	// and: the entries are mapped
+	if entries[0].Type != "c8y_Restart" || entries[0].Status != "SUCCESSFUL" || entries[0].Source != "4711" {
+		t.Errorf("Operation entry = %v", entries[0])
+	}
+	if entries[2].Severity != "MAJOR" || entries[2].Status != "ACTIVE" {
+		t.Errorf("Alarm entry = %v", entries[2])
+	}
+	if _, ok := entries[3].Fragments["c8y_Position"]; !ok {
+		t.Errorf("Event entry fragments = %v", entries[3].Fragments)
+	}
+	if entries[4].Text != "Device updated: name changed" || entries[4].User != "admin" {
+		t.Errorf("Audit entry = %v", entries[4])
+	}
+
+	// and: the audit records of the alarm belong to the device
+	if entries[5].Type != "Alarm" || entries[5].Source != "4711" {
+		t.Errorf("Alarm audit entry = %v", entries[5])
+	}
+
+	// and: each api was queried for the source or its alarm
+	for _, request := range requests {
+		if !strings.Contains(request, "4711") && !strings.Contains(request, "source=20") {
+			t.Errorf("Request %s is not filtered by source", request)
+		}
+	}
+}
+
+func TestHistory_Replay_AuditRecordsOfOtherTypes(t *testing.T) {
+	// given
+	var requests []string
+	history, ts := buildHistory(&requests)
+	defer ts.Close()
+
+	// when: only audit records of the inventory are selected
+	entries, err := history.Replay(Query{Source: "4711", Kinds: []Kind{AUDIT}, Types: []string{"Inventory"}})
+
+	// then: the alarms are not searched for audit records
+	if err != nil {
+		t.Fatalf("Replay() got an unexpected error: %s", err)
+	}
+	if ids(entries) != "audit:40" {
+		t.Errorf("Replay() = %s, want %s", ids(entries), "audit:40")
+	}
+	if len(requests) != 1 {
+		t.Errorf("Requests = %v, want only the audit records of the device", requests)
+	}
+}
+
+func TestHistory_Replay_Filter(t *testing.T) {
+	var requests []string
+	history, ts := buildHistory(&requests)
+	defer ts.Close()
+	from := start.Add(time.Minute)
+	to := start.Add(3 * time.Minute)
+
+	entries, err := history.Replay(Query{Source: "4711", Kinds: []Kind{EVENT, ALARM}, Types: []string{"c8y_Restarted", "c8y_Overheat"}, DateFrom: &from, DateTo: &to})
+
+	if err != nil {
+		t.Fatalf("Replay() got an unexpected error: %s", err)
+	}
+	if ids(entries) != "event:11, alarm:20" {
+		t.Errorf("Replay() = %s, want %s", ids(entries), "event:11, alarm:20")
+	}
+	if len(requests) != 2 {
+		t.Errorf("Requests = %v, want only events and alarms", requests)
+	}
+}
+
+func TestHistory_Replay_Invalid(t *testing.T) {
+	history := NewHistory(nil, nil, nil, nil)
+	before := start.Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{"Without source", Query{}},
+		{"Unknown kind", Query{Source: "4711", Kinds: []Kind{"measurement"}}},
+		{"DateTo before DateFrom", Query{Source: "4711", DateFrom: &start, DateTo: &before}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := history.Replay(tt.query); err == nil {
+				t.Errorf("Replay() error expected but was nil")
+			}
+		})
+	}
+}
+
+func TestHistory_Replay_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+
+	_, err := NewHistory(events.NewEventsApi(client), nil, nil, nil).Replay(Query{Source: "4711"})
+
+	if err == nil || !strings.Contains(err.Error(), "error while finding events") {
+		t.Errorf("Replay() error = %v, want a find error", err)
+	}
+}
+
+func TestWriteTimeline(t *testing.T) {
+	var buffer bytes.Buffer
+	entries := []Entry{
+		{Time: start, Kind: ALARM, Id: "20", Type: "c8y_Overheat", Text: "Too hot", Severity: "MAJOR", Status: "ACTIVE"},
+		{Time: start.Add(time.Minute), Kind: EVENT, Id: "11", Type: "c8y_Restarted"},
+	}
+
+	err := WriteTimeline(&buffer, entries)
+
+	expected := "2020-06-30T08:00:00Z  ALARM      c8y_Overheat  [MAJOR ACTIVE] Too hot (id 20)\n" +
+		"2020-06-30T08:01:00Z  EVENT      c8y_Restarted (id 11)\n"
+	if err != nil || buffer.String() != expected {
+		t.Errorf("WriteTimeline() = %q, %v, want %q", buffer.String(), err, expected)
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var buffer bytes.Buffer
+	entries := []Entry{
+		{Time: start, Kind: EVENT, Id: "11", Type: "c8y_Restarted", Source: "4711"},
+		{Time: start, Kind: AUDIT, Id: "40", Source: "4711", User: "admin"},
+	}
+
+	err := WriteJSONLines(&buffer, entries)
+
+	expected := `{"time":"2020-06-30T08:00:00Z","kind":"event","id":"11","type":"c8y_Restarted","source":"4711"}` + "\n" +
+		`{"time":"2020-06-30T08:00:00Z","kind":"audit","id":"40","source":"4711","user":"admin"}` + "\n"
+	if err != nil || buffer.String() != expected {
+		t.Errorf("WriteJSONLines() = %q, %v, want %q", buffer.String(), err, expected)
+	}
+}
@@ -91,6 +91,8 @@ type OperationQuery struct {
 	DeviceID string
 	Status   string
 	AgentID  string
+	DateFrom *time.Time // Start date or date and time of the operation creation.
+	DateTo   *time.Time // End date or date and time of the operation creation.
 }
 
 func (o *OperationQuery) QueryParams(params *url.Values) {
@@ -105,4 +107,12 @@ func (o *OperationQuery) QueryParams(params *url.Values) {
 	if len(o.AgentID) > 0 {
 		params.Add("agentId", o.AgentID)
 	}
+
+	if o.DateFrom != nil {
+		params.Add("dateFrom", o.DateFrom.Format(time.RFC3339))
+	}
+
+	if o.DateTo != nil {
+		params.Add("dateTo", o.DateTo.Format(time.RFC3339))
+	}
 }
@@ -23,7 +23,7 @@ var responseEvent = &Event{
 
 func buildEventsApi(url string) Events {
 	httpClient := http.DefaultClient
-	client := generic.Client{
+	client := &generic.Client{
 		HTTPClient: httpClient,
 		BaseURL:    url,
 		Username:   "foo",
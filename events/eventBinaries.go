@@ -0,0 +1,140 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const DEFAULT_BINARY_CONTENT_TYPE = "application/octet-stream"
+
+/*
+Represents the meta data of a binary attached to an event.
+See: https://cumulocity.com/guides/reference/events/#binaries
+*/
+type EventBinary struct {
+	Id      string     `json:"id,omitempty"`
+	Self    string     `json:"self,omitempty"`
+	Source  string     `json:"source,omitempty"` // Id of the event
+	Name    string     `json:"name,omitempty"`
+	Type    string     `json:"type,omitempty"` // Content type of the binary
+	Length  int64      `json:"length,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+/*
+Attaches a binary to an event. The binary is sent as request body with the given content type.
+When no content type is given, DEFAULT_BINARY_CONTENT_TYPE is used.
+
+See: https://cumulocity.com/guides/reference/events/#post-upload-a-binary
+*/
+func (e *events) UploadBinary(eventId string, contentType string, binary io.Reader) (*EventBinary, *generic.Error) {
+	return e.sendBinary(http.MethodPost, http.StatusCreated, eventId, contentType, binary, "UploadBinary")
+}
+
+/*
+Replaces the binary of an event.
+
+See: https://cumulocity.com/guides/reference/events/#put-replace-a-binary
+*/
+func (e *events) ReplaceBinary(eventId string, contentType string, binary io.Reader) (*EventBinary, *generic.Error) {
+	return e.sendBinary(http.MethodPut, http.StatusCreated, eventId, contentType, binary, "ReplaceBinary")
+}
+
+/*
+Streams the binary of an event into the writer. Returns the content type of the binary.
+
+See: https://cumulocity.com/guides/reference/events/#get-download-a-binary
+*/
+func (e *events) DownloadBinary(eventId string, writer io.Writer) (string, *generic.Error) {
+	if len(eventId) == 0 {
+		return "", generic.ClientError("Downloading a binary without eventId is not allowed", "DownloadBinary")
+	}
+	if writer == nil {
+		return "", generic.ClientError("Downloading a binary without writer is not allowed", "DownloadBinary")
+	}
+
+	response, err := e.client.Stream(http.MethodGet, e.binaryPath(eventId), nil, generic.AcceptHeader("*/*"))
+	if err != nil {
+		return "", generic.ClientError(fmt.Sprintf("Error while downloading a binary: %s", err.Error()), "DownloadBinary")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return "", generic.CreateErrorFromResponse(body, response.StatusCode)
+	}
+
+	if _, err := io.Copy(writer, response.Body); err != nil {
+		return "", generic.ClientError(fmt.Sprintf("Error while downloading a binary: %s", err.Error()), "DownloadBinary")
+	}
+
+	return response.Header.Get("Content-Type"), nil
+}
+
+/*
+Deletes the binary of an event.
+
+See: https://cumulocity.com/guides/reference/events/#delete-delete-a-binary
+*/
+func (e *events) DeleteBinary(eventId string) *generic.Error {
+	if len(eventId) == 0 {
+		return generic.ClientError("Deleting a binary without eventId is not allowed", "DeleteBinary")
+	}
+
+	body, status, err := e.client.Delete(e.binaryPath(eventId), generic.EmptyHeader())
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting a binary: %s", err.Error()), "DeleteBinary")
+	}
+
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
+	}
+
+	return nil
+}
+
+// -- internal
+
+func (e *events) binaryPath(eventId string) string {
+	return fmt.Sprintf("%s/%s/binaries", e.basePath, url.QueryEscape(eventId))
+}
+
+func (e *events) sendBinary(method string, expectedStatus int, eventId string, contentType string, binary io.Reader, info string) (*EventBinary, *generic.Error) {
+	if len(eventId) == 0 {
+		return nil, generic.ClientError("Sending a binary without eventId is not allowed", info)
+	}
+	if binary == nil {
+		return nil, generic.ClientError("Sending a binary without content is not allowed", info)
+	}
+	if len(contentType) == 0 {
+		contentType = DEFAULT_BINARY_CONTENT_TYPE
+	}
+
+	headers := generic.AcceptAndContentTypeHeader("application/json", contentType)
+	response, err := e.client.Stream(method, e.binaryPath(eventId), binary, headers)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while sending a binary: %s", err.Error()), info)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while reading the response: %s", err.Error()), info)
+	}
+
+	if response.StatusCode != expectedStatus {
+		return nil, generic.CreateErrorFromResponse(body, response.StatusCode)
+	}
+
+	var result EventBinary
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), info)
+	}
+	return &result, nil
+}
@@ -3,19 +3,25 @@ package events
 import (
 	"fmt"
 	"github.com/tarent/gomulocity/generic"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"time"
 )
 
-const EVENT_ACCEPT_HEADER = "application/vnd.com.nsn.cumulocity.eventApi+json"
+const (
+	EVENT_API_PATH = "/event/events"
+
+	EVENT_TYPE            = "application/vnd.com.nsn.cumulocity.event+json"
+	EVENT_COLLECTION_TYPE = "application/vnd.com.nsn.cumulocity.eventCollection+json"
+)
 
 // Creates a new events api object
 // client - Must be a gomulocity client.
 // returns - The `Events`-api object
-func NewEventsApi(client generic.Client) Events {
-	return &events{client, "/event/events"}
+func NewEventsApi(client *generic.Client) Events {
+	return &events{client, EVENT_API_PATH}
 }
 
 type Events interface {
@@ -30,6 +36,15 @@ type Events interface {
 	// successfully.
 	DeleteEvent(eventId string) *generic.Error
 
+	// Deletes events by query. If error is nil, events were deleted successfully.
+	// ATTENTION: at least one filter should be set otherwise an error will be thrown.
+	// Use DeleteAll() (with caution!) instead if you want delete all events!
+	DeleteMany(query *EventQuery) *generic.Error
+
+	// Deletes all events. If error is nil, events were deleted successfully.
+	// ATTENTION: use it with caution!
+	DeleteAll() *generic.Error
+
 	// Gets an exiting event by its id. If the id does not exists, nil is returned.
 	Get(eventId string) (*Event, *generic.Error)
 
@@ -37,8 +52,8 @@ type Events interface {
 	GetForDevice(source string, pageSize int) (*EventCollection, *generic.Error)
 
 	// Returns an event collection, found by the given event query parameters.
-	// all query parameters are AND concat.
-	Find(query EventQuery) (*EventCollection, *generic.Error)
+	// All query parameters are AND concatenated.
+	Find(query *EventQuery, pageSize int) (*EventCollection, *generic.Error)
 
 	// Gets the next page from an existing event collection.
 	// If there is no next page, nil is returned.
@@ -47,40 +62,76 @@ type Events interface {
 	// Gets the previous page from an existing event collection.
 	// If there is no previous page, nil is returned.
 	PreviousPage(c *EventCollection) (*EventCollection, *generic.Error)
+
+	// Attaches a binary to an existing event. The binary is streamed from the reader.
+	// An event can only have one binary, use ReplaceBinary to change it.
+	UploadBinary(eventId string, contentType string, binary io.Reader) (*EventBinary, *generic.Error)
+
+	// Streams the binary of an event into the writer and returns its content type.
+	DownloadBinary(eventId string, writer io.Writer) (string, *generic.Error)
+
+	// Replaces the binary of an event. The binary is streamed from the reader.
+	ReplaceBinary(eventId string, contentType string, binary io.Reader) (*EventBinary, *generic.Error)
+
+	// Deletes the binary of an event. If error is nil, the binary was deleted successfully.
+	DeleteBinary(eventId string) *generic.Error
 }
 
+/*
+Query parameters to search for and delete events.
+See: https://cumulocity.com/guides/reference/events/#event-collection
+*/
 type EventQuery struct {
-	DateFrom     *time.Time
-	DateTo       *time.Time
-	FragmentType string
-	Type         string
-	Source       string
-	PageSize     int
+	DateFrom        *time.Time // Start date or date and time of the event occurrence.
+	DateTo          *time.Time // End date or date and time of the event occurrence.
+	CreatedFrom     *time.Time // Start date or date and time of the event creation.
+	CreatedTo       *time.Time // End date or date and time of the event creation.
+	LastUpdatedFrom *time.Time // Start date or date and time of the last update of the event.
+	LastUpdatedTo   *time.Time // End date or date and time of the last update of the event.
+	FragmentType    string     // Only events with this fragment.
+	FragmentValue   string     // Only events with this value of the fragment. FragmentType must be set as well.
+	Type            string     // Event type.
+	Source          string     // Source device id.
+
+	// When set to true also events for related source assets will be included.
+	// When this parameter is provided also source must be defined.
+	WithSourceAssets bool
+
+	// When set to true also events for related source devices will be included.
+	// When this parameter is provided also source must be defined.
+	WithSourceDevices bool
+
+	// It's not a filter. It's the sort order. As per default the events are delivered from the newest to the oldest.
+	// Setting to true is only valid with a DateFrom or DateTo filter. In that case the oldest event will be at the first place.
+	Revert bool
 }
 
-func (q EventQuery) QueryParams() (string, *generic.Error) {
-	if q.PageSize < 0 || q.PageSize > 2000 {
-		return "", generic.ClientError(fmt.Sprintf("The page size must be between 1 and 2000. Was %d", q.PageSize), "QueryParams")
+// Appends the query parameters to the provided parameter values for a request.
+// When provided values is nil an error will be created
+func (q EventQuery) QueryParams(params *url.Values) error {
+	if params == nil {
+		return fmt.Errorf("The provided parameter values must not be nil!")
 	}
 
-	params := url.Values{}
+	generic.TimeRangeParameters("dateFrom", q.DateFrom, "dateTo", q.DateTo, params)
+	generic.TimeRangeParameters("createdFrom", q.CreatedFrom, "createdTo", q.CreatedTo, params)
+	generic.TimeRangeParameters("lastUpdatedFrom", q.LastUpdatedFrom, "lastUpdatedTo", q.LastUpdatedTo, params)
 
-	if q.DateFrom != nil {
-		params.Add("dateFrom", q.DateFrom.Format(time.RFC3339))
-	}
-
-	if q.DateTo != nil {
-		params.Add("dateTo", q.DateTo.Format(time.RFC3339))
-	}
-
-	if q.PageSize > 0 {
-		params.Add("pageSize", fmt.Sprintf("%d", q.PageSize))
+	if q.DateFrom != nil && q.DateTo != nil && q.DateTo.Before(*q.DateFrom) {
+		return fmt.Errorf("failed to build query: 'DateTo' must not be before 'DateFrom'.")
 	}
 
 	if len(q.FragmentType) > 0 {
 		params.Add("fragmentType", q.FragmentType)
 	}
 
+	if len(q.FragmentValue) > 0 {
+		if len(q.FragmentType) == 0 {
+			return fmt.Errorf("failed to build query: when 'FragmentValue' parameter is defined also FragmentType must be set.")
+		}
+		params.Add("fragmentValue", q.FragmentValue)
+	}
+
 	if len(q.Type) > 0 {
 		params.Add("type", q.Type)
 	}
@@ -89,11 +140,32 @@ func (q EventQuery) QueryParams() (string, *generic.Error) {
 		params.Add("source", q.Source)
 	}
 
-	return params.Encode(), nil
+	if q.WithSourceAssets {
+		if len(q.Source) == 0 {
+			return fmt.Errorf("failed to build query: when 'WithSourceAssets' parameter is defined also Source must be set.")
+		}
+		params.Add("withSourceAssets", "true")
+	}
+
+	if q.WithSourceDevices {
+		if len(q.Source) == 0 {
+			return fmt.Errorf("failed to build query: when 'WithSourceDevices' parameter is defined also Source must be set.")
+		}
+		params.Add("withSourceDevices", "true")
+	}
+
+	if q.Revert {
+		if q.DateFrom == nil && q.DateTo == nil {
+			return fmt.Errorf("failed to build query: if 'Revert' parameter is set to true, 'DateFrom' or 'DateTo' should be set as well.")
+		}
+		params.Add("revert", "true")
+	}
+
+	return nil
 }
 
 type events struct {
-	client   generic.Client
+	client   *generic.Client
 	basePath string
 }
 
@@ -117,7 +189,7 @@ func (e *events) CreateEvent(event *CreateEvent) (*Event, *generic.Error) {
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the event: %s", err.Error()), "CreateEvent")
 	}
 
-	body, status, err := e.client.Post(e.basePath, bytes, generic.AcceptHeader(EVENT_ACCEPT_HEADER))
+	body, status, err := e.client.Post(e.basePath, bytes, generic.AcceptAndContentTypeHeader(EVENT_TYPE, EVENT_TYPE))
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while posting a new event: %s", err.Error()), "CreateEvent")
 	}
@@ -135,7 +207,7 @@ func (e *events) UpdateEvent(eventId string, event *UpdateEvent) (*Event, *gener
 	}
 
 	path := fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId))
-	body, status, err := e.client.Put(path, bytes, generic.AcceptHeader(EVENT_ACCEPT_HEADER))
+	body, status, err := e.client.Put(path, bytes, generic.AcceptAndContentTypeHeader(EVENT_TYPE, EVENT_TYPE))
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while updating an event: %s", err.Error()), "UpdateEvent")
 	}
@@ -146,30 +218,84 @@ func (e *events) UpdateEvent(eventId string, event *UpdateEvent) (*Event, *gener
 	return parseEventResponse(body)
 }
 
+func (e *events) DeleteMany(query *EventQuery) *generic.Error {
+	if query == nil {
+		return generic.ClientError("No filter set. At least one filter has to be set to avoid accident deletion of all events. Use `DeleteAll()` if you really want to remove them all", "DeleteManyEvents")
+	}
+	queryParamsValues := &url.Values{}
+	err := query.QueryParams(queryParamsValues)
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while building query parameters for deletion of events: %s", err.Error()), "DeleteManyEvents")
+	}
+	if len(*queryParamsValues) == 0 {
+		return generic.ClientError("No filter set. At least one filter has to be set to avoid accident deletion of all events. Use `DeleteAll()` if you really want to remove them all", "DeleteManyEvents")
+	}
+
+	body, status, err := e.client.Delete(fmt.Sprintf("%s?%s", e.basePath, queryParamsValues.Encode()), generic.EmptyHeader())
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting events: %s", err.Error()), "DeleteManyEvents")
+	}
+
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
+	}
+
+	return nil
+}
+
+/*
+ATTENTION: This function deletes all events
+*/
+func (e *events) DeleteAll() *generic.Error {
+	body, status, err := e.client.Delete(e.basePath, generic.EmptyHeader())
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting events: %s", err.Error()), "DeleteAllEvents")
+	}
+
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
+	}
+	log.Println("WARNING: all events of the tenant were deleted!")
+
+	return nil
+}
+
 func (e *events) Get(eventId string) (*Event, *generic.Error) {
-	body, status, err := e.client.Get(fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId)), generic.EmptyHeader())
+	body, status, err := e.client.Get(fmt.Sprintf("%s/%s", e.basePath, url.QueryEscape(eventId)), generic.AcceptHeader(EVENT_TYPE))
 
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while getting an event: %s", err.Error()), "Get")
 	}
-	if status != http.StatusOK {
+	if status == http.StatusNotFound {
 		return nil, nil
 	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
 
 	return parseEventResponse(body)
 }
 
 func (e *events) GetForDevice(source string, pageSize int) (*EventCollection, *generic.Error) {
-	return e.Find(EventQuery{Source: source, PageSize: pageSize})
+	return e.Find(&EventQuery{Source: source}, pageSize)
 }
 
-func (e *events) Find(query EventQuery) (*EventCollection, *generic.Error) {
-	queryParams, err := query.QueryParams()
+func (e *events) Find(query *EventQuery, pageSize int) (*EventCollection, *generic.Error) {
+	if query == nil {
+		query = &EventQuery{}
+	}
+	queryParamsValues := &url.Values{}
+	err := query.QueryParams(queryParamsValues)
 	if err != nil {
-		return nil, err
+		return nil, generic.ClientError(fmt.Sprintf("Error while building query parameters to search for events: %s", err.Error()), "FindEvents")
 	}
 
-	return e.getCommon(fmt.Sprintf("%s?%s", e.basePath, queryParams))
+	err = generic.PageSizeParameter(pageSize, queryParamsValues)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while building pageSize parameter to fetch events: %s", err.Error()), "FindEvents")
+	}
+
+	return e.getCommon(fmt.Sprintf("%s?%s", e.basePath, queryParamsValues.Encode()))
 }
 
 func (e *events) NextPage(c *EventCollection) (*EventCollection, *generic.Error) {
@@ -221,7 +347,10 @@ func (e *events) getPage(reference string) (*EventCollection, *generic.Error) {
 }
 
 func (e *events) getCommon(path string) (*EventCollection, *generic.Error) {
-	body, status, err := e.client.Get(path, generic.EmptyHeader())
+	body, status, err := e.client.Get(path, generic.AcceptHeader(EVENT_COLLECTION_TYPE))
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while getting events: %s", err.Error()), "GetEventCollection")
+	}
 
 	if status != http.StatusOK {
 		return nil, generic.CreateErrorFromResponse(body, status)
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var eventBinary = `{
+	"id": "5555",
+	"self": "https://t0815.cumulocity.com/event/events/1337/binaries",
+	"source": "1337",
+	"name": "snapshot.jpg",
+	"type": "image/jpeg",
+	"length": 11
+}`
+
+// Records method, path, content type and body of the request and responds with the given status and body.
+func buildBinaryHttpServer(status int, contentType string, response string) (*httptest.Server, *[]string) {
+	var captured []string
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		captured = []string{r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(body)}
+
+		if len(contentType) > 0 {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	})), &captured
+}
+
+func TestEvents_UploadBinary(t *testing.T) {
+	// given: a test server
+	ts, captured := buildBinaryHttpServer(http.StatusCreated, "", eventBinary)
+	defer ts.Close()
+
+	// when
+	binary, err := buildEventsApi(ts.URL).UploadBinary("1337", "image/jpeg", strings.NewReader("jpeg binary"))
+
+	// then
+	if err != nil {
+		t.Fatalf("UploadBinary() got an unexpected error: %s", err.Error())
+	}
+	expected := []string{http.MethodPost, "/event/events/1337/binaries", "image/jpeg", "jpeg binary"}
+	if strings.Join(*captured, "|") != strings.Join(expected, "|") {
+		t.Errorf("UploadBinary() sent %v, want %v", *captured, expected)
+	}
+	if binary.Id != "5555" || binary.Source != "1337" || binary.Type != "image/jpeg" || binary.Length != 11 {
+		t.Errorf("UploadBinary() = %v", binary)
+	}
+}
+
+func TestEvents_ReplaceBinary_DefaultContentType(t *testing.T) {
+	ts, captured := buildBinaryHttpServer(http.StatusCreated, "", eventBinary)
+	defer ts.Close()
+
+	_, err := buildEventsApi(ts.URL).ReplaceBinary("1337", "", bytes.NewReader([]byte{1, 2, 3}))
+
+	if err != nil {
+		t.Fatalf("ReplaceBinary() got an unexpected error: %s", err.Error())
+	}
+	if (*captured)[0] != http.MethodPut || (*captured)[2] != DEFAULT_BINARY_CONTENT_TYPE {
+		t.Errorf("ReplaceBinary() sent %v", *captured)
+	}
+}
+
+func TestEvents_UploadBinary_Conflict(t *testing.T) {
+	ts, _ := buildBinaryHttpServer(http.StatusConflict, "", `{"error": "event/Conflict", "message": "Binary already exists"}`)
+	defer ts.Close()
+
+	_, err := buildEventsApi(ts.URL).UploadBinary("1337", "text/plain", strings.NewReader("log"))
+
+	if err == nil || err.ErrorType != "409: event/Conflict" {
+		t.Errorf("UploadBinary() error = %v, want a conflict", err)
+	}
+}
+
+func TestEvents_UploadBinary_Invalid(t *testing.T) {
+	api := buildEventsApi("http://localhost")
+
+	if _, err := api.UploadBinary("", "text/plain", strings.NewReader("log")); err == nil {
+		t.Errorf("UploadBinary() without event id got no error")
+	}
+	if _, err := api.UploadBinary("1337", "text/plain", nil); err == nil {
+		t.Errorf("UploadBinary() without binary got no error")
+	}
+}
+
+func TestEvents_DownloadBinary(t *testing.T) {
+	ts, captured := buildBinaryHttpServer(http.StatusOK, "image/jpeg", "jpeg binary")
+	defer ts.Close()
+	var buffer bytes.Buffer
+
+	contentType, err := buildEventsApi(ts.URL).DownloadBinary("1337", &buffer)
+
+	if err != nil {
+		t.Fatalf("DownloadBinary() got an unexpected error: %s", err.Error())
+	}
+	if buffer.String() != "jpeg binary" || contentType != "image/jpeg" {
+		t.Errorf("DownloadBinary() = %s, %s", contentType, buffer.String())
+	}
+	if (*captured)[0] != http.MethodGet || (*captured)[1] != "/event/events/1337/binaries" {
+		t.Errorf("DownloadBinary() sent %v", *captured)
+	}
+}
+
+func TestEvents_DownloadBinary_NotFound(t *testing.T) {
+	ts, _ := buildBinaryHttpServer(http.StatusNotFound, "", `{"error": "event/Not Found", "message": "Binary not found"}`)
+	defer ts.Close()
+	var buffer bytes.Buffer
+
+	_, err := buildEventsApi(ts.URL).DownloadBinary("1337", &buffer)
+
+	if err == nil || err.ErrorType != "404: event/Not Found" || buffer.Len() > 0 {
+		t.Errorf("DownloadBinary() error = %v, want not found", err)
+	}
+}
+
+func TestEvents_DeleteBinary(t *testing.T) {
+	ts, captured := buildBinaryHttpServer(http.StatusNoContent, "", "")
+	defer ts.Close()
+
+	err := buildEventsApi(ts.URL).DeleteBinary("1337")
+
+	if err != nil {
+		t.Fatalf("DeleteBinary() got an unexpected error: %s", err.Error())
+	}
+	if (*captured)[0] != http.MethodDelete || (*captured)[1] != "/event/events/1337/binaries" {
+		t.Errorf("DeleteBinary() sent %v", *captured)
+	}
+}
@@ -48,3 +48,56 @@ func TestEvents_Delete_Event_NotFound(t *testing.T) {
 		return
 	}
 }
+
+func TestEvents_DeleteMany(t *testing.T) {
+	var capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	err := buildEventsApi(ts.URL).DeleteMany(&EventQuery{Source: "4711", Type: "Type_1"})
+
+	if err != nil {
+		t.Fatalf("DeleteMany() got an unexpected error: %s", err.Error())
+	}
+	if capturedUrl != "/event/events?source=4711&type=Type_1" {
+		t.Errorf("DeleteMany() url = %s, want %s", capturedUrl, "/event/events?source=4711&type=Type_1")
+	}
+}
+
+func TestEvents_DeleteMany_WithoutFilter(t *testing.T) {
+	var called bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	api := buildEventsApi(ts.URL)
+
+	if err := api.DeleteMany(nil); err == nil {
+		t.Errorf("DeleteMany() with nil query expected an error")
+	}
+	if err := api.DeleteMany(&EventQuery{}); err == nil {
+		t.Errorf("DeleteMany() with empty query expected an error")
+	}
+	if called {
+		t.Errorf("DeleteMany() without filter must not send a request")
+	}
+}
+
+func TestEvents_DeleteAll(t *testing.T) {
+	var capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	err := buildEventsApi(ts.URL).DeleteAll()
+
+	if err != nil || capturedUrl != "/event/events" {
+		t.Errorf("DeleteAll() = %v with url %s", err, capturedUrl)
+	}
+}
@@ -102,21 +102,78 @@ func TestEvents_FindWithFilter(t *testing.T) {
 			EventQuery{Source: "4711", DateFrom: &dateFrom, DateTo: &dateTo},
 			"dateFrom=2020-06-01T01%3A00%3A00Z&dateTo=2020-06-30T01%3A00%3A00Z&source=4711",
 		},
+		{
+			"ForCreated",
+			EventQuery{CreatedFrom: &dateFrom, CreatedTo: &dateTo},
+			"createdFrom=2020-06-01T01%3A00%3A00Z&createdTo=2020-06-30T01%3A00%3A00Z",
+		},
+		{
+			"ForLastUpdated",
+			EventQuery{LastUpdatedFrom: &dateFrom, LastUpdatedTo: &dateTo},
+			"lastUpdatedFrom=2020-06-01T01%3A00%3A00Z&lastUpdatedTo=2020-06-30T01%3A00%3A00Z",
+		},
+		{
+			"ForFragmentValue",
+			EventQuery{FragmentType: "c8y_Status", FragmentValue: "online"},
+			"fragmentType=c8y_Status&fragmentValue=online",
+		},
+		{
+			"ForSourceWithAssetsAndDevices",
+			EventQuery{Source: "4711", WithSourceAssets: true, WithSourceDevices: true},
+			"source=4711&withSourceAssets=true&withSourceDevices=true",
+		},
+		{
+			"ForTimeReverted",
+			EventQuery{DateFrom: &dateFrom, Revert: true},
+			"dateFrom=2020-06-01T01%3A00%3A00Z&revert=true",
+		},
 	}
 
 	api := buildEventsApi(ts.URL)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			api.Find(tt.query)
+			_, _ = api.Find(&tt.query, 5)
 			cUrl, err := url.Parse(capturedUrl)
 
 			if err != nil {
 				t.Fatalf("Find() - The captured URL is invalid - URL: %s, error: %s", capturedUrl, err.Error())
 			}
 
-			if cUrl.RawQuery != tt.expectedQuery {
-				t.Errorf("Find() = %v, want %v", cUrl.RawQuery, tt.expectedQuery)
+			values := cUrl.Query()
+			if values.Get("pageSize") != "5" {
+				t.Errorf("Find() pageSize = %v, want %v", values.Get("pageSize"), 5)
+			}
+			values.Del("pageSize")
+			if values.Encode() != tt.expectedQuery {
+				t.Errorf("Find() = %v, want %v", values.Encode(), tt.expectedQuery)
+			}
+		})
+	}
+}
+
+func TestEvents_Find_InvalidQuery(t *testing.T) {
+	dateFrom, _ := time.Parse(time.RFC3339, "2020-06-01T01:00:00.00Z")
+	dateTo, _ := time.Parse(time.RFC3339, "2020-06-30T01:00:00.00Z")
+
+	tests := []struct {
+		name  string
+		query EventQuery
+	}{
+		{"DateToBeforeDateFrom", EventQuery{DateFrom: &dateTo, DateTo: &dateFrom}},
+		{"FragmentValueWithoutFragmentType", EventQuery{FragmentValue: "online"}},
+		{"WithSourceAssetsWithoutSource", EventQuery{WithSourceAssets: true}},
+		{"WithSourceDevicesWithoutSource", EventQuery{WithSourceDevices: true}},
+		{"RevertWithoutDate", EventQuery{Revert: true}},
+	}
+
+	api := buildEventsApi("http://localhost")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.Find(&tt.query, 5)
+
+			if err == nil {
+				t.Errorf("Find() error expected but was nil")
 			}
 		})
 	}
@@ -129,7 +186,7 @@ func TestEvents_Find_HandlesPageSize(t *testing.T) {
 		errExpected bool
 	}{
 		{"Negative", -1, true},
-		{"Zero", 0, false},
+		{"Zero", 0, true},
 		{"Max", 2000, false},
 		{"too large", 2001, true},
 		{"in range", 10, false},
@@ -147,28 +204,19 @@ func TestEvents_Find_HandlesPageSize(t *testing.T) {
 	api := buildEventsApi(ts.URL)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			query := EventQuery{
-				Source:   deviceId,
-				PageSize: tt.pageSize,
-			}
-			_, err := api.Find(query)
+			query := &EventQuery{Source: deviceId}
+			_, err := api.Find(query, tt.pageSize)
 
 			if tt.errExpected {
 				if err == nil {
-					t.Error("GetForDevice() error expected but was nil")
+					t.Error("Find() error expected but was nil")
 				}
 			}
 
 			if !tt.errExpected {
-				contains := strings.Contains(capturedUrl, fmt.Sprintf("pageSize=%d", tt.pageSize))
-
-				if tt.pageSize != 0 && !contains {
+				if !strings.Contains(capturedUrl, fmt.Sprintf("pageSize=%d", tt.pageSize)) {
 					t.Errorf("Find() expected pageSize '%d' in url. '%s' given", tt.pageSize, capturedUrl)
 				}
-
-				if tt.pageSize == 0 && contains {
-					t.Error("Find() expected no pageSize in url on value 0")
-				}
 			}
 		})
 	}
@@ -182,7 +230,7 @@ func TestEvents_ReturnsCollection(t *testing.T) {
 
 	api := buildEventsApi(ts.URL)
 
-	collection, err := api.Find(EventQuery{})
+	collection, err := api.Find(&EventQuery{}, 5)
 
 	if err != nil {
 		t.Fatalf("Find() - Error given but no expected")
@@ -206,7 +254,7 @@ func TestEvents_ReturnsCollection_CustomElements(t *testing.T) {
 
 	api := buildEventsApi(ts.URL)
 
-	collection, err := api.Find(EventQuery{})
+	collection, err := api.Find(&EventQuery{}, 5)
 
 	if err != nil {
 		t.Fatalf("Find() - Error given but no expected")
@@ -251,7 +299,7 @@ func TestEvents_FindReturnsError(t *testing.T) {
 
 	api := buildEventsApi(ts.URL)
 
-	_, err := api.Find(EventQuery{})
+	_, err := api.Find(&EventQuery{}, 5)
 
 	if err == nil {
 		t.Fatalf("Find() - Error expected")
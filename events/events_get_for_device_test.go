@@ -85,7 +85,7 @@ func TestEvents_GetForDevice_HandlesPageSize(t *testing.T) {
 		errExpected bool
 	}{
 		{"Negative", -1, true},
-		{"Zero", 0, false},
+		{"Zero", 0, true},
 		{"Max", 2000, false},
 		{"too large", 2001, true},
 		{"in range", 10, false},
@@ -112,15 +112,9 @@ func TestEvents_GetForDevice_HandlesPageSize(t *testing.T) {
 			}
 
 			if !tt.errExpected {
-				contains := strings.Contains(capturedUrl, fmt.Sprintf("pageSize=%d", tt.pageSize))
-
-				if tt.pageSize != 0 && !contains {
+				if !strings.Contains(capturedUrl, fmt.Sprintf("pageSize=%d", tt.pageSize)) {
 					t.Errorf("GetForDevice() expected pageSize '%d' in url. '%s' given", tt.pageSize, capturedUrl)
 				}
-
-				if tt.pageSize == 0 && contains {
-					t.Error("GetForDevice() expected no pageSize in url on value 0")
-				}
 			}
 		})
 	}
@@ -40,7 +40,7 @@ func TestEvents_Update_Event_Success_SendsData(t *testing.T) {
 	}
 
 	header := requestCapture.Header.Get("Accept")
-	want := "application/vnd.com.nsn.cumulocity.eventApi+json"
+	want := EVENT_TYPE
 	if header != want {
 		t.Errorf("UpdateEvent() accent header = %v, want %v", header, want)
 	}
@@ -2,6 +2,7 @@ package generic
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -52,6 +53,31 @@ func (client *Client) Get(path string, header map[string][]string) ([]byte, int,
 	return client.request(http.MethodGet, path, []byte{}, header)
 }
 
+// Sends a request streaming the body from the given reader and returns the response without reading its body,
+// so large binaries are neither buffered on upload nor on download. The caller must close the response body.
+// body - may be nil for requests without body.
+func (client *Client) Stream(method, path string, body io.Reader, header map[string][]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, client.BaseURL+path, body)
+	if err != nil {
+		log.Printf("Error while creating a request: %s", err.Error())
+		return nil, err
+	}
+
+	req.SetBasicAuth(client.Username, client.Password)
+	for header, values := range header {
+		for _, value := range values {
+			req.Header.Add(header, value)
+		}
+	}
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		log.Printf("An error occured: %s", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (client *Client) request(method, path string, body []byte, header map[string][]string) ([]byte, int, error) {
 	url := client.BaseURL + path
 	//log.Printf("HTTP %s on URL %s", method, url)
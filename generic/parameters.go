@@ -0,0 +1,17 @@
+package generic
+
+import (
+	"net/url"
+	"time"
+)
+
+// Appends the query params of a time range, ex. 'dateFrom' and 'dateTo', to the provided parameter values for a request.
+// Times, which are nil, are left out.
+func TimeRangeParameters(fromName string, from *time.Time, toName string, to *time.Time, params *url.Values) {
+	if from != nil {
+		params.Add(fromName, from.Format(time.RFC3339))
+	}
+	if to != nil {
+		params.Add(toName, to.Format(time.RFC3339))
+	}
+}
@@ -6,4 +6,5 @@ require (
 	github.com/gorilla/websocket v1.4.2
 	github.com/orasik/gocomparejson v0.0.0-20171229174629-2835e0393d0f
 	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -12,7 +12,7 @@ import (
 
 const (
 	IDENTITY_TYPE               = "application/vnd.com.nsn.cumulocity.identityApi+json"
-	EXTERNAL_ID_COLLECTION_TYPE = "application/vnd.com.nsn.cumulocity.exteralIdCollection+json"
+	EXTERNAL_ID_COLLECTION_TYPE = "application/vnd.com.nsn.cumulocity.externalIdCollection+json"
 	EXTERNAL_ID_TYPE            = "application/vnd.com.nsn.cumulocity.externalId+json"
 )
 
@@ -44,6 +44,7 @@ type NewExternalID struct {
 type IdentityAPI interface {
 	GetIdentity() (*Identity, *generic.Error)
 	GetExternalID(externalIDType, externalID string) (*ExternalID, *generic.Error)
+	GetExternalIDs(managedObjectID string) (*ExternalIDCollection, *generic.Error)
 	CreateExternalID(ID NewExternalID, deviceID string) (ExternalID, *generic.Error)
 	DeleteExternalID(externalIDType, externalID string) *generic.Error
 }
@@ -107,7 +108,7 @@ func (i identityAPI) CreateExternalID(externalId NewExternalID, deviceID string)
 }
 
 func (i identityAPI) GetExternalID(externalIDtype string, externalID string) (*ExternalID, *generic.Error) {
-	body, status, err := i.client.Get(fmt.Sprintf("%s/%s/%s/%s", i.basePath, url.QueryEscape("extrenalIds"), url.QueryEscape(externalIDtype), url.QueryEscape(externalID)), generic.AcceptHeader(IDENTITY_TYPE))
+	body, status, err := i.client.Get(fmt.Sprintf("%s/%s/%s/%s", i.basePath, "externalIds", url.QueryEscape(externalIDtype), url.QueryEscape(externalID)), generic.AcceptHeader(EXTERNAL_ID_TYPE))
 
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while getting an externalID: %s", err.Error()), "get")
@@ -127,6 +128,28 @@ func (i identityAPI) GetExternalID(externalIDtype string, externalID string) (*E
 	return &result, nil
 }
 
+// Returns all external ids of the managed object.
+func (i identityAPI) GetExternalIDs(managedObjectID string) (*ExternalIDCollection, *generic.Error) {
+	if len(managedObjectID) == 0 {
+		return nil, generic.ClientError("Getting externalIDs without a managedObject id is not allowed", "GetExternalIDs")
+	}
+
+	body, status, err := i.client.Get(fmt.Sprintf("%s/globalIds/%s/externalIds", i.basePath, url.QueryEscape(managedObjectID)), generic.AcceptHeader(EXTERNAL_ID_COLLECTION_TYPE))
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while getting the externalIDs: %s", err.Error()), "GetExternalIDs")
+	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+	result := ExternalIDCollection{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), "ResponseParser")
+	}
+
+	return &result, nil
+}
+
 func (i identityAPI) DeleteExternalID(externalIDType, externalID string) *generic.Error {
 	if len(externalIDType) == 0 || len(externalID) == 0 {
 		return generic.ClientError("Deleting deviceRegistrations without an id is not allowed", "DeleteDeviceRegistration")
@@ -1,6 +1,8 @@
 package identity
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -44,3 +46,61 @@ func TestIdentity_Get_Nonexisting_ExternalId(t *testing.T) {
 		t.Fatalf("GetExternalId() returned a wrong Identity")
 	}
 }
+
+func TestIdentity_Get_ExternalId_RequestsExternalIdPath(t *testing.T) {
+	// given: A test server capturing the request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCapture = r
+		_, _ = w.Write([]byte(externalID))
+	}))
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildIdentityAPI(ts.URL)
+
+	_, err := api.GetExternalID("c8y_Serial", "4711")
+
+	if err != nil {
+		t.Fatalf("GetExternalId() got an unexpected error: %s", err.Error())
+	}
+	if requestCapture.URL.Path != "/identity/externalIds/c8y_Serial/4711" {
+		t.Errorf("GetExternalId() requested %s", requestCapture.URL.Path)
+	}
+	if requestCapture.Header.Get("Accept") != EXTERNAL_ID_TYPE {
+		t.Errorf("GetExternalId() accepts %s", requestCapture.Header.Get("Accept"))
+	}
+}
+
+func TestIdentity_Get_ExternalIds(t *testing.T) {
+	// given: A test server capturing the request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCapture = r
+		_, _ = w.Write([]byte(`{"self": "selfUrl", "externalIds": [` + externalID + `, {"externalId": "4711", "type": "c8y_Serial"}]}`))
+	}))
+	defer ts.Close()
+
+	// and: the api as system under test
+	api := buildIdentityAPI(ts.URL)
+
+	collection, err := api.GetExternalIDs("104")
+
+	if err != nil {
+		t.Fatalf("GetExternalIds() got an unexpected error: %s", err.Error())
+	}
+	if requestCapture.URL.Path != "/identity/globalIds/104/externalIds" {
+		t.Errorf("GetExternalIds() requested %s", requestCapture.URL.Path)
+	}
+	if len(collection.ExternalIds) != 2 || collection.ExternalIds[1].ExternalId != "4711" || collection.ExternalIds[1].Type != "c8y_Serial" {
+		t.Errorf("GetExternalIds() = %v", collection)
+	}
+}
+
+func TestIdentity_Get_ExternalIds_WithoutId(t *testing.T) {
+	api := buildIdentityAPI("http://localhost")
+
+	collection, err := api.GetExternalIDs("")
+
+	if err == nil || collection != nil {
+		t.Errorf("GetExternalIds() = %v, %v; expected an error", collection, err)
+	}
+}
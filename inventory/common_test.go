@@ -35,6 +35,17 @@ func buildInventoryReferenceApi(testServer *httptest.Server) InventoryReferenceA
 	return NewInventoryReferenceApi(c)
 }
 
+func buildInventoryBinaryApi(testServer *httptest.Server) InventoryBinaryApi {
+	c := &generic.Client{
+		HTTPClient: testServer.Client(),
+		BaseURL:    testServer.URL,
+		Username:   USER,
+		Password:   PASSWORD,
+	}
+
+	return NewInventoryBinaryApi(c)
+}
+
 var newManagedObject = &NewManagedObject{
 	Type:         "test-type",
 	Name:         "Test Device",
@@ -47,7 +58,7 @@ var managedObjectUpdate = &ManagedObjectUpdate{
 }
 var expectedUpdateRequestBody = `{"type":"updated test-type","name":"updated Test Device"}`
 
-var expectedRequestBody = `{"type":"test-type","name":"Test Device","creationTime":"2020-07-03T10:16:35.87+02:00", "c8y_IsDevice":null}`
+var expectedRequestBody = `{"type":"test-type","name":"Test Device","creationTime":"2020-07-03T10:16:35.87+02:00"}`
 var managedObjectId = "9963944"
 var referenceId = "4711"
 var query = "$filter=name eq '*Test*' $orderby=id desc"
@@ -68,19 +79,12 @@ var expectedManagedObject = &ManagedObject{
 	LastUpdated:     creationTime,
 	Self:            "https://t0815.cumulocity.com/inventory/managedObjects/9963944",
 	Owner:           "gomulocity",
-	AdditionParents: AdditionParents{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/additionParents"},
-	AssetParents:    AssetParents{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/assetParents"},
-	DeviceParents:   DeviceParents{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/deviceParents"},
-	ChildAdditions: ChildAdditions{References: []struct {
-		ManagedObject struct {
-			Id   string "json:\"id,omitempty\""
-			Name string "json:\"name,omitempty\""
-			Self string "json:\"self,omitempty\""
-		} "json:\"managedObject,omitempty\""
-		Self string "json:\"self,omitempty\""
-	}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAdditions"},
-	ChildAssets:  ChildAssets{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAssets"},
-	ChildDevices: ChildDevices{References: []interface{}{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childDevices"},
+	AdditionParents: AdditionParents{References: []ManagedObjectReference{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/additionParents"},
+	AssetParents:    AssetParents{References: []ManagedObjectReference{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/assetParents"},
+	DeviceParents:   DeviceParents{References: []ManagedObjectReference{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/deviceParents"},
+	ChildAdditions:  ChildAdditions{References: []ManagedObjectReference{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAdditions"},
+	ChildAssets:     ChildAssets{References: []ManagedObjectReference{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childAssets"},
+	ChildDevices:    ChildDevices{References: []ManagedObjectReference{}, Self: "https://t0815.cumulocity.com/inventory/managedObjects/9963944/childDevices"},
 	AdditionalFields: map[string]interface{}{
 		"custom": "hello",
 	},
@@ -25,6 +25,9 @@ type InventoryApi interface {
 
 	Update(managedObjectId string, managedObject *ManagedObjectUpdate) (*ManagedObject, *generic.Error)
 
+	// Returns the measurement series of a managed object as "<fragment>.<series>", ex. "c8y_TemperatureMeasurement.T".
+	SupportedSeries(managedObjectId string) ([]string, *generic.Error)
+
 	// Deletion by managedObject id. If error is nil, managed object was deleted successfully.
 	Delete(managedObjectId string) *generic.Error
 
@@ -64,7 +67,10 @@ Creates a new managed object based on the given variables.
 See: https://cumulocity.com/guides/reference/inventory/#post-create-a-new-managedobject
 */
 func (inventoryApi *inventoryApi) Create(newManagedObject *NewManagedObject) (*ManagedObject, *generic.Error) {
-	bytes, err := json.Marshal(newManagedObject)
+	if newManagedObject == nil {
+		return nil, generic.ClientError("Creating a managedObject without a managedObject is not allowed", "CreateManagedObject")
+	}
+	bytes, err := generic.JsonFromObject(newManagedObject)
 	if err != nil {
 		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the managedObject: %s", err.Error()), "CreateManagedObject")
 	}
@@ -107,6 +113,34 @@ func (inventoryApi *inventoryApi) Get(managedObjectId string) (*ManagedObject, *
 	return parseManagedObjectResponse(body)
 }
 
+/*
+Gets the measurement series, which were sent for the managedObject with the given Id.
+
+See: https://cumulocity.com/guides/reference/inventory/#supported-series
+*/
+func (inventoryApi *inventoryApi) SupportedSeries(managedObjectId string) ([]string, *generic.Error) {
+	if len(managedObjectId) == 0 {
+		return nil, generic.ClientError("managedObjectId must not be empty", "GetSupportedSeries")
+	}
+
+	path := fmt.Sprintf("%s/%s/supportedSeries", inventoryApi.basePath, url.QueryEscape(managedObjectId))
+	body, status, err := inventoryApi.client.Get(path, generic.AcceptHeader("application/json"))
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while getting the supported series: %s", err.Error()), "GetSupportedSeries")
+	}
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	var result struct {
+		SupportedSeries []string `json:"c8y_SupportedSeries"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while parsing the supported series: %s", err.Error()), "GetSupportedSeries")
+	}
+	return result.SupportedSeries, nil
+}
+
 /*
 Updates the managedObject with given Id.
 
@@ -0,0 +1,48 @@
+package inventory
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInventoryApi_SupportedSeries(t *testing.T) {
+	// given
+	var reqURL, reqAccept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqURL = r.URL.String()
+		reqAccept = r.Header.Get("Accept")
+		_, _ = w.Write([]byte(`{"c8y_SupportedSeries": ["c8y_TemperatureMeasurement.T", "c8y_Battery.level"]}`))
+	}))
+	defer ts.Close()
+
+	// when
+	series, err := buildInventoryApi(ts).SupportedSeries("4711")
+
+	// then
+	if err != nil {
+		t.Fatalf("SupportedSeries() got an unexpected error: %s", err)
+	}
+	if strings.Join(series, ",") != "c8y_TemperatureMeasurement.T,c8y_Battery.level" {
+		t.Errorf("SupportedSeries() = %v", series)
+	}
+	if reqURL != "/inventory/managedObjects/4711/supportedSeries" || reqAccept != "application/json" {
+		t.Errorf("Unexpected request %s with accept header %s", reqURL, reqAccept)
+	}
+}
+
+func TestInventoryApi_SupportedSeries_Errors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "inventory/Not Found", "message": "Finding device data from database failed"}`))
+	}))
+	defer ts.Close()
+
+	if _, err := buildInventoryApi(ts).SupportedSeries(""); err == nil || err.Message != "managedObjectId must not be empty" {
+		t.Errorf("SupportedSeries() without id got an unexpected error: %v", err)
+	}
+	if _, err := buildInventoryApi(ts).SupportedSeries("4711"); err == nil || !strings.HasPrefix(err.ErrorType, "404") {
+		t.Errorf("SupportedSeries() of missing managed object got an unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package inventory
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"time"
+)
+
+const (
+	INVENTORY_BINARY_API_PATH = "/inventory/binaries"
+
+	BINARY_DEFAULT_CONTENT_TYPE = "application/octet-stream"
+)
+
+/*
+Represents the managed object describing a binary in the file repository.
+See: https://cumulocity.com/guides/reference/binaries/
+*/
+type Binary struct {
+	Id          string     `json:"id"`
+	Name        string     `json:"name,omitempty"`
+	Type        string     `json:"type,omitempty"` // Content type of the binary
+	ContentType string     `json:"contentType,omitempty"`
+	Length      int64      `json:"length,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+	Self        string     `json:"self,omitempty"`
+
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
+}
+
+type BinaryCollection struct {
+	Self       string                    `json:"self"`
+	Binaries   []Binary                  `json:"managedObjects" jsonc:"collection"`
+	Statistics *generic.PagingStatistics `json:"statistics,omitempty"`
+	Prev       string                    `json:"prev,omitempty"`
+	Next       string                    `json:"next,omitempty"`
+}
+
+// The meta data of a binary to upload. When no Type is given, BINARY_DEFAULT_CONTENT_TYPE is used.
+type NewBinary struct {
+	Name             string                 `json:"name"`
+	Type             string                 `json:"type"`
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
+}
+
+type BinaryFilter struct {
+	Type            string   // Content type of the binaries
+	Owner           string   // Owner of the binaries
+	Text            string   // Text contained in the name of the binaries
+	ChildAdditionId string   // Only binaries, which are child additions of this managed object
+	Ids             []string // Ids of the binaries
+}
+
+// Appends the filter query parameters to the provided parameter values for a request.
+// When provided values is nil an error will be created
+func (binaryFilter BinaryFilter) QueryParams(params *url.Values) error {
+	if params == nil {
+		return fmt.Errorf("The provided parameter values must not be nil!")
+	}
+
+	filter := InventoryFilter{
+		Type:            binaryFilter.Type,
+		Ids:             binaryFilter.Ids,
+		Text:            binaryFilter.Text,
+		Owner:           binaryFilter.Owner,
+		ChildAdditionId: binaryFilter.ChildAdditionId,
+	}
+	return filter.QueryParams(params)
+}
+
+type InventoryBinaryApi interface {
+	// Uploads a new binary. The content is streamed from the reader and returns the created binary managed object.
+	Upload(newBinary *NewBinary, content io.Reader) (*Binary, *generic.Error)
+
+	// Streams the content of a binary into the writer and returns its content type.
+	Download(binaryId string, writer io.Writer) (string, *generic.Error)
+
+	// Replaces the content of a binary. The content is streamed from the reader.
+	Replace(binaryId string, contentType string, content io.Reader) (*Binary, *generic.Error)
+
+	// Deletion by binary id. If error is nil, the binary was deleted successfully.
+	Delete(binaryId string) *generic.Error
+
+	// Returns a collection of binary managed objects, found by the given filter parameters.
+	// All query parameters are AND concatenated.
+	Find(binaryFilter *BinaryFilter, pageSize int) (*BinaryCollection, *generic.Error)
+
+	// Gets the next page from an existing binary collection.
+	// If there is no next page, nil is returned.
+	NextPage(c *BinaryCollection) (*BinaryCollection, *generic.Error)
+
+	// Gets the previous page from an existing binary collection.
+	// If there is no previous page, nil is returned.
+	PreviousPage(c *BinaryCollection) (*BinaryCollection, *generic.Error)
+}
+
+type inventoryBinaryApi struct {
+	client   *generic.Client
+	basePath string
+}
+
+// Creates a new inventory binary api object
+//
+// client - Must be a gomulocity client.
+// returns - The `InventoryBinaryApi` object
+func NewInventoryBinaryApi(client *generic.Client) InventoryBinaryApi {
+	return &inventoryBinaryApi{client, INVENTORY_BINARY_API_PATH}
+}
+
+/*
+Uploads a new binary as multipart form with the meta data as `object` and the content as `file` part.
+The form is streamed, the content is not loaded into memory.
+
+See: https://cumulocity.com/guides/reference/binaries/#post-upload-a-file
+*/
+func (binaryApi *inventoryBinaryApi) Upload(newBinary *NewBinary, content io.Reader) (*Binary, *generic.Error) {
+	if newBinary == nil || len(newBinary.Name) == 0 {
+		return nil, generic.ClientError("Uploading a binary without name is not allowed", "UploadBinary")
+	}
+	if content == nil {
+		return nil, generic.ClientError("Uploading a binary without content is not allowed", "UploadBinary")
+	}
+	object := *newBinary
+	if len(object.Type) == 0 {
+		object.Type = BINARY_DEFAULT_CONTENT_TYPE
+	}
+	objectJson, err := generic.JsonFromObject(&object)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while marshalling the binary: %s", err.Error()), "UploadBinary")
+	}
+
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeBinaryForm(form, objectJson, object, content))
+	}()
+	defer reader.Close()
+
+	headers := generic.AcceptAndContentTypeHeader(MANAGED_OBJECT_TYPE, form.FormDataContentType())
+	response, err := binaryApi.client.Stream(http.MethodPost, binaryApi.basePath, reader, headers)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while uploading a binary: %s", err.Error()), "UploadBinary")
+	}
+	return parseBinaryStreamResponse(response, http.StatusCreated, "UploadBinary")
+}
+
+/*
+Streams the content of a binary into the writer. Returns the content type of the binary.
+
+See: https://cumulocity.com/guides/reference/binaries/#get-download-a-file
+*/
+func (binaryApi *inventoryBinaryApi) Download(binaryId string, writer io.Writer) (string, *generic.Error) {
+	if len(binaryId) == 0 {
+		return "", generic.ClientError("Downloading a binary without an id is not allowed", "DownloadBinary")
+	}
+	if writer == nil {
+		return "", generic.ClientError("Downloading a binary without writer is not allowed", "DownloadBinary")
+	}
+
+	response, err := binaryApi.client.Stream(http.MethodGet, binaryApi.binaryPath(binaryId), nil, generic.AcceptHeader("*/*"))
+	if err != nil {
+		return "", generic.ClientError(fmt.Sprintf("Error while downloading a binary: %s", err.Error()), "DownloadBinary")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return "", generic.CreateErrorFromResponse(body, response.StatusCode)
+	}
+
+	if _, err := io.Copy(writer, response.Body); err != nil {
+		return "", generic.ClientError(fmt.Sprintf("Error while downloading a binary: %s", err.Error()), "DownloadBinary")
+	}
+
+	return response.Header.Get("Content-Type"), nil
+}
+
+/*
+Replaces the content of a binary. The content is sent as request body with the given content type.
+When no content type is given, BINARY_DEFAULT_CONTENT_TYPE is used.
+
+See: https://cumulocity.com/guides/reference/binaries/#put-replace-a-file
+*/
+func (binaryApi *inventoryBinaryApi) Replace(binaryId string, contentType string, content io.Reader) (*Binary, *generic.Error) {
+	if len(binaryId) == 0 {
+		return nil, generic.ClientError("Replacing a binary without an id is not allowed", "ReplaceBinary")
+	}
+	if content == nil {
+		return nil, generic.ClientError("Replacing a binary without content is not allowed", "ReplaceBinary")
+	}
+	if len(contentType) == 0 {
+		contentType = BINARY_DEFAULT_CONTENT_TYPE
+	}
+
+	headers := generic.AcceptAndContentTypeHeader(MANAGED_OBJECT_TYPE, contentType)
+	response, err := binaryApi.client.Stream(http.MethodPut, binaryApi.binaryPath(binaryId), content, headers)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while replacing a binary: %s", err.Error()), "ReplaceBinary")
+	}
+	return parseBinaryStreamResponse(response, http.StatusCreated, "ReplaceBinary")
+}
+
+/*
+Deletes the binary and its managed object.
+
+See: https://cumulocity.com/guides/reference/binaries/#delete-remove-a-stored-file
+*/
+func (binaryApi *inventoryBinaryApi) Delete(binaryId string) *generic.Error {
+	if len(binaryId) == 0 {
+		return generic.ClientError("Deleting a binary without an id is not allowed", "DeleteBinary")
+	}
+
+	body, status, err := binaryApi.client.Delete(binaryApi.binaryPath(binaryId), generic.EmptyHeader())
+	if err != nil {
+		return generic.ClientError(fmt.Sprintf("Error while deleting binary with id [%s]: %s", binaryId, err.Error()), "DeleteBinary")
+	}
+
+	if status != http.StatusNoContent {
+		return generic.CreateErrorFromResponse(body, status)
+	}
+
+	return nil
+}
+
+/*
+Returns a collection of binary managed objects.
+
+See: https://cumulocity.com/guides/reference/binaries/#get-a-representation-of-a-file-collection
+*/
+func (binaryApi *inventoryBinaryApi) Find(binaryFilter *BinaryFilter, pageSize int) (*BinaryCollection, *generic.Error) {
+	if binaryFilter == nil {
+		binaryFilter = &BinaryFilter{}
+	}
+	queryParamsValues := &url.Values{}
+	err := binaryFilter.QueryParams(queryParamsValues)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while building query parameters to search for binaries: %s", err.Error()), "FindBinaries")
+	}
+
+	err = generic.PageSizeParameter(pageSize, queryParamsValues)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while building pageSize parameter to fetch binaries: %s", err.Error()), "FindBinaries")
+	}
+
+	return binaryApi.getCommon(fmt.Sprintf("%s?%s", binaryApi.basePath, queryParamsValues.Encode()))
+}
+
+func (binaryApi *inventoryBinaryApi) NextPage(c *BinaryCollection) (*BinaryCollection, *generic.Error) {
+	return binaryApi.getPage(c.Next)
+}
+
+func (binaryApi *inventoryBinaryApi) PreviousPage(c *BinaryCollection) (*BinaryCollection, *generic.Error) {
+	return binaryApi.getPage(c.Prev)
+}
+
+// -- internal
+
+func (binaryApi *inventoryBinaryApi) binaryPath(binaryId string) string {
+	return fmt.Sprintf("%s/%s", binaryApi.basePath, url.QueryEscape(binaryId))
+}
+
+func (binaryApi *inventoryBinaryApi) getPage(reference string) (*BinaryCollection, *generic.Error) {
+	if reference == "" {
+		log.Print("No page reference given. Returning nil.")
+		return nil, nil
+	}
+
+	nextUrl, err := url.Parse(reference)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Unparsable URL given for page reference: '%s'", reference), "GetPage")
+	}
+
+	collection, genErr := binaryApi.getCommon(fmt.Sprintf("%s?%s", nextUrl.Path, nextUrl.RawQuery))
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	if len(collection.Binaries) == 0 {
+		log.Print("Returned collection is empty. Returning nil.")
+		return nil, nil
+	}
+
+	return collection, nil
+}
+
+func (binaryApi *inventoryBinaryApi) getCommon(path string) (*BinaryCollection, *generic.Error) {
+	body, status, err := binaryApi.client.Get(path, generic.AcceptHeader(MANAGED_OBJECT_COLLECTION_TYPE))
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while getting binaries: %s", err.Error()), "GetBinaryCollection")
+	}
+
+	if status != http.StatusOK {
+		return nil, generic.CreateErrorFromResponse(body, status)
+	}
+
+	var result BinaryCollection
+	if len(body) > 0 {
+		err = generic.ObjectFromJson(body, &result)
+		if err != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), "GetBinaryCollection")
+		}
+	} else {
+		return nil, generic.ClientError("Response body was empty", "GetBinaryCollection")
+	}
+
+	return &result, nil
+}
+
+func writeBinaryForm(form *multipart.Writer, objectJson []byte, object NewBinary, content io.Reader) error {
+	if err := form.WriteField("object", string(objectJson)); err != nil {
+		return err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, object.Name))
+	header.Set("Content-Type", object.Type)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return err
+	}
+
+	return form.Close()
+}
+
+func parseBinaryStreamResponse(response *http.Response, expectedStatus int, info string) (*Binary, *generic.Error) {
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while reading the response: %s", err.Error()), info)
+	}
+
+	if response.StatusCode != expectedStatus {
+		return nil, generic.CreateErrorFromResponse(body, response.StatusCode)
+	}
+
+	var result Binary
+	if err := generic.ObjectFromJson(body, &result); err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while parsing response JSON: %s", err.Error()), info)
+	}
+	return &result, nil
+}
@@ -0,0 +1,189 @@
+package inventory
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var binaryJson = `{
+	"id": "12345",
+	"name": "firmware.bin",
+	"type": "application/octet-stream",
+	"length": 8,
+	"owner": "admin",
+	"c8y_IsBinary": "",
+	"self": "https://t0815.cumulocity.com/inventory/binaries/12345"
+}`
+
+func TestInventoryBinaryApi_Upload(t *testing.T) {
+	// given: a test server reading the multipart form
+	var object, file, fileName, fileType, contentType string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Fatalf("Upload() sent no multipart form: %s", err)
+		}
+		for part, err := reader.NextPart(); err == nil; part, err = reader.NextPart() {
+			content, _ := ioutil.ReadAll(part)
+			switch part.FormName() {
+			case "object":
+				object = string(content)
+			case "file":
+				file, fileName, fileType = string(content), part.FileName(), part.Header.Get("Content-Type")
+			}
+		}
+		contentType = r.Header.Get("Accept")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(binaryJson))
+	}))
+	defer ts.Close()
+
+	// when
+	binary, err := buildInventoryBinaryApi(ts).Upload(&NewBinary{Name: "firmware.bin", AdditionalFields: map[string]interface{}{"c8y_Firmware": true}}, strings.NewReader("firmware"))
+
+	// then
+	if err != nil {
+		t.Fatalf("Upload() got an unexpected error: %s", err.Error())
+	}
+	if binary.Id != "12345" || binary.Length != 8 || binary.Owner != "admin" {
+		t.Errorf("Upload() = %v", binary)
+	}
+	if object != `{"c8y_Firmware":true,"name":"firmware.bin","type":"application/octet-stream"}` {
+		t.Errorf("Upload() object = %s", object)
+	}
+	if file != "firmware" || fileName != "firmware.bin" || fileType != BINARY_DEFAULT_CONTENT_TYPE {
+		t.Errorf("Upload() file = %s, %s, %s", file, fileName, fileType)
+	}
+	if contentType != MANAGED_OBJECT_TYPE {
+		t.Errorf("Upload() accept = %s, want %s", contentType, MANAGED_OBJECT_TYPE)
+	}
+}
+
+func TestInventoryBinaryApi_Upload_Invalid(t *testing.T) {
+	api := NewInventoryBinaryApi(nil)
+
+	if _, err := api.Upload(nil, strings.NewReader("x")); err == nil {
+		t.Errorf("Upload() without binary got no error")
+	}
+	if _, err := api.Upload(&NewBinary{Name: "x"}, nil); err == nil {
+		t.Errorf("Upload() without content got no error")
+	}
+}
+
+func TestInventoryBinaryApi_Upload_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": "security/Forbidden", "message": "Access denied"}`))
+	}))
+	defer ts.Close()
+
+	_, err := buildInventoryBinaryApi(ts).Upload(&NewBinary{Name: "report.pdf", Type: "application/pdf"}, strings.NewReader("pdf"))
+
+	if err == nil || err.ErrorType != "403: security/Forbidden" {
+		t.Errorf("Upload() error = %v, want forbidden", err)
+	}
+}
+
+func TestInventoryBinaryApi_Download(t *testing.T) {
+	var capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("pdf content"))
+	}))
+	defer ts.Close()
+	var buffer bytes.Buffer
+
+	contentType, err := buildInventoryBinaryApi(ts).Download("12345", &buffer)
+
+	if err != nil {
+		t.Fatalf("Download() got an unexpected error: %s", err.Error())
+	}
+	if capturedUrl != "/inventory/binaries/12345" || contentType != "application/pdf" || buffer.String() != "pdf content" {
+		t.Errorf("Download() = %s, %s from %s", contentType, buffer.String(), capturedUrl)
+	}
+}
+
+func TestInventoryBinaryApi_Download_NotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "inventory/Not Found", "message": "Binary not found"}`))
+	}))
+	defer ts.Close()
+	var buffer bytes.Buffer
+
+	_, err := buildInventoryBinaryApi(ts).Download("12345", &buffer)
+
+	if err == nil || err.ErrorType != "404: inventory/Not Found" || buffer.Len() > 0 {
+		t.Errorf("Download() error = %v, want not found", err)
+	}
+}
+
+func TestInventoryBinaryApi_Replace(t *testing.T) {
+	var method, capturedUrl, contentType, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		method, capturedUrl, contentType, body = r.Method, r.URL.String(), r.Header.Get("Content-Type"), string(content)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(binaryJson))
+	}))
+	defer ts.Close()
+
+	binary, err := buildInventoryBinaryApi(ts).Replace("12345", "", strings.NewReader("firmware"))
+
+	if err != nil {
+		t.Fatalf("Replace() got an unexpected error: %s", err.Error())
+	}
+	if binary.Id != "12345" {
+		t.Errorf("Replace() = %v", binary)
+	}
+	if method != http.MethodPut || capturedUrl != "/inventory/binaries/12345" || contentType != BINARY_DEFAULT_CONTENT_TYPE || body != "firmware" {
+		t.Errorf("Replace() sent %s %s %s %s", method, capturedUrl, contentType, body)
+	}
+}
+
+func TestInventoryBinaryApi_Delete(t *testing.T) {
+	var method, capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, capturedUrl = r.Method, r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	err := buildInventoryBinaryApi(ts).Delete("12345")
+
+	if err != nil || method != http.MethodDelete || capturedUrl != "/inventory/binaries/12345" {
+		t.Errorf("Delete() = %v with %s %s", err, method, capturedUrl)
+	}
+}
+
+func TestInventoryBinaryApi_Find(t *testing.T) {
+	var capturedUrl string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUrl = r.URL.String()
+		_, _ = w.Write([]byte(`{"managedObjects": [` + binaryJson + `], "next": "` + "http://" + r.Host + `/inventory/binaries?pageSize=5&currentPage=2"}`))
+	}))
+	defer ts.Close()
+
+	collection, err := buildInventoryBinaryApi(ts).Find(&BinaryFilter{Type: "application/pdf", Owner: "admin", ChildAdditionId: "4711"}, 5)
+
+	if err != nil {
+		t.Fatalf("Find() got an unexpected error: %s", err.Error())
+	}
+	if len(collection.Binaries) != 1 || collection.Binaries[0].Name != "firmware.bin" {
+		t.Errorf("Find() = %v", collection)
+	}
+	expected := "/inventory/binaries?childAdditionId=4711&owner=admin&pageSize=5&type=application%2Fpdf"
+	if capturedUrl != expected {
+		t.Errorf("Find() url = %s, want %s", capturedUrl, expected)
+	}
+
+	next, err := buildInventoryBinaryApi(ts).NextPage(collection)
+	if err != nil || next == nil || capturedUrl != "/inventory/binaries?pageSize=5&currentPage=2" {
+		t.Errorf("NextPage() = %v, %v from %s", next, err, capturedUrl)
+	}
+}
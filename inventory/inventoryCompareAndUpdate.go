@@ -0,0 +1,196 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"reflect"
+)
+
+const DEFAULT_UPDATE_RETRIES = 5
+
+// Fields, which change with every update and are not compared to detect concurrent changes.
+var volatileFields = map[string]bool{"lastUpdated": true, "self": true}
+
+/*
+Computes the changes for the current state of a managed object. Returning nil skips the update.
+The function is called again with a newer state, when the managed object is changed concurrently,
+so it must not have side effects besides computing the changes.
+*/
+type UpdateFunc func(current *ManagedObject) (*ManagedObjectUpdate, error)
+
+/*
+Updates a managed object without losing concurrent changes of the same fragments, which happen after the update.
+The platform has no conditional update, so a concurrent change of the same fragments between the read and the
+update is overwritten without being noticed. Only writers, which also use CompareAndUpdate, re-apply their
+changes in this case.
+
+The changes are computed from the current state and applied. Afterwards the managed object is read again
+to verify the update: When LastUpdated differs from the updated managed object, another writer changed it
+after the update. When this concurrent change touched a fragment of the changes, the changes are computed
+again and applied again. They are computed from the new state with the own changed fragments, which were not
+changed concurrently, reset to the state before the update. So the update is not applied twice to a fragment,
+ex. when it increments a counter. Concurrent changes of other fragments are kept, because an update only
+replaces the fragments it contains. After maxRetries concurrent changes, an error is returned.
+maxRetries defaults to DEFAULT_UPDATE_RETRIES, if not positive.
+*/
+func CompareAndUpdate(inventoryApi InventoryApi, managedObjectId string, maxRetries int, update UpdateFunc) (*ManagedObject, *generic.Error) {
+	if maxRetries <= 0 {
+		maxRetries = DEFAULT_UPDATE_RETRIES
+	}
+
+	current, err := getExisting(inventoryApi, managedObjectId)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := computeChanges(update, current)
+	if err != nil || changes == nil {
+		return current, err
+	}
+
+	for retry := 0; ; retry++ {
+		updated, err := inventoryApi.Update(managedObjectId, changes)
+		if err != nil {
+			return nil, err
+		}
+		latest, err := getExisting(inventoryApi, managedObjectId)
+		if err != nil {
+			return nil, err
+		}
+		if latest.LastUpdated.Equal(updated.LastUpdated) {
+			return latest, nil
+		}
+
+		concurrentFields, jsonErr := changedFields(updated, latest)
+		if jsonErr != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Error while comparing managed object %s: %s", managedObjectId, jsonErr.Error()), "CompareAndUpdate")
+		}
+		if !overlaps(concurrentFields, changes) {
+			return latest, nil
+		}
+		if retry == maxRetries {
+			return nil, generic.ClientError(fmt.Sprintf("Managed object %s was changed concurrently %d times", managedObjectId, retry+1), "CompareAndUpdate")
+		}
+
+		base, jsonErr := withoutOwnChanges(latest, current, changes, concurrentFields)
+		if jsonErr != nil {
+			return nil, generic.ClientError(fmt.Sprintf("Error while resetting the changes of managed object %s: %s", managedObjectId, jsonErr.Error()), "CompareAndUpdate")
+		}
+		current = base
+		changes, err = computeChanges(update, current)
+		if err != nil || changes == nil {
+			return latest, err
+		}
+	}
+}
+
+// -- internal
+
+func getExisting(inventoryApi InventoryApi, managedObjectId string) (*ManagedObject, *generic.Error) {
+	managedObject, err := inventoryApi.Get(managedObjectId)
+	if err != nil {
+		return nil, err
+	}
+	if managedObject == nil {
+		return nil, generic.ClientError(fmt.Sprintf("Managed object %s does not exist", managedObjectId), "CompareAndUpdate")
+	}
+	return managedObject, nil
+}
+
+func computeChanges(update UpdateFunc, current *ManagedObject) (*ManagedObjectUpdate, *generic.Error) {
+	changes, err := update(current)
+	if err != nil {
+		return nil, generic.ClientError(fmt.Sprintf("Error while computing the changes of managed object %s: %s", current.Id, err.Error()), "CompareAndUpdate")
+	}
+	return changes, nil
+}
+
+// Returns the top level fields, which differ between both managed objects.
+func changedFields(before *ManagedObject, after *ManagedObject) (map[string]bool, error) {
+	beforeFields, err := fieldsOf(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fieldsOf(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for name, value := range afterFields {
+		if !volatileFields[name] && !reflect.DeepEqual(value, beforeFields[name]) {
+			changed[name] = true
+		}
+	}
+	for name := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			changed[name] = true
+		}
+	}
+	return changed, nil
+}
+
+func fieldsOf(managedObject *ManagedObject) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	bytes, err := generic.JsonFromObject(managedObject)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, &fields)
+	return fields, err
+}
+
+func overlaps(fields map[string]bool, changes *ManagedObjectUpdate) bool {
+	for _, name := range fieldsChangedBy(changes) {
+		if fields[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldsChangedBy(changes *ManagedObjectUpdate) []string {
+	var names []string
+	if len(changes.Type) > 0 {
+		names = append(names, "type")
+	}
+	if len(changes.Name) > 0 {
+		names = append(names, "name")
+	}
+	for name := range changes.AdditionalFields {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Returns the latest state, in which the fields of the changes, which were not changed concurrently, are reset to
+// the state the changes were computed from.
+func withoutOwnChanges(latest *ManagedObject, computedFrom *ManagedObject, changes *ManagedObjectUpdate, concurrentFields map[string]bool) (*ManagedObject, error) {
+	fields, err := fieldsOf(latest)
+	if err != nil {
+		return nil, err
+	}
+	previousFields, err := fieldsOf(computedFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range fieldsChangedBy(changes) {
+		if concurrentFields[name] {
+			continue
+		}
+		if value, ok := previousFields[name]; ok {
+			fields[name] = value
+		} else {
+			delete(fields, name)
+		}
+	}
+
+	bytes, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var base ManagedObject
+	err = generic.ObjectFromJson(bytes, &base)
+	return &base, err
+}
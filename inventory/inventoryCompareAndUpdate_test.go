@@ -0,0 +1,228 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A managed object, which is changed by another writer right after each of the first PUT requests. PUT bodies are recorded.
+type compareAndUpdatePlatform struct {
+	fields     map[string]interface{}
+	version    int
+	concurrent []map[string]interface{}
+	puts       []string
+}
+
+func (p *compareAndUpdatePlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.fields == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		body, _ := ioutil.ReadAll(r.Body)
+		p.puts = append(p.puts, string(body))
+		p.apply(body)
+		_, _ = w.Write(p.state())
+
+		if len(p.concurrent) > 0 {
+			concurrent, _ := json.Marshal(p.concurrent[0])
+			p.concurrent = p.concurrent[1:]
+			p.apply(concurrent)
+		}
+		return
+	}
+	_, _ = w.Write(p.state())
+}
+
+func (p *compareAndUpdatePlatform) apply(body []byte) {
+	var fields map[string]interface{}
+	_ = json.Unmarshal(body, &fields)
+	for name, value := range fields {
+		p.fields[name] = value
+	}
+	p.version++
+}
+
+func (p *compareAndUpdatePlatform) state() []byte {
+	p.fields["id"] = "1"
+	p.fields["lastUpdated"] = fmt.Sprintf("2020-07-03T10:16:%02dZ", p.version)
+	bytes, _ := json.Marshal(p.fields)
+	return bytes
+}
+
+func buildCompareAndUpdatePlatform(software map[string]interface{}, concurrent ...map[string]interface{}) (*compareAndUpdatePlatform, *httptest.Server) {
+	platform := &compareAndUpdatePlatform{
+		fields:     map[string]interface{}{"c8y_Software": software, "c8y_Position": map[string]interface{}{"lat": 1}},
+		concurrent: concurrent,
+	}
+	return platform, httptest.NewServer(platform)
+}
+
+// Adds a software package to the current software.
+func addSoftware(calls *[]string) UpdateFunc {
+	return func(current *ManagedObject) (*ManagedObjectUpdate, error) {
+		*calls = append(*calls, current.LastUpdated.Format("05"))
+		currentSoftware, err := current.Software()
+		if err != nil {
+			return nil, err
+		}
+		software := C8YSoftware{"agent": "2.0"}
+		for name, version := range currentSoftware {
+			software[name] = version
+		}
+		return (&ManagedObjectUpdate{}).SetSoftware(software), nil
+	}
+}
+
+func TestCompareAndUpdate_WithoutConcurrentChange(t *testing.T) {
+	// given
+	var calls []string
+	platform, ts := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"})
+	defer ts.Close()
+
+	// when
+	managedObject, err := CompareAndUpdate(buildInventoryApi(ts), "1", 0, addSoftware(&calls))
+
+	// then
+	if err != nil || managedObject.Id != "1" {
+		t.Fatalf("CompareAndUpdate() = %v, %v", managedObject, err)
+	}
+	if strings.Join(platform.puts, "|") != `{"c8y_Software":{"agent":"2.0","os":"10"}}` || len(calls) != 1 {
+		t.Errorf("CompareAndUpdate() updated %v with %v calls", platform.puts, calls)
+	}
+}
+
+func TestCompareAndUpdate_KeepsChangesOfOtherFragments(t *testing.T) {
+	// given: the position is changed concurrently
+	var calls []string
+	platform, ts := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"},
+		map[string]interface{}{"c8y_Position": map[string]interface{}{"lat": 2}})
+	defer ts.Close()
+
+	// when
+	managedObject, err := CompareAndUpdate(buildInventoryApi(ts), "1", 0, addSoftware(&calls))
+
+	// then: the software is updated once
+	if err != nil {
+		t.Fatalf("CompareAndUpdate() got an unexpected error: %s", err)
+	}
+	if strings.Join(platform.puts, "|") != `{"c8y_Software":{"agent":"2.0","os":"10"}}` || strings.Join(calls, ",") != "00" {
+		t.Errorf("CompareAndUpdate() updated %v with calls %v", platform.puts, calls)
+	}
+	if managedObject.LastUpdated.Format("05") != "02" {
+		t.Errorf("CompareAndUpdate() = %v, want the latest state", managedObject)
+	}
+}
+
+func TestCompareAndUpdate_ReappliesChangesOfSameFragment(t *testing.T) {
+	// given: another writer installs a package right after the update and drops the updated one
+	var calls []string
+	platform, ts := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"},
+		map[string]interface{}{"c8y_Software": map[string]interface{}{"os": "10", "vim": "8"}})
+	defer ts.Close()
+
+	// when
+	_, err := CompareAndUpdate(buildInventoryApi(ts), "1", 0, addSoftware(&calls))
+
+	// then: the changes are computed again and both packages are kept
+	if err != nil {
+		t.Fatalf("CompareAndUpdate() got an unexpected error: %s", err)
+	}
+	expected := `{"c8y_Software":{"agent":"2.0","os":"10"}}|{"c8y_Software":{"agent":"2.0","os":"10","vim":"8"}}`
+	if strings.Join(platform.puts, "|") != expected || strings.Join(calls, ",") != "00,02" {
+		t.Errorf("CompareAndUpdate() updated %v with calls %v", platform.puts, calls)
+	}
+}
+
+func TestCompareAndUpdate_DoesNotReapplyOwnChanges(t *testing.T) {
+	// given: an update, which counts the installations, and another writer changing the software after the update
+	var calls []string
+	platform, ts := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"},
+		map[string]interface{}{"c8y_Software": map[string]interface{}{"os": "10", "vim": "8"}})
+	defer ts.Close()
+
+	countInstallations := func(current *ManagedObject) (*ManagedObjectUpdate, error) {
+		changes, err := addSoftware(&calls)(current)
+		if err != nil {
+			return nil, err
+		}
+		count := 0.0
+		if installations, ok := current.AdditionalFields["c8y_Installations"].(map[string]interface{}); ok {
+			count, _ = installations["count"].(float64)
+		}
+		changes.AdditionalFields["c8y_Installations"] = map[string]interface{}{"count": count + 1}
+		return changes, nil
+	}
+
+	// when
+	_, err := CompareAndUpdate(buildInventoryApi(ts), "1", 0, countInstallations)
+
+	// then: the installation is counted once
+	if err != nil {
+		t.Fatalf("CompareAndUpdate() got an unexpected error: %s", err)
+	}
+	expected := `{"c8y_Installations":{"count":1},"c8y_Software":{"agent":"2.0","os":"10"}}|{"c8y_Installations":{"count":1},"c8y_Software":{"agent":"2.0","os":"10","vim":"8"}}`
+	if strings.Join(platform.puts, "|") != expected {
+		t.Errorf("CompareAndUpdate() updated %v, want %v", platform.puts, expected)
+	}
+}
+
+func TestCompareAndUpdate_TooManyConcurrentChanges(t *testing.T) {
+	var calls []string
+	platform, ts := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"},
+		map[string]interface{}{"c8y_Software": map[string]interface{}{"os": "11"}},
+		map[string]interface{}{"c8y_Software": map[string]interface{}{"os": "12"}},
+		map[string]interface{}{"c8y_Software": map[string]interface{}{"os": "13"}},
+	)
+	defer ts.Close()
+
+	_, err := CompareAndUpdate(buildInventoryApi(ts), "1", 2, addSoftware(&calls))
+
+	if err == nil || err.Message != "Managed object 1 was changed concurrently 3 times" {
+		t.Errorf("CompareAndUpdate() got an unexpected error: %v", err)
+	}
+	if len(platform.puts) != 3 {
+		t.Errorf("CompareAndUpdate() updated %v", platform.puts)
+	}
+}
+
+func TestCompareAndUpdate_WithoutChanges(t *testing.T) {
+	platform, ts := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"})
+	defer ts.Close()
+
+	managedObject, err := CompareAndUpdate(buildInventoryApi(ts), "1", 0, func(current *ManagedObject) (*ManagedObjectUpdate, error) {
+		return nil, nil
+	})
+
+	if err != nil || managedObject == nil || len(platform.puts) != 0 {
+		t.Errorf("CompareAndUpdate() = %v, %v and updated %v", managedObject, err, platform.puts)
+	}
+}
+
+func TestCompareAndUpdate_Errors(t *testing.T) {
+	ts := httptest.NewServer(&compareAndUpdatePlatform{})
+	defer ts.Close()
+
+	_, err := CompareAndUpdate(buildInventoryApi(ts), "1", 0, func(current *ManagedObject) (*ManagedObjectUpdate, error) {
+		return nil, nil
+	})
+	if err == nil || err.Message != "Managed object 1 does not exist" {
+		t.Errorf("CompareAndUpdate() of missing managed object got an unexpected error: %v", err)
+	}
+
+	_, ts2 := buildCompareAndUpdatePlatform(map[string]interface{}{"os": "10"})
+	defer ts2.Close()
+
+	_, err = CompareAndUpdate(buildInventoryApi(ts2), "1", 0, func(current *ManagedObject) (*ManagedObjectUpdate, error) {
+		return nil, fmt.Errorf("invalid state")
+	})
+	if err == nil || err.Message != "Error while computing the changes of managed object 1: invalid state" {
+		t.Errorf("CompareAndUpdate() with failing update got an unexpected error: %v", err)
+	}
+}
@@ -3,23 +3,37 @@ package inventory
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
 type InventoryFilter struct {
-	Type         string
-	FragmentType string
-	Ids          []string
-	Text         string
+	Type              string
+	FragmentType      string
+	Ids               []string
+	Text              string
+	Owner             string // Only managed objects owned by this user
+	ChildAssetId      string // Only managed objects, which have this child asset
+	ChildDeviceId     string // Only managed objects, which have this child device
+	ChildAdditionId   string // Only managed objects, which have this child addition
+	OnlyRoots         bool   // Only managed objects without parents
+	WithParents       bool   // Include the parents of each managed object
+	WithChildren      *bool  // Include the children of each managed object. The platform default is true.
+	SkipChildrenNames bool   // Include the children without their names
+	WithTotalPages    bool   // Include the total number of pages in the statistics
 }
 
 // Appends the filter query parameters to the provided parameter values for a request.
-// When provided values is nil an error will be created
+// When provided values is nil or the filter contains incompatible parameters an error will be created
 func (inventoryFilter InventoryFilter) QueryParams(params *url.Values) error {
 	if params == nil {
 		return fmt.Errorf("The provided parameter values must not be nil!")
 	}
 
+	if err := inventoryFilter.validate(); err != nil {
+		return err
+	}
+
 	if len(inventoryFilter.Type) > 0 {
 		params.Add("type", inventoryFilter.Type)
 	}
@@ -36,5 +50,60 @@ func (inventoryFilter InventoryFilter) QueryParams(params *url.Values) error {
 		params.Add("text", inventoryFilter.Text)
 	}
 
+	if len(inventoryFilter.Owner) > 0 {
+		params.Add("owner", inventoryFilter.Owner)
+	}
+
+	if len(inventoryFilter.ChildAssetId) > 0 {
+		params.Add("childAssetId", inventoryFilter.ChildAssetId)
+	}
+
+	if len(inventoryFilter.ChildDeviceId) > 0 {
+		params.Add("childDeviceId", inventoryFilter.ChildDeviceId)
+	}
+
+	if len(inventoryFilter.ChildAdditionId) > 0 {
+		params.Add("childAdditionId", inventoryFilter.ChildAdditionId)
+	}
+
+	if inventoryFilter.OnlyRoots {
+		params.Add("onlyRoots", "true")
+	}
+
+	if inventoryFilter.WithParents {
+		params.Add("withParents", "true")
+	}
+
+	if inventoryFilter.WithChildren != nil {
+		params.Add("withChildren", strconv.FormatBool(*inventoryFilter.WithChildren))
+	}
+
+	if inventoryFilter.SkipChildrenNames {
+		params.Add("skipChildrenNames", "true")
+	}
+
+	if inventoryFilter.WithTotalPages {
+		params.Add("withTotalPages", "true")
+	}
+
+	return nil
+}
+
+func (inventoryFilter InventoryFilter) validate() error {
+	childFilters := 0
+	for _, id := range []string{inventoryFilter.ChildAssetId, inventoryFilter.ChildDeviceId, inventoryFilter.ChildAdditionId} {
+		if len(id) > 0 {
+			childFilters++
+		}
+	}
+
+	if childFilters > 1 {
+		return fmt.Errorf("failed to build query: only one of 'ChildAssetId', 'ChildDeviceId' and 'ChildAdditionId' can be set.")
+	}
+
+	if inventoryFilter.SkipChildrenNames && inventoryFilter.WithChildren != nil && !*inventoryFilter.WithChildren {
+		return fmt.Errorf("failed to build query: 'SkipChildrenNames' can not be set when 'WithChildren' is false.")
+	}
+
 	return nil
 }
@@ -93,3 +93,87 @@ func TestInventoryFilter_QueryParams_WithoutQueryParamValues(t *testing.T) {
 		t.Errorf("Unexpected error was returned: %s; expected: %s", err, expectedError)
 	}
 }
+
+func TestInventoryFilter_QueryParams_ExtendedParameters(t *testing.T) {
+	// given
+	withChildren := false
+	collectionFilter := InventoryFilter{
+		Owner:          "device_4711",
+		ChildAssetId:   "100",
+		WithParents:    true,
+		WithChildren:   &withChildren,
+		WithTotalPages: true,
+	}
+	queryParamsValues := &url.Values{}
+
+	// when
+	err := collectionFilter.QueryParams(queryParamsValues)
+
+	// then
+	if err != nil {
+		t.Errorf("Unexpected error was returned: %s", err)
+	}
+
+	expectedQuery := "childAssetId=100&owner=device_4711&withChildren=false&withParents=true&withTotalPages=true"
+	if queryParamsValues.Encode() != expectedQuery {
+		t.Errorf("Unexpected query params were created: %s; expected: %s", queryParamsValues.Encode(), expectedQuery)
+	}
+}
+
+func TestInventoryFilter_QueryParams_OnlyRoots(t *testing.T) {
+	// given
+	collectionFilter := InventoryFilter{
+		FragmentType:      "c8y_IsDevice",
+		OnlyRoots:         true,
+		SkipChildrenNames: true,
+	}
+	queryParamsValues := &url.Values{}
+
+	// when
+	err := collectionFilter.QueryParams(queryParamsValues)
+
+	// then
+	if err != nil {
+		t.Errorf("Unexpected error was returned: %s", err)
+	}
+
+	expectedQuery := "fragmentType=c8y_IsDevice&onlyRoots=true&skipChildrenNames=true"
+	if queryParamsValues.Encode() != expectedQuery {
+		t.Errorf("Unexpected query params were created: %s; expected: %s", queryParamsValues.Encode(), expectedQuery)
+	}
+}
+
+func TestInventoryFilter_QueryParams_IncompatibleParameters(t *testing.T) {
+	withoutChildren := false
+	tests := []struct {
+		name          string
+		filter        InventoryFilter
+		expectedError string
+	}{
+		{
+			"Several child ids",
+			InventoryFilter{ChildAssetId: "1", ChildDeviceId: "2"},
+			"failed to build query: only one of 'ChildAssetId', 'ChildDeviceId' and 'ChildAdditionId' can be set.",
+		},
+		{
+			"Skip children names without children",
+			InventoryFilter{SkipChildrenNames: true, WithChildren: &withoutChildren},
+			"failed to build query: 'SkipChildrenNames' can not be set when 'WithChildren' is false.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryParamsValues := &url.Values{}
+
+			err := tt.filter.QueryParams(queryParamsValues)
+
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("Unexpected error was returned: %v; expected: %s", err, tt.expectedError)
+			}
+			if len(*queryParamsValues) != 0 {
+				t.Errorf("Unexpected query params were created: %s", queryParamsValues.Encode())
+			}
+		})
+	}
+}
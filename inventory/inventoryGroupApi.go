@@ -0,0 +1,152 @@
+package inventory
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"strings"
+)
+
+const (
+	DEVICE_GROUP_TYPE     = "c8y_DeviceGroup"
+	DEVICE_SUBGROUP_TYPE  = "c8y_DeviceSubgroup"
+	DEVICE_GROUP_FRAGMENT = "c8y_IsDeviceGroup"
+)
+
+/*
+Manages device groups. Groups and subgroups are managed objects with the c8y_IsDeviceGroup fragment.
+Their members - devices, subgroups and other assets - are child assets of the group.
+
+See: https://cumulocity.com/guides/reference/device-management/#device-groups
+*/
+type InventoryGroupApi interface {
+	// Creates a new top level group.
+	CreateGroup(name string) (*ManagedObject, *generic.Error)
+
+	// Creates a new subgroup and adds it to the parent group.
+	CreateSubgroup(parentGroupId string, name string) (*ManagedObject, *generic.Error)
+
+	// Adds a device, subgroup or other asset to the group.
+	AddMember(groupId string, managedObjectId string) *generic.Error
+
+	// Removes a device, subgroup or other asset from the group. The managed object itself is not deleted.
+	RemoveMember(groupId string, managedObjectId string) *generic.Error
+
+	// Moves a device, subgroup or other asset from one group to another.
+	MoveMember(fromGroupId string, toGroupId string, managedObjectId string) *generic.Error
+
+	// Returns the members of the group. When recursive is true, also the members of all subgroups are returned.
+	Members(groupId string, recursive bool) ([]ManagedObject, *generic.Error)
+
+	// Returns a collection of groups and subgroups with the given name.
+	FindByName(name string, pageSize int) (*ManagedObjectCollection, *generic.Error)
+}
+
+type inventoryGroupApi struct {
+	inventoryApi InventoryApi
+	referenceApi InventoryReferenceApi
+}
+
+// Creates a new inventory group api object
+//
+// client - Must be a gomulocity client.
+// returns - The `InventoryGroupApi` object
+func NewInventoryGroupApi(client *generic.Client) InventoryGroupApi {
+	return &inventoryGroupApi{NewInventoryApi(client), NewInventoryReferenceApi(client)}
+}
+
+func (groupApi *inventoryGroupApi) CreateGroup(name string) (*ManagedObject, *generic.Error) {
+	return groupApi.create(DEVICE_GROUP_TYPE, name, "CreateGroup")
+}
+
+/*
+Creates a new subgroup and adds it as child asset to the parent group.
+When the subgroup can not be added, it is deleted again.
+*/
+func (groupApi *inventoryGroupApi) CreateSubgroup(parentGroupId string, name string) (*ManagedObject, *generic.Error) {
+	if len(parentGroupId) == 0 {
+		return nil, generic.ClientError("Creating a subgroup without parent group is not allowed", "CreateSubgroup")
+	}
+
+	subgroup, err := groupApi.create(DEVICE_SUBGROUP_TYPE, name, "CreateSubgroup")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := groupApi.AddMember(parentGroupId, subgroup.Id); err != nil {
+		_ = groupApi.inventoryApi.Delete(subgroup.Id)
+		return nil, err
+	}
+	return subgroup, nil
+}
+
+func (groupApi *inventoryGroupApi) AddMember(groupId string, managedObjectId string) *generic.Error {
+	_, err := groupApi.referenceApi.Create(groupId, CHILD_ASSETS, managedObjectId)
+	return err
+}
+
+func (groupApi *inventoryGroupApi) RemoveMember(groupId string, managedObjectId string) *generic.Error {
+	return groupApi.referenceApi.Delete(groupId, CHILD_ASSETS, managedObjectId)
+}
+
+/*
+Moves a member by adding it to the target group first and removing it from the source group afterwards.
+When the removal fails, the managed object is a member of both groups.
+*/
+func (groupApi *inventoryGroupApi) MoveMember(fromGroupId string, toGroupId string, managedObjectId string) *generic.Error {
+	if fromGroupId == toGroupId {
+		return generic.ClientError("Moving a member into the same group is not allowed", "MoveMember")
+	}
+
+	if err := groupApi.AddMember(toGroupId, managedObjectId); err != nil {
+		return err
+	}
+	return groupApi.RemoveMember(fromGroupId, managedObjectId)
+}
+
+/*
+Returns the members of the group breadth-first. Members of several subgroups are returned once.
+The members contain the data of the references only, use Resolve of ManagedObjectReferences to get the full managed objects.
+*/
+func (groupApi *inventoryGroupApi) Members(groupId string, recursive bool) ([]ManagedObject, *generic.Error) {
+	options := HierarchyOptions{ReferenceTypes: []ReferenceType{CHILD_ASSETS}, MaxDepth: 1}
+	if recursive {
+		options.MaxDepth = 0
+	}
+
+	hierarchy, err := BuildHierarchy(groupApi.inventoryApi, groupApi.referenceApi, groupId, options)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []ManagedObject{}
+	hierarchy.Walk(func(node *HierarchyNode) bool {
+		if node != hierarchy.Root {
+			members = append(members, node.ManagedObject)
+		}
+		return true
+	})
+	return members, nil
+}
+
+func (groupApi *inventoryGroupApi) FindByName(name string, pageSize int) (*ManagedObjectCollection, *generic.Error) {
+	if len(name) == 0 {
+		return nil, generic.ClientError("Finding a group without name is not allowed", "FindGroupByName")
+	}
+
+	query := fmt.Sprintf("$filter=(has(%s) and name eq '%s')", DEVICE_GROUP_FRAGMENT, strings.ReplaceAll(name, "'", "''"))
+	return groupApi.inventoryApi.FindByQuery(query, pageSize)
+}
+
+// -- internal
+
+func (groupApi *inventoryGroupApi) create(groupType string, name string, info string) (*ManagedObject, *generic.Error) {
+	if len(name) == 0 {
+		return nil, generic.ClientError("Creating a group without name is not allowed", info)
+	}
+
+	return groupApi.inventoryApi.Create(&NewManagedObject{
+		Type:             groupType,
+		Name:             name,
+		AdditionalFields: map[string]interface{}{DEVICE_GROUP_FRAGMENT: map[string]interface{}{}},
+	})
+}
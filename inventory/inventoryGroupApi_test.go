@@ -0,0 +1,198 @@
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Records all requests and serves created groups, references and the child assets of a graph.
+type groupPlatform struct {
+	children     map[string][]string
+	requests     []string
+	failOnDelete bool
+}
+
+func (p *groupPlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.Path, INVENTORY_API_PATH)
+	p.requests = append(p.requests, strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, path, body)))
+
+	switch {
+	case r.Method == http.MethodPost && path == "":
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "100", "name": "Created"}`))
+	case r.Method == http.MethodPost:
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"managedObject": {"id": "1"}}`))
+	case r.Method == http.MethodDelete && p.failOnDelete:
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": "security/Forbidden", "message": "Access denied"}`))
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(path, "/childAssets"):
+		var references []string
+		for _, id := range p.children[strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/childAssets")] {
+			references = append(references, fmt.Sprintf(`{"managedObject": {"id": "%s", "name": "Member %s"}}`, id, id))
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"references": [%s]}`, strings.Join(references, ","))))
+	case path == "":
+		_, _ = w.Write([]byte(`{"managedObjects": []}`))
+	default:
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"id": "%s"}`, strings.TrimPrefix(path, "/"))))
+	}
+}
+
+func buildInventoryGroupApi(p *groupPlatform) (InventoryGroupApi, *httptest.Server) {
+	ts := httptest.NewServer(p)
+	return &inventoryGroupApi{buildInventoryApi(ts), buildInventoryReferenceApi(ts)}, ts
+}
+
+func assertGroupRequests(t *testing.T, p *groupPlatform, expected ...string) {
+	if strings.Join(p.requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Requests = \n%s\nwant \n%s", strings.Join(p.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestInventoryGroupApi_CreateGroup(t *testing.T) {
+	p := &groupPlatform{}
+	api, ts := buildInventoryGroupApi(p)
+	defer ts.Close()
+
+	group, err := api.CreateGroup("Building A")
+
+	if err != nil {
+		t.Fatalf("CreateGroup() got an unexpected error: %s", err.Error())
+	}
+	if group.Id != "100" {
+		t.Errorf("CreateGroup() = %v", group)
+	}
+	assertGroupRequests(t, p, `POST  {"c8y_IsDeviceGroup":{},"name":"Building A","type":"c8y_DeviceGroup"}`)
+}
+
+func TestInventoryGroupApi_CreateSubgroup(t *testing.T) {
+	p := &groupPlatform{}
+	api, ts := buildInventoryGroupApi(p)
+	defer ts.Close()
+
+	subgroup, err := api.CreateSubgroup("1", "Floor 1")
+
+	if err != nil || subgroup.Id != "100" {
+		t.Fatalf("CreateSubgroup() = %v, %v", subgroup, err)
+	}
+	assertGroupRequests(t, p,
+		`POST  {"c8y_IsDeviceGroup":{},"name":"Floor 1","type":"c8y_DeviceSubgroup"}`,
+		`POST /1/childAssets {"managedObject":{"id":"100"}}`)
+}
+
+func TestInventoryGroupApi_CreateSubgroup_ParentFails(t *testing.T) {
+	p := &groupPlatform{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/childAssets") {
+			p.requests = append(p.requests, "POST reference")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		p.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	api := &inventoryGroupApi{buildInventoryApi(ts), buildInventoryReferenceApi(ts)}
+
+	_, err := api.CreateSubgroup("1", "Floor 1")
+
+	if err == nil {
+		t.Fatalf("CreateSubgroup() error expected but was nil")
+	}
+	if last := p.requests[len(p.requests)-1]; last != "DELETE /100" {
+		t.Errorf("The subgroup was not deleted again: %v", p.requests)
+	}
+}
+
+func TestInventoryGroupApi_MoveMember(t *testing.T) {
+	p := &groupPlatform{}
+	api, ts := buildInventoryGroupApi(p)
+	defer ts.Close()
+
+	err := api.MoveMember("1", "2", "4711")
+
+	if err != nil {
+		t.Fatalf("MoveMember() got an unexpected error: %s", err.Error())
+	}
+	assertGroupRequests(t, p,
+		`POST /2/childAssets {"managedObject":{"id":"4711"}}`,
+		`DELETE /1/childAssets/4711`)
+
+	if err := api.MoveMember("1", "1", "4711"); err == nil {
+		t.Errorf("MoveMember() into the same group got no error")
+	}
+}
+
+func TestInventoryGroupApi_MoveMember_RemoveFails(t *testing.T) {
+	p := &groupPlatform{failOnDelete: true}
+	api, ts := buildInventoryGroupApi(p)
+	defer ts.Close()
+
+	err := api.MoveMember("1", "2", "4711")
+
+	if err == nil || err.ErrorType != "403: security/Forbidden" {
+		t.Errorf("MoveMember() error = %v, want forbidden", err)
+	}
+}
+
+func TestInventoryGroupApi_Members(t *testing.T) {
+	p := &groupPlatform{children: map[string][]string{
+		"1": {"2", "3"},
+		"2": {"4", "5"},
+	}}
+	api, ts := buildInventoryGroupApi(p)
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		recursive bool
+		expected  string
+	}{
+		{"Direct members", false, "2,3"},
+		{"Recursive", true, "2,3,4,5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := api.Members("1", tt.recursive)
+
+			if err != nil {
+				t.Fatalf("Members() got an unexpected error: %s", err.Error())
+			}
+			var ids []string
+			for _, member := range members {
+				ids = append(ids, member.Id)
+			}
+			if strings.Join(ids, ",") != tt.expected {
+				t.Errorf("Members() = %v, want %s", ids, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInventoryGroupApi_FindByName(t *testing.T) {
+	p := &groupPlatform{}
+	var capturedQuery string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedQuery = r.URL.Query().Get("query")
+		p.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	api := &inventoryGroupApi{buildInventoryApi(ts), buildInventoryReferenceApi(ts)}
+
+	_, err := api.FindByName("Tom's devices", 10)
+
+	if err != nil {
+		t.Fatalf("FindByName() got an unexpected error: %s", err.Error())
+	}
+	if capturedQuery != "$filter=(has(c8y_IsDeviceGroup) and name eq 'Tom''s devices')" {
+		t.Errorf("FindByName() query = %s", capturedQuery)
+	}
+}
@@ -0,0 +1,244 @@
+package inventory
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_HIERARCHY_CONCURRENCY = 4
+
+	hierarchyPageSize = 2000
+)
+
+type HierarchyOptions struct {
+	ReferenceTypes []ReferenceType // References to follow. Defaults to CHILD_DEVICES.
+	MaxDepth       int             // Maximum depth below the root. 0 means unlimited.
+	Concurrency    int             // Number of managed objects whose children are fetched in parallel. Defaults to DEFAULT_HIERARCHY_CONCURRENCY.
+}
+
+// A managed object in a hierarchy. The root has no parent and depth 0.
+type HierarchyNode struct {
+	ManagedObject ManagedObject
+	ReferenceType ReferenceType // Type of the reference from the parent to this node.
+	Parent        *HierarchyNode
+	Children      []*HierarchyNode
+	Depth         int
+}
+
+// A reference between two managed objects of a hierarchy.
+type HierarchyReference struct {
+	ParentId      string
+	ChildId       string
+	ReferenceType ReferenceType
+}
+
+/*
+Hierarchy is an in-memory tree of managed objects below a root.
+A managed object is part of the tree only once. References to managed objects already in the tree are
+not followed again: Cycles holds the references pointing to an ancestor, SharedChildren the references to
+managed objects with more than one parent.
+*/
+type Hierarchy struct {
+	Root           *HierarchyNode
+	Cycles         []HierarchyReference
+	SharedChildren []HierarchyReference
+
+	nodes map[string]*HierarchyNode
+}
+
+/*
+Builds the hierarchy below the root by walking the references breadth-first.
+The children of all managed objects of a level are fetched concurrently.
+*/
+func BuildHierarchy(inventoryApi InventoryApi, referenceApi InventoryReferenceApi, rootId string, options HierarchyOptions) (*Hierarchy, *generic.Error) {
+	if len(options.ReferenceTypes) == 0 {
+		options.ReferenceTypes = []ReferenceType{CHILD_DEVICES}
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = DEFAULT_HIERARCHY_CONCURRENCY
+	}
+
+	root, err := inventoryApi.Get(rootId)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, generic.ClientError(fmt.Sprintf("Managed object %s does not exist", rootId), "BuildHierarchy")
+	}
+
+	hierarchy := &Hierarchy{
+		Root:  &HierarchyNode{ManagedObject: *root},
+		nodes: map[string]*HierarchyNode{},
+	}
+	hierarchy.nodes[root.Id] = hierarchy.Root
+
+	level := []*HierarchyNode{hierarchy.Root}
+	for depth := 1; len(level) > 0 && (options.MaxDepth <= 0 || depth <= options.MaxDepth); depth++ {
+		references, err := fetchChildReferences(referenceApi, level, options)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []*HierarchyNode
+		for i, parent := range level {
+			for _, reference := range references[i] {
+				if node := hierarchy.add(parent, reference); node != nil {
+					next = append(next, node)
+				}
+			}
+		}
+		level = next
+	}
+
+	return hierarchy, nil
+}
+
+// Returns the node of the managed object or nil, if it is not part of the hierarchy.
+func (h *Hierarchy) Node(managedObjectId string) *HierarchyNode {
+	return h.nodes[managedObjectId]
+}
+
+// Returns the parent node of the managed object or nil for the root and unknown managed objects.
+func (h *Hierarchy) Parent(managedObjectId string) *HierarchyNode {
+	if node := h.nodes[managedObjectId]; node != nil {
+		return node.Parent
+	}
+	return nil
+}
+
+// Returns the nodes from the root to the managed object or nil, if it is not part of the hierarchy.
+func (h *Hierarchy) Path(managedObjectId string) []*HierarchyNode {
+	var path []*HierarchyNode
+	for node := h.nodes[managedObjectId]; node != nil; node = node.Parent {
+		path = append([]*HierarchyNode{node}, path...)
+	}
+	return path
+}
+
+// Returns the number of managed objects in the hierarchy.
+func (h *Hierarchy) Size() int {
+	return len(h.nodes)
+}
+
+// Visits all nodes breadth-first, starting with the root. Stops when visit returns false.
+func (h *Hierarchy) Walk(visit func(node *HierarchyNode) bool) {
+	queue := []*HierarchyNode{h.Root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if !visit(node) {
+			return
+		}
+		queue = append(queue, node.Children...)
+	}
+}
+
+/*
+Renders the hierarchy depth-first, one managed object per line indented by its depth. Ex.:
+
+	Gateway (1)
+	  Sensor A (2)
+	  Sensor B (3)
+*/
+func (h *Hierarchy) String() string {
+	var builder strings.Builder
+	var render func(node *HierarchyNode)
+	render = func(node *HierarchyNode) {
+		builder.WriteString(fmt.Sprintf("%s%s (%s)\n", strings.Repeat("  ", node.Depth), node.ManagedObject.Name, node.ManagedObject.Id))
+		for _, child := range node.Children {
+			render(child)
+		}
+	}
+	render(h.Root)
+	return builder.String()
+}
+
+// -- internal
+
+type childReference struct {
+	managedObject ManagedObject
+	referenceType ReferenceType
+}
+
+// Adds the child to the parent. Returns nil, when the child is already part of the hierarchy.
+func (h *Hierarchy) add(parent *HierarchyNode, child childReference) *HierarchyNode {
+	id := child.managedObject.Id
+	if _, ok := h.nodes[id]; ok {
+		reference := HierarchyReference{ParentId: parent.ManagedObject.Id, ChildId: id, ReferenceType: child.referenceType}
+		if isAncestor(parent, id) {
+			h.Cycles = append(h.Cycles, reference)
+		} else {
+			h.SharedChildren = append(h.SharedChildren, reference)
+		}
+		return nil
+	}
+
+	node := &HierarchyNode{ManagedObject: child.managedObject, ReferenceType: child.referenceType, Parent: parent, Depth: parent.Depth + 1}
+	parent.Children = append(parent.Children, node)
+	h.nodes[id] = node
+	return node
+}
+
+func isAncestor(node *HierarchyNode, managedObjectId string) bool {
+	for ; node != nil; node = node.Parent {
+		if node.ManagedObject.Id == managedObjectId {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetches the child references of all nodes concurrently. The result has the order of the nodes.
+func fetchChildReferences(referenceApi InventoryReferenceApi, nodes []*HierarchyNode, options HierarchyOptions) ([][]childReference, *generic.Error) {
+	references := make([][]childReference, len(nodes))
+	errs := make([]*generic.Error, len(nodes))
+
+	semaphore := make(chan struct{}, options.Concurrency)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, managedObjectId string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			for _, referenceType := range options.ReferenceTypes {
+				children, err := fetchReferences(referenceApi, managedObjectId, referenceType)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				references[i] = append(references[i], children...)
+			}
+		}(i, node.ManagedObject.Id)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return references, nil
+}
+
+func fetchReferences(referenceApi InventoryReferenceApi, managedObjectId string, referenceType ReferenceType) ([]childReference, *generic.Error) {
+	var references []childReference
+
+	collection, err := referenceApi.GetMany(managedObjectId, referenceType, hierarchyPageSize)
+	for ; collection != nil; collection, err = referenceApi.NextPage(collection) {
+		for _, reference := range collection.References {
+			references = append(references, childReference{managedObject: reference.ManagedObject, referenceType: referenceType})
+		}
+		if len(collection.References) < hierarchyPageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return references, nil
+}
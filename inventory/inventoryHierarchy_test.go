@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Serves the managed objects and their child devices and assets of a graph. ex. "1/childDevices" -> ["2", "3"]
+type hierarchyPlatform struct {
+	children map[string][]string
+	mutex    sync.Mutex
+	requests []string
+}
+
+func (p *hierarchyPlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, INVENTORY_API_PATH+"/")
+	p.mutex.Lock()
+	p.requests = append(p.requests, path)
+	p.mutex.Unlock()
+
+	if !strings.Contains(path, "/") {
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"id": "%s", "name": "Device %s"}`, path, path)))
+		return
+	}
+
+	references := []ManagedObjectReference{}
+	for _, id := range p.children[path] {
+		references = append(references, ManagedObjectReference{ManagedObject: ManagedObject{Id: id, Name: "Device " + id}})
+	}
+	body, _ := json.Marshal(ManagedObjectReferenceCollection{References: references})
+	_, _ = w.Write(body)
+}
+
+func buildHierarchy(t *testing.T, children map[string][]string, options HierarchyOptions) (*Hierarchy, *hierarchyPlatform) {
+	p := &hierarchyPlatform{children: children}
+	ts := httptest.NewServer(p)
+	defer ts.Close()
+
+	hierarchy, err := BuildHierarchy(buildInventoryApi(ts), buildInventoryReferenceApi(ts), "1", options)
+	if err != nil {
+		t.Fatalf("BuildHierarchy() got an unexpected error: %s", err.Error())
+	}
+	return hierarchy, p
+}
+
+func TestBuildHierarchy(t *testing.T) {
+	// given: a gateway with two child devices, one of them with a child device
+	children := map[string][]string{
+		"1/childDevices": {"2", "3"},
+		"2/childDevices": {"4"},
+	}
+
+	// when
+	hierarchy, _ := buildHierarchy(t, children, HierarchyOptions{})
+
+	// then
+	expected := "Device 1 (1)\n  Device 2 (2)\n    Device 4 (4)\n  Device 3 (3)\n"
+	if hierarchy.String() != expected {
+		t.Errorf("BuildHierarchy() = \n%s, want \n%s", hierarchy, expected)
+	}
+	if hierarchy.Size() != 4 {
+		t.Errorf("Size() = %d, want 4", hierarchy.Size())
+	}
+	if parent := hierarchy.Parent("4"); parent == nil || parent.ManagedObject.Id != "2" {
+		t.Errorf("Parent(4) = %v, want 2", parent)
+	}
+	if parent := hierarchy.Parent("1"); parent != nil {
+		t.Errorf("Parent(1) = %v, want nil", parent)
+	}
+	if node := hierarchy.Node("4"); node == nil || node.Depth != 2 || node.ReferenceType != CHILD_DEVICES {
+		t.Errorf("Node(4) = %v", node)
+	}
+
+	var path []string
+	for _, node := range hierarchy.Path("4") {
+		path = append(path, node.ManagedObject.Id)
+	}
+	if strings.Join(path, ",") != "1,2,4" {
+		t.Errorf("Path(4) = %v, want [1 2 4]", path)
+	}
+
+	var visited []string
+	hierarchy.Walk(func(node *HierarchyNode) bool {
+		visited = append(visited, node.ManagedObject.Id)
+		return true
+	})
+	if strings.Join(visited, ",") != "1,2,3,4" {
+		t.Errorf("Walk() visited %v, want breadth-first [1 2 3 4]", visited)
+	}
+}
+
+func TestBuildHierarchy_CyclesAndSharedChildren(t *testing.T) {
+	children := map[string][]string{
+		"1/childAssets":  {"2", "3"},
+		"2/childAssets":  {"4"},
+		"3/childAssets":  {"4"},
+		"4/childDevices": {"1"},
+	}
+
+	hierarchy, _ := buildHierarchy(t, children, HierarchyOptions{ReferenceTypes: []ReferenceType{CHILD_ASSETS, CHILD_DEVICES}})
+
+	if hierarchy.Size() != 4 {
+		t.Errorf("Size() = %d, want 4", hierarchy.Size())
+	}
+	if len(hierarchy.Cycles) != 1 || hierarchy.Cycles[0] != (HierarchyReference{ParentId: "4", ChildId: "1", ReferenceType: CHILD_DEVICES}) {
+		t.Errorf("Cycles = %v", hierarchy.Cycles)
+	}
+	if len(hierarchy.SharedChildren) != 1 || hierarchy.SharedChildren[0] != (HierarchyReference{ParentId: "3", ChildId: "4", ReferenceType: CHILD_ASSETS}) {
+		t.Errorf("SharedChildren = %v", hierarchy.SharedChildren)
+	}
+}
+
+func TestBuildHierarchy_MaxDepth(t *testing.T) {
+	children := map[string][]string{
+		"1/childDevices": {"2"},
+		"2/childDevices": {"3"},
+		"3/childDevices": {"4"},
+	}
+
+	hierarchy, p := buildHierarchy(t, children, HierarchyOptions{MaxDepth: 2, Concurrency: 1})
+
+	if hierarchy.Size() != 3 || hierarchy.Node("4") != nil {
+		t.Errorf("BuildHierarchy() = \n%s, want depth 2", hierarchy)
+	}
+	for _, request := range p.requests {
+		if strings.HasPrefix(request, "3/") {
+			t.Errorf("The children of the deepest level were fetched: %v", p.requests)
+		}
+	}
+}
+
+func TestBuildHierarchy_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"Root not found", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}},
+		{"References forbidden", func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "childDevices") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id": "1"}`))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+
+			hierarchy, err := BuildHierarchy(buildInventoryApi(ts), buildInventoryReferenceApi(ts), "1", HierarchyOptions{})
+
+			if err == nil || hierarchy != nil {
+				t.Errorf("BuildHierarchy() = %v, %v, want an error", hierarchy, err)
+			}
+		})
+	}
+}
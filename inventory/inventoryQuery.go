@@ -0,0 +1,218 @@
+package inventory
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SortDirection string
+
+const (
+	ASCENDING  SortDirection = "asc"
+	DESCENDING SortDirection = "desc"
+)
+
+var queryPropertyPattern = regexp.MustCompile(`^[A-Za-z0-9_$][A-Za-z0-9_$.\-]*$`)
+
+/*
+A filter expression of the inventory query language. Create it with the functions Eq, Ne, Gt, Ge, Lt, Le,
+Has, ByGroupId and combine it with And, Or and Not. Invalid properties or values are reported by Build.
+
+Dates are compared by their date property, so a time.Time value is compared with <property>.date,
+ex. Gt("creationTime", t) results in creationTime.date gt '2020-07-03T10:16:35Z'.
+
+See: https://cumulocity.com/guides/reference/inventory/#query-language
+*/
+type QueryFilter struct {
+	expression string
+	err        error
+}
+
+// property eq value. Strings may contain * as wildcard.
+func Eq(property string, value interface{}) QueryFilter {
+	return compare(property, "eq", value)
+}
+
+// property ne value
+func Ne(property string, value interface{}) QueryFilter {
+	return compare(property, "ne", value)
+}
+
+// property gt value
+func Gt(property string, value interface{}) QueryFilter {
+	return compare(property, "gt", value)
+}
+
+// property ge value
+func Ge(property string, value interface{}) QueryFilter {
+	return compare(property, "ge", value)
+}
+
+// property lt value
+func Lt(property string, value interface{}) QueryFilter {
+	return compare(property, "lt", value)
+}
+
+// property le value
+func Le(property string, value interface{}) QueryFilter {
+	return compare(property, "le", value)
+}
+
+// Managed objects with the fragment.
+func Has(fragment string) QueryFilter {
+	if err := validateQueryProperty(fragment); err != nil {
+		return QueryFilter{err: err}
+	}
+	return QueryFilter{expression: fmt.Sprintf("has(%s)", fragment)}
+}
+
+// Managed objects, which are child assets of the group.
+func ByGroupId(groupId string) QueryFilter {
+	if len(groupId) == 0 || !queryPropertyPattern.MatchString(groupId) {
+		return QueryFilter{err: fmt.Errorf("invalid group id '%s'", groupId)}
+	}
+	return QueryFilter{expression: fmt.Sprintf("bygroupid(%s)", groupId)}
+}
+
+// All filters must match.
+func And(filters ...QueryFilter) QueryFilter {
+	return combine("and", filters)
+}
+
+// At least one filter must match.
+func Or(filters ...QueryFilter) QueryFilter {
+	return combine("or", filters)
+}
+
+// The filter must not match.
+func Not(filter QueryFilter) QueryFilter {
+	if filter.err != nil {
+		return filter
+	}
+	return QueryFilter{expression: fmt.Sprintf("not(%s)", filter.expression)}
+}
+
+func (f QueryFilter) String() string {
+	return f.expression
+}
+
+/*
+InventoryQuery builds a query for InventoryApi.FindByQuery. Ex.:
+
+	query, err := NewInventoryQuery(And(Eq("type", "c8y_Sensor"), Has("c8y_IsDevice"))).OrderBy("name", ASCENDING).Build()
+	// $filter=(type eq 'c8y_Sensor' and has(c8y_IsDevice)) $orderby=name asc
+*/
+type InventoryQuery struct {
+	filter  *QueryFilter
+	orderBy []string
+	err     error
+}
+
+// Creates a new query with the filter.
+func NewInventoryQuery(filter QueryFilter) *InventoryQuery {
+	return &InventoryQuery{filter: &filter}
+}
+
+// Creates a new query without filter, ex. to order all managed objects.
+func NewUnfilteredInventoryQuery() *InventoryQuery {
+	return &InventoryQuery{}
+}
+
+// Orders the result by the property. Can be called several times, the first call has the highest priority.
+func (q *InventoryQuery) OrderBy(property string, direction SortDirection) *InventoryQuery {
+	if err := validateQueryProperty(property); err != nil {
+		q.err = err
+		return q
+	}
+	if direction != ASCENDING && direction != DESCENDING {
+		q.err = fmt.Errorf("invalid sort direction '%s'", direction)
+		return q
+	}
+
+	q.orderBy = append(q.orderBy, fmt.Sprintf("%s %s", property, direction))
+	return q
+}
+
+// Returns the query string or the first error of the filters and orderings.
+func (q *InventoryQuery) Build() (string, error) {
+	if q.err != nil {
+		return "", fmt.Errorf("failed to build query: %s", q.err.Error())
+	}
+
+	var parts []string
+	if q.filter != nil {
+		if q.filter.err != nil {
+			return "", fmt.Errorf("failed to build query: %s", q.filter.err.Error())
+		}
+		parts = append(parts, "$filter="+q.filter.expression)
+	}
+	if len(q.orderBy) > 0 {
+		parts = append(parts, "$orderby="+strings.Join(q.orderBy, ","))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// -- internal
+
+func compare(property string, operator string, value interface{}) QueryFilter {
+	if err := validateQueryProperty(property); err != nil {
+		return QueryFilter{err: err}
+	}
+
+	literal, err := queryLiteral(value)
+	if err != nil {
+		return QueryFilter{err: fmt.Errorf("invalid value of '%s': %s", property, err.Error())}
+	}
+	if _, ok := value.(time.Time); ok && !strings.HasSuffix(property, ".date") {
+		property += ".date"
+	}
+	return QueryFilter{expression: fmt.Sprintf("%s %s %s", property, operator, literal)}
+}
+
+func combine(operator string, filters []QueryFilter) QueryFilter {
+	if len(filters) == 0 {
+		return QueryFilter{err: fmt.Errorf("'%s' needs at least one filter", operator)}
+	}
+	if len(filters) == 1 {
+		return filters[0]
+	}
+
+	expressions := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		if filter.err != nil {
+			return filter
+		}
+		expressions = append(expressions, filter.expression)
+	}
+	return QueryFilter{expression: fmt.Sprintf("(%s)", strings.Join(expressions, " "+operator+" "))}
+}
+
+func validateQueryProperty(property string) error {
+	if !queryPropertyPattern.MatchString(property) {
+		return fmt.Errorf("invalid property '%s'", property)
+	}
+	return nil
+}
+
+// Strings and times are quoted, single quotes are escaped by doubling them.
+func queryLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case time.Time:
+		return "'" + v.Format(time.RFC3339) + "'", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
@@ -0,0 +1,77 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInventoryQuery_Build(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339, "2020-07-03T10:16:35Z")
+
+	tests := []struct {
+		name     string
+		query    *InventoryQuery
+		expected string
+	}{
+		{"Equals", NewInventoryQuery(Eq("type", "c8y_Sensor")), "$filter=type eq 'c8y_Sensor'"},
+		{"Wildcard", NewInventoryQuery(Eq("name", "*Test*")), "$filter=name eq '*Test*'"},
+		{"Escaped quote", NewInventoryQuery(Eq("name", "Tom's device")), "$filter=name eq 'Tom''s device'"},
+		{"Fragment property", NewInventoryQuery(Ne("c8y_Hardware.model", "RPi")), "$filter=c8y_Hardware.model ne 'RPi'"},
+		{"Numbers", NewInventoryQuery(And(Gt("c8y_Battery.level", 10), Le("c8y_Battery.level", 99.5))),
+			"$filter=(c8y_Battery.level gt 10 and c8y_Battery.level le 99.5)"},
+		{"Bool and time", NewInventoryQuery(And(Eq("c8y_Active", true), Ge("creationTime", created), Lt("lastUpdated", created))),
+			"$filter=(c8y_Active eq true and creationTime.date ge '2020-07-03T10:16:35Z' and lastUpdated.date lt '2020-07-03T10:16:35Z')"},
+		{"Time of date property", NewInventoryQuery(Gt("c8y_Maintenance.date", created)), "$filter=c8y_Maintenance.date gt '2020-07-03T10:16:35Z'"},
+		{"Has", NewInventoryQuery(Has("c8y_IsDevice")), "$filter=has(c8y_IsDevice)"},
+		{"By group", NewInventoryQuery(And(ByGroupId("4711"), Has("c8y_IsDevice"))), "$filter=(bygroupid(4711) and has(c8y_IsDevice))"},
+		{"Nested", NewInventoryQuery(And(Eq("type", "x"), Or(Has("c8y_IsDevice"), Not(Has("c8y_IsDeviceGroup"))))),
+			"$filter=(type eq 'x' and (has(c8y_IsDevice) or not(has(c8y_IsDeviceGroup))))"},
+		{"Single and", NewInventoryQuery(And(Has("c8y_IsDevice"))), "$filter=has(c8y_IsDevice)"},
+		{"Order", NewInventoryQuery(Has("c8y_IsDevice")).OrderBy("name", ASCENDING).OrderBy("creationTime", DESCENDING),
+			"$filter=has(c8y_IsDevice) $orderby=name asc,creationTime desc"},
+		{"Order only", NewUnfilteredInventoryQuery().OrderBy("id", DESCENDING), "$orderby=id desc"},
+		{"Empty", NewUnfilteredInventoryQuery(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Build()
+
+			if err != nil {
+				t.Fatalf("Build() got an unexpected error: %s", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Build() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInventoryQuery_Build_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    *InventoryQuery
+		expected string
+	}{
+		{"Empty property", NewInventoryQuery(Eq("", "x")), "invalid property ''"},
+		{"Injected property", NewInventoryQuery(Eq("name eq 'x') or (type", "y")), "invalid property"},
+		{"Invalid fragment", NewInventoryQuery(Has("c8y_IsDevice)")), "invalid property 'c8y_IsDevice)'"},
+		{"Invalid group", NewInventoryQuery(ByGroupId("1) or (2")), "invalid group id"},
+		{"Unsupported value", NewInventoryQuery(Eq("c8y_Tags", []string{"a"})), "invalid value of 'c8y_Tags': unsupported type []string"},
+		{"Empty and", NewInventoryQuery(And()), "'and' needs at least one filter"},
+		{"Nested error", NewInventoryQuery(Or(Has("c8y_IsDevice"), Not(Eq("", 1)))), "invalid property ''"},
+		{"Invalid order", NewInventoryQuery(Has("c8y_IsDevice")).OrderBy("name,id", ASCENDING), "invalid property 'name,id'"},
+		{"Invalid direction", NewUnfilteredInventoryQuery().OrderBy("name", "up"), "invalid sort direction 'up'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Build()
+
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Build() = %s, %v, want error %s", got, err, tt.expected)
+			}
+		})
+	}
+}
@@ -1,16 +1,19 @@
 package inventory
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/tarent/gomulocity/generic"
+	"strconv"
 	"time"
 )
 
 type NewManagedObject struct {
-	Type         string      `json:"type,omitempty"`
-	Name         string      `json:"name,omitempty"`
-	CreationTime *time.Time  `json:"creationTime,omitempty"`
-	C8y_IsDevice interface{} `json:"c8y_IsDevice"`
+	Type             string                 `json:"type,omitempty"`
+	Name             string                 `json:"name,omitempty"`
+	CreationTime     *time.Time             `json:"creationTime,omitempty"`
+	C8y_IsDevice     interface{}            `json:"c8y_IsDevice,omitempty"`
+	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
 
 type ManagedObjectUpdate struct {
@@ -19,6 +22,82 @@ type ManagedObjectUpdate struct {
 	AdditionalFields map[string]interface{} `jsonc:"flat"`
 }
 
+// The standard device fragments.
+const (
+	HARDWARE_FRAGMENT              = "c8y_Hardware"
+	FIRMWARE_FRAGMENT              = "c8y_Firmware"
+	SOFTWARE_FRAGMENT              = "c8y_Software"
+	REQUIRED_AVAILABILITY_FRAGMENT = "c8y_RequiredAvailability"
+	AVAILABILITY_FRAGMENT          = "c8y_Availability"
+	SUPPORTED_OPERATIONS_FRAGMENT  = "c8y_SupportedOperations"
+	AGENT_FRAGMENT                 = "c8y_Agent"
+	MOBILE_FRAGMENT                = "c8y_Mobile"
+)
+
+// Sets the c8y_Hardware fragment.
+func (u *ManagedObjectUpdate) SetHardware(hardware C8YHardware) *ManagedObjectUpdate {
+	return u.setFragment(HARDWARE_FRAGMENT, hardware)
+}
+
+// Sets the c8y_Firmware fragment.
+func (u *ManagedObjectUpdate) SetFirmware(firmware C8YFirmware) *ManagedObjectUpdate {
+	return u.setFragment(FIRMWARE_FRAGMENT, firmware)
+}
+
+// Sets the c8y_Software fragment. Software, which is not part of the given software, is removed from the fragment.
+func (u *ManagedObjectUpdate) SetSoftware(software C8YSoftware) *ManagedObjectUpdate {
+	if software == nil {
+		software = C8YSoftware{}
+	}
+	return u.setFragment(SOFTWARE_FRAGMENT, software)
+}
+
+// Sets the c8y_RequiredAvailability fragment. 0 or less disables the availability monitoring.
+func (u *ManagedObjectUpdate) SetRequiredAvailability(responseIntervalMinutes int) *ManagedObjectUpdate {
+	return u.setFragment(REQUIRED_AVAILABILITY_FRAGMENT, C8YRequiredAvailability{ResponseInterval: responseIntervalMinutes})
+}
+
+// Sets the c8y_SupportedOperations fragment. Without operations, no operation is supported.
+func (u *ManagedObjectUpdate) SetSupportedOperations(operations ...string) *ManagedObjectUpdate {
+	if operations == nil {
+		operations = []string{}
+	}
+	return u.setFragment(SUPPORTED_OPERATIONS_FRAGMENT, operations)
+}
+
+// Sets the c8y_Agent fragment.
+func (u *ManagedObjectUpdate) SetAgent(agent C8YAgent) *ManagedObjectUpdate {
+	return u.setFragment(AGENT_FRAGMENT, agent)
+}
+
+// Sets the c8y_Mobile fragment.
+func (u *ManagedObjectUpdate) SetMobile(mobile C8YMobile) *ManagedObjectUpdate {
+	return u.setFragment(MOBILE_FRAGMENT, mobile)
+}
+
+func (u *ManagedObjectUpdate) setFragment(name string, value interface{}) *ManagedObjectUpdate {
+	if u.AdditionalFields == nil {
+		u.AdditionalFields = map[string]interface{}{}
+	}
+	u.AdditionalFields[name] = value
+	return u
+}
+
+// The references of a managed object to its parents or children.
+type ManagedObjectReferences struct {
+	References []ManagedObjectReference `json:"references"`
+	Self       string                   `json:"self,omitempty"`
+}
+
+type (
+	AdditionParents = ManagedObjectReferences
+	AssetParents    = ManagedObjectReferences
+	DeviceParents   = ManagedObjectReferences
+	ChildAdditions  = ManagedObjectReferences
+	ChildAssets     = ManagedObjectReferences
+	ChildDevices    = ManagedObjectReferences
+)
+
 type (
 	ManagedObjectCollection struct {
 		Self           string                    `json:"self"`
@@ -52,14 +131,6 @@ type (
 		AdditionalFields       map[string]interface{} `jsonc:"flat"`
 	}
 
-	AssetParents struct {
-		References []interface{} `json:"references"`
-		Self       string        `json:"self,omitempty"`
-	}
-	AdditionParents struct {
-		References []interface{} `json:"references"`
-		Self       string        `json:"self,omitempty"`
-	}
 	C8YActiveAlarmsStatus struct {
 		Critical int `json:"critical,omitempty"`
 		Major    int `json:"major,omitempty"`
@@ -74,39 +145,41 @@ type (
 	C8YDataPoint struct {
 	}
 	C8YFirmware struct {
+		Name    string `json:"name,omitempty"`
 		Version string `json:"version,omitempty"`
+		Url     string `json:"url,omitempty"`
 	}
 	C8YHardware struct {
 		Model        string `json:"model,omitempty"`
+		Revision     string `json:"revision,omitempty"`
 		SerialNumber string `json:"serialNumber,omitempty"`
 	}
+	// The installed software packages by name with their versions.
+	// Read from {"<name>": "<version>"}, {"<name>": {"version": "<version>"}} and [{"name": "<name>", "version": "<version>"}].
+	C8YSoftware map[string]string
+	// The response interval in minutes. 0 or less disables the availability monitoring.
 	C8YRequiredAvailability struct {
-		ResponseInterval int `json:"responseInterval,omitempty"`
+		ResponseInterval int `json:"responseInterval"`
 	}
-	ChildAdditions struct {
-		References []struct {
-			ManagedObject struct {
-				Id   string `json:"id,omitempty"`
-				Name string `json:"name,omitempty"`
-				Self string `json:"self,omitempty"`
-			} `json:"managedObject,omitempty"`
-			Self string `json:"self,omitempty"`
-		} `json:"references"`
-		Self string `json:"self,omitempty"`
+	C8YAgent struct {
+		Name       string `json:"name,omitempty"`
+		Version    string `json:"version,omitempty"`
+		Url        string `json:"url,omitempty"`
+		Maintainer string `json:"maintainer,omitempty"`
 	}
-	ChildAssets struct {
-		References []interface{} `json:"references"`
-		Self       string        `json:"self,omitempty"`
+	C8YMobile struct {
+		Imei            string `json:"imei,omitempty"`
+		CellId          string `json:"cellId,omitempty"`
+		Iccid           string `json:"iccid,omitempty"`
+		Imsi            string `json:"imsi,omitempty"`
+		Mcc             string `json:"mcc,omitempty"`
+		Mnc             string `json:"mnc,omitempty"`
+		Lac             string `json:"lac,omitempty"`
+		Msisdn          string `json:"msisdn,omitempty"`
+		CurrentOperator string `json:"currentOperator,omitempty"`
+		CurrentBand     string `json:"currentBand,omitempty"`
+		ConnType        string `json:"connType,omitempty"`
 	}
-	ChildDevices struct {
-		References []interface{} `json:"references"`
-		Self       string        `json:"self,omitempty"`
-	}
-	DeviceParents struct {
-		References []interface{} `json:"references"`
-		Self       string        `json:"self,omitempty"`
-	}
-
 	C8YStatus struct {
 		Details struct {
 			Active              int `json:"active,omitempty"`
@@ -152,11 +225,187 @@ func (m *ManagedObject) FilterAdditionalFieldByName(fieldName string) (interface
 	}
 }
 
+/*
+The standard device fragments stay in the AdditionalFields of a managed object and are decoded on demand.
+The getters return nil without error, if the managed object has no such fragment. Numbers and booleans
+are accepted for text fields, ex. {"c8y_Mobile": {"mcc": 262}}, fields which are not modelled are ignored.
+*/
+
+// Returns the c8y_Hardware fragment.
+func (m *ManagedObject) Hardware() (*C8YHardware, error) {
+	var hardware C8YHardware
+	ok, err := m.decodeFragment(HARDWARE_FRAGMENT, &hardware)
+	if !ok {
+		return nil, err
+	}
+	return &hardware, nil
+}
+
+// Returns the c8y_Firmware fragment.
+func (m *ManagedObject) Firmware() (*C8YFirmware, error) {
+	var firmware C8YFirmware
+	ok, err := m.decodeFragment(FIRMWARE_FRAGMENT, &firmware)
+	if !ok {
+		return nil, err
+	}
+	return &firmware, nil
+}
+
+// Returns the c8y_Software fragment.
+func (m *ManagedObject) Software() (C8YSoftware, error) {
+	var software C8YSoftware
+	ok, err := m.decodeFragment(SOFTWARE_FRAGMENT, &software)
+	if !ok {
+		return nil, err
+	}
+	return software, nil
+}
+
+// Returns the c8y_RequiredAvailability fragment.
+func (m *ManagedObject) RequiredAvailability() (*C8YRequiredAvailability, error) {
+	var requiredAvailability C8YRequiredAvailability
+	ok, err := m.decodeFragment(REQUIRED_AVAILABILITY_FRAGMENT, &requiredAvailability)
+	if !ok {
+		return nil, err
+	}
+	return &requiredAvailability, nil
+}
+
+// Returns the c8y_Availability fragment, which is set by the platform.
+func (m *ManagedObject) Availability() (*C8YAvailability, error) {
+	var availability C8YAvailability
+	ok, err := m.decodeFragment(AVAILABILITY_FRAGMENT, &availability)
+	if !ok {
+		return nil, err
+	}
+	return &availability, nil
+}
+
+// Returns the c8y_Agent fragment.
+func (m *ManagedObject) Agent() (*C8YAgent, error) {
+	var agent C8YAgent
+	ok, err := m.decodeFragment(AGENT_FRAGMENT, &agent)
+	if !ok {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// Returns the c8y_Mobile fragment.
+func (m *ManagedObject) Mobile() (*C8YMobile, error) {
+	var mobile C8YMobile
+	ok, err := m.decodeFragment(MOBILE_FRAGMENT, &mobile)
+	if !ok {
+		return nil, err
+	}
+	return &mobile, nil
+}
+
+// Returns false, if the fragment is missing or invalid.
+func (m *ManagedObject) decodeFragment(name string, target interface{}) (bool, error) {
+	value, ok := m.AdditionalFields[name]
+	if !ok || value == nil {
+		return false, nil
+	}
+
+	bytes, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(bytes, target)
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid fragment %s: %s", name, err.Error())
+	}
+	return true, nil
+}
+
+func (h *C8YHardware) UnmarshalJSON(data []byte) error {
+	type plain C8YHardware
+	return unmarshalTextFields(data, (*plain)(h))
+}
+
+func (f *C8YFirmware) UnmarshalJSON(data []byte) error {
+	type plain C8YFirmware
+	return unmarshalTextFields(data, (*plain)(f))
+}
+
+func (a *C8YAgent) UnmarshalJSON(data []byte) error {
+	type plain C8YAgent
+	return unmarshalTextFields(data, (*plain)(a))
+}
+
+func (m *C8YMobile) UnmarshalJSON(data []byte) error {
+	type plain C8YMobile
+	return unmarshalTextFields(data, (*plain)(m))
+}
+
+func (s *C8YSoftware) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	software := C8YSoftware{}
+	switch packages := raw.(type) {
+	case map[string]interface{}:
+		for name, value := range packages {
+			if fields, ok := value.(map[string]interface{}); ok {
+				value = fields["version"]
+			}
+			software[name] = textOf(value)
+		}
+	case []interface{}:
+		for _, value := range packages {
+			if fields, ok := value.(map[string]interface{}); ok && fields["name"] != nil {
+				software[textOf(fields["name"])] = textOf(fields["version"])
+			}
+		}
+	case nil:
+	default:
+		return fmt.Errorf("unexpected software %s", string(data))
+	}
+	*s = software
+	return nil
+}
+
+// Unmarshals an object into a struct of strings, numbers and booleans are converted to text.
+func unmarshalTextFields(data []byte, target interface{}) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name, value := range fields {
+		switch value.(type) {
+		case float64, bool:
+			fields[name] = textOf(value)
+		}
+	}
+
+	bytes, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, target)
+}
+
+func textOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 type ReferenceType string
 
 const (
-	CHILD_DEVICES ReferenceType = "childDevices"
-	CHILD_ASSETS  ReferenceType = "childAssets"
+	CHILD_DEVICES   ReferenceType = "childDevices"
+	CHILD_ASSETS    ReferenceType = "childAssets"
+	CHILD_ADDITIONS ReferenceType = "childAdditions"
 )
 
 type Source struct {
@@ -172,6 +421,86 @@ type ManagedObjectReference struct {
 	Self          string        `json:"self"`
 }
 
+/*
+Gets the full referenced managed object. The platform fills in only id, name and self of the managed object
+embedded in a reference, all other fields of it are empty. Returns nil, if the managed object does not exist anymore.
+*/
+func (r ManagedObjectReference) Resolve(inventoryApi InventoryApi) (*ManagedObject, *generic.Error) {
+	return inventoryApi.Get(r.ManagedObject.Id)
+}
+
+// Returns the ids of the referenced managed objects.
+func (r ManagedObjectReferences) IDs() []string {
+	ids := make([]string, 0, len(r.References))
+	for _, reference := range r.References {
+		ids = append(ids, reference.ManagedObject.Id)
+	}
+	return ids
+}
+
+/*
+Gets the full referenced managed objects in the order of the references. The managed objects are
+fetched in batches of 100. Managed objects, which do not exist anymore, are skipped.
+*/
+func (r ManagedObjectReferences) Resolve(inventoryApi InventoryApi) ([]ManagedObject, *generic.Error) {
+	const batchSize = 100
+
+	ids := r.IDs()
+	found := map[string]ManagedObject{}
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		collection, err := inventoryApi.Find(&InventoryFilter{Ids: ids[start:end]}, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, managedObject := range collection.ManagedObjects {
+			found[managedObject.Id] = managedObject
+		}
+	}
+
+	managedObjects := make([]ManagedObject, 0, len(ids))
+	for _, id := range ids {
+		if managedObject, ok := found[id]; ok {
+			managedObjects = append(managedObjects, managedObject)
+		}
+	}
+	return managedObjects, nil
+}
+
+// Returns the ids of the child devices.
+func (m *ManagedObject) ChildDeviceIDs() []string {
+	return m.ChildDevices.IDs()
+}
+
+// Returns the ids of the child assets.
+func (m *ManagedObject) ChildAssetIDs() []string {
+	return m.ChildAssets.IDs()
+}
+
+// Returns the ids of the child additions.
+func (m *ManagedObject) ChildAdditionIDs() []string {
+	return m.ChildAdditions.IDs()
+}
+
+// Returns the ids of the device parents.
+func (m *ManagedObject) DeviceParentIDs() []string {
+	return m.DeviceParents.IDs()
+}
+
+// Returns the ids of the asset parents.
+func (m *ManagedObject) AssetParentIDs() []string {
+	return m.AssetParents.IDs()
+}
+
+// Returns the ids of the addition parents.
+func (m *ManagedObject) AdditionParentIDs() []string {
+	return m.AdditionParents.IDs()
+}
+
 type ManagedObjectReferenceCollection struct {
 	Self       string                    `json:"self"`
 	References []ManagedObjectReference  `json:"references"`
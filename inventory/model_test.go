@@ -1,6 +1,10 @@
 package inventory
 
 import (
+	"github.com/tarent/gomulocity/generic"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -40,3 +44,179 @@ func TestManagedObject_FilterForField_Error(t *testing.T) {
 		t.Errorf("received an unexpected error: %s", err)
 	}
 }
+
+func TestManagedObject_ReferenceIDs(t *testing.T) {
+	body := []byte(`{
+		"id": "1",
+		"childDevices": {"references": [
+			{"managedObject": {"id": "2", "name": "Sensor A"}, "self": "https://t0815.cumulocity.com/inventory/managedObjects/1/childDevices/2"},
+			{"managedObject": {"id": "3", "name": "Sensor B"}}
+		]},
+		"childAdditions": {"references": [{"managedObject": {"id": "4"}}]},
+		"deviceParents": {"references": [{"managedObject": {"id": "5"}}]}
+	}`)
+	var object ManagedObject
+	if err := generic.ObjectFromJson(body, &object); err != nil {
+		t.Fatalf("received an unexpected error: %s", err)
+	}
+
+	if ids := strings.Join(object.ChildDeviceIDs(), ","); ids != "2,3" {
+		t.Errorf("ChildDeviceIDs() = %s, want 2,3", ids)
+	}
+	if name := object.ChildDevices.References[0].ManagedObject.Name; name != "Sensor A" {
+		t.Errorf("Child device name = %s, want Sensor A", name)
+	}
+	if ids := strings.Join(object.ChildAdditionIDs(), ","); ids != "4" {
+		t.Errorf("ChildAdditionIDs() = %s, want 4", ids)
+	}
+	if ids := strings.Join(object.DeviceParentIDs(), ","); ids != "5" {
+		t.Errorf("DeviceParentIDs() = %s, want 5", ids)
+	}
+	if ids := object.ChildAssetIDs(); len(ids) != 0 {
+		t.Errorf("ChildAssetIDs() = %v, want none", ids)
+	}
+}
+
+func TestManagedObjectReferences_Resolve(t *testing.T) {
+	// given: a test server, which knows the managed objects 2 and 4
+	var capturedQueries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedQueries = append(capturedQueries, r.URL.Query().Get("ids"))
+		_, _ = w.Write([]byte(`{"managedObjects": [{"id": "4", "name": "Sensor 4", "custom": true}, {"id": "2", "name": "Sensor 2"}]}`))
+	}))
+	defer ts.Close()
+	references := ManagedObjectReferences{References: []ManagedObjectReference{
+		{ManagedObject: ManagedObject{Id: "2"}},
+		{ManagedObject: ManagedObject{Id: "3"}},
+		{ManagedObject: ManagedObject{Id: "4"}},
+	}}
+
+	// when
+	managedObjects, err := references.Resolve(buildInventoryApi(ts))
+
+	// then: the existing managed objects are returned in the order of the references
+	if err != nil {
+		t.Fatalf("Resolve() got an unexpected error: %s", err.Error())
+	}
+	if len(managedObjects) != 2 || managedObjects[0].Name != "Sensor 2" || managedObjects[1].AdditionalFields["custom"] != true {
+		t.Errorf("Resolve() = %v", managedObjects)
+	}
+	if strings.Join(capturedQueries, "|") != "2,3,4" {
+		t.Errorf("Resolve() queried %v, want one batch", capturedQueries)
+	}
+}
+
+func TestManagedObjectReference_Resolve(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(givenResponseBody))
+	}))
+	defer ts.Close()
+
+	managedObject, err := ManagedObjectReference{ManagedObject: ManagedObject{Id: managedObjectId}}.Resolve(buildInventoryApi(ts))
+
+	if err != nil || managedObject == nil || managedObject.Name != "Test Device" {
+		t.Errorf("Resolve() = %v, %v", managedObject, err)
+	}
+}
+
+func TestManagedObject_StandardFragments(t *testing.T) {
+	body := []byte(`{
+		"id": "1",
+		"c8y_Hardware": {"model": "RPi", "revision": "a02082", "serialNumber": "4711", "custom": {"a": 1}},
+		"c8y_Firmware": {"name": "raspbian", "version": 10},
+		"c8y_Software": {"pi-driver": "3.4.5", "agent": {"version": "1.0", "url": ""}},
+		"c8y_RequiredAvailability": {"responseInterval": 0},
+		"c8y_Availability": {"status": "AVAILABLE"},
+		"c8y_SupportedOperations": ["c8y_Restart"],
+		"c8y_Agent": {"name": "agent", "version": "1.0"},
+		"c8y_Mobile": {"imei": "356938035643809", "iccid": "8944", "mcc": 262},
+		"custom": true
+	}`)
+	var object ManagedObject
+	if err := generic.ObjectFromJson(body, &object); err != nil {
+		t.Fatalf("received an unexpected error: %s", err)
+	}
+
+	// the fragments are kept completely
+	if len(object.AdditionalFields) != 8 || object.AdditionalFields["c8y_Hardware"].(map[string]interface{})["custom"] == nil {
+		t.Errorf("AdditionalFields = %v, want all fragments", object.AdditionalFields)
+	}
+
+	hardware, err := object.Hardware()
+	if err != nil || hardware.Revision != "a02082" || hardware.SerialNumber != "4711" {
+		t.Errorf("Hardware() = %v, %v", hardware, err)
+	}
+	firmware, err := object.Firmware()
+	if err != nil || firmware.Name != "raspbian" || firmware.Version != "10" {
+		t.Errorf("Firmware() = %v, %v", firmware, err)
+	}
+	software, err := object.Software()
+	if err != nil || software["pi-driver"] != "3.4.5" || software["agent"] != "1.0" {
+		t.Errorf("Software() = %v, %v", software, err)
+	}
+	requiredAvailability, err := object.RequiredAvailability()
+	if err != nil || requiredAvailability.ResponseInterval != 0 {
+		t.Errorf("RequiredAvailability() = %v, %v", requiredAvailability, err)
+	}
+	availability, err := object.Availability()
+	if err != nil || availability.Status != "AVAILABLE" {
+		t.Errorf("Availability() = %v, %v", availability, err)
+	}
+	agent, err := object.Agent()
+	if err != nil || agent.Version != "1.0" || (*object.C8YSupportedOperations)[0] != "c8y_Restart" {
+		t.Errorf("Agent() = %v, %v, C8YSupportedOperations = %v", agent, err, object.C8YSupportedOperations)
+	}
+	mobile, err := object.Mobile()
+	if err != nil || mobile.Imei != "356938035643809" || mobile.Mcc != "262" {
+		t.Errorf("Mobile() = %v, %v", mobile, err)
+	}
+}
+
+func TestManagedObject_StandardFragments_Missing(t *testing.T) {
+	object := ManagedObject{AdditionalFields: map[string]interface{}{"c8y_Mobile": "invalid"}}
+
+	if hardware, err := object.Hardware(); hardware != nil || err != nil {
+		t.Errorf("Hardware() = %v, %v, want nil", hardware, err)
+	}
+	if software, err := object.Software(); software != nil || err != nil {
+		t.Errorf("Software() = %v, %v, want nil", software, err)
+	}
+	if mobile, err := object.Mobile(); mobile != nil || err == nil {
+		t.Errorf("Mobile() = %v, %v, want an error", mobile, err)
+	}
+}
+
+func TestManagedObject_Software_List(t *testing.T) {
+	object := ManagedObject{AdditionalFields: map[string]interface{}{
+		"c8y_Software": []interface{}{map[string]interface{}{"name": "os", "version": "10", "url": ""}},
+	}}
+
+	software, err := object.Software()
+
+	if err != nil || len(software) != 1 || software["os"] != "10" {
+		t.Errorf("Software() = %v, %v, want os 10", software, err)
+	}
+}
+
+func TestManagedObjectUpdate_SetStandardFragments(t *testing.T) {
+	update := &ManagedObjectUpdate{Name: "Sensor"}
+	update.SetHardware(C8YHardware{Model: "RPi", SerialNumber: "4711"}).
+		SetFirmware(C8YFirmware{Version: "10"}).
+		SetSoftware(nil).
+		SetRequiredAvailability(0).
+		SetSupportedOperations().
+		SetAgent(C8YAgent{Name: "agent"}).
+		SetMobile(C8YMobile{Imei: "356938035643809"})
+
+	bytes, err := generic.JsonFromObject(update)
+
+	if err != nil {
+		t.Fatalf("received an unexpected error: %s", err)
+	}
+	expected := `{"c8y_Agent":{"name":"agent"},"c8y_Firmware":{"version":"10"},"c8y_Hardware":{"model":"RPi","serialNumber":"4711"},` +
+		`"c8y_Mobile":{"imei":"356938035643809"},"c8y_RequiredAvailability":{"responseInterval":0},"c8y_Software":{},` +
+		`"c8y_SupportedOperations":[],"name":"Sensor"}`
+	if string(bytes) != expected {
+		t.Errorf("JsonFromObject() = %s, want %s", bytes, expected)
+	}
+}
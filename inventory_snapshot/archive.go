@@ -0,0 +1,87 @@
+package inventory_snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/inventory"
+	"io"
+	"time"
+)
+
+// The version of the archive format written by WriteArchive.
+const ARCHIVE_VERSION = 1
+
+/*
+Archive is a portable snapshot of an inventory subtree.
+The managed objects are ordered breadth-first, so parents are listed before their children.
+*/
+type Archive struct {
+	Version        int              `json:"version"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	RootId         string           `json:"rootId"`
+	ManagedObjects []ArchivedObject `json:"managedObjects"`
+	References     []Reference      `json:"references"`
+}
+
+// A managed object without its platform managed fields like id, owner, timestamps and references.
+type ArchivedObject struct {
+	Id          string                 `json:"id"`
+	Type        string                 `json:"type,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	Fragments   map[string]interface{} `json:"fragments"`
+	ExternalIds []ExternalId           `json:"externalIds"`
+}
+
+type ExternalId struct {
+	Type       string `json:"type"`
+	ExternalId string `json:"externalId"`
+}
+
+// A reference between two managed objects of the archive.
+type Reference struct {
+	ParentId      string                  `json:"parentId"`
+	ChildId       string                  `json:"childId"`
+	ReferenceType inventory.ReferenceType `json:"referenceType"`
+}
+
+// Writes the archive as indented JSON.
+func WriteArchive(writer io.Writer, archive *Archive) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return fmt.Errorf("error while writing the archive: %s", err.Error())
+	}
+	return nil
+}
+
+// Reads an archive and checks, that its references only point to managed objects of the archive.
+func ReadArchive(reader io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(reader).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("error while reading the archive: %s", err.Error())
+	}
+	if err := archive.validate(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+func (a *Archive) validate() error {
+	if a.Version != ARCHIVE_VERSION {
+		return fmt.Errorf("invalid archive: unsupported version %d", a.Version)
+	}
+
+	ids := map[string]bool{}
+	for _, managedObject := range a.ManagedObjects {
+		if len(managedObject.Id) == 0 || ids[managedObject.Id] {
+			return fmt.Errorf("invalid archive: managed object id '%s' is empty or not unique", managedObject.Id)
+		}
+		ids[managedObject.Id] = true
+	}
+	for _, reference := range a.References {
+		if !ids[reference.ParentId] || !ids[reference.ChildId] {
+			return fmt.Errorf("invalid archive: reference %s -> %s points outside of the archive", reference.ParentId, reference.ChildId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package inventory_snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"time"
+)
+
+// The fields of a managed object, which are managed by the platform and not archived.
+var platformFields = []string{
+	"id", "self", "type", "name", "owner", "creationTime", "lastUpdated",
+	"childDevices", "childAssets", "childAdditions", "deviceParents", "assetParents", "additionParents",
+	"c8y_Availability", "c8y_ActiveAlarmsStatus", "c8y_Connection",
+}
+
+// The references followed by the exporter.
+var exportedReferenceTypes = []inventory.ReferenceType{inventory.CHILD_DEVICES, inventory.CHILD_ASSETS, inventory.CHILD_ADDITIONS}
+
+/*
+Exporter snapshots a managed object with all its child devices, child assets and child additions.
+References to ancestors and managed objects with several parents in the subtree are kept.
+*/
+type Exporter struct {
+	inventoryApi inventory.InventoryApi
+	referenceApi inventory.InventoryReferenceApi
+	identityApi  identity.IdentityAPI
+}
+
+// Creates a new exporter.
+func NewExporter(inventoryApi inventory.InventoryApi, referenceApi inventory.InventoryReferenceApi, identityApi identity.IdentityAPI) *Exporter {
+	return &Exporter{inventoryApi: inventoryApi, referenceApi: referenceApi, identityApi: identityApi}
+}
+
+// Exports the subtree below the root.
+func (e *Exporter) Export(rootId string) (*Archive, error) {
+	hierarchy, err := inventory.BuildHierarchy(e.inventoryApi, e.referenceApi, rootId, inventory.HierarchyOptions{ReferenceTypes: exportedReferenceTypes})
+	if err != nil {
+		return nil, fmt.Errorf("error while walking the hierarchy of %s: %s", rootId, err.Error())
+	}
+
+	archive := &Archive{Version: ARCHIVE_VERSION, CreatedAt: time.Now().UTC(), RootId: rootId, References: []Reference{}}
+	references := inventory.ManagedObjectReferences{}
+	hierarchy.Walk(func(node *inventory.HierarchyNode) bool {
+		references.References = append(references.References, inventory.ManagedObjectReference{ManagedObject: node.ManagedObject})
+		if node.Parent != nil {
+			archive.References = append(archive.References, Reference{node.Parent.ManagedObject.Id, node.ManagedObject.Id, node.ReferenceType})
+		}
+		return true
+	})
+	for _, reference := range append(hierarchy.SharedChildren, hierarchy.Cycles...) {
+		archive.References = append(archive.References, Reference{reference.ParentId, reference.ChildId, reference.ReferenceType})
+	}
+
+	// The nodes contain the data of the references only.
+	managedObjects, err := references.Resolve(e.inventoryApi)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the managed objects of %s: %s", rootId, err.Error())
+	}
+
+	archive.ManagedObjects = make([]ArchivedObject, 0, len(managedObjects))
+	resolved := map[string]bool{}
+	for i := range managedObjects {
+		archived, err := e.archive(&managedObjects[i])
+		if err != nil {
+			return nil, err
+		}
+		archive.ManagedObjects = append(archive.ManagedObjects, archived)
+		resolved[archived.Id] = true
+	}
+
+	// Managed objects deleted during the export are skipped, so are the references to them.
+	archivedReferences := make([]Reference, 0, len(archive.References))
+	for _, reference := range archive.References {
+		if resolved[reference.ParentId] && resolved[reference.ChildId] {
+			archivedReferences = append(archivedReferences, reference)
+		}
+	}
+	archive.References = archivedReferences
+	return archive, nil
+}
+
+// -- internal
+
+func (e *Exporter) archive(managedObject *inventory.ManagedObject) (ArchivedObject, error) {
+	archived := ArchivedObject{
+		Id:          managedObject.Id,
+		Type:        managedObject.Type,
+		Name:        managedObject.Name,
+		Fragments:   map[string]interface{}{},
+		ExternalIds: []ExternalId{},
+	}
+
+	bytes, jsonErr := generic.JsonFromObject(managedObject)
+	if jsonErr == nil {
+		jsonErr = json.Unmarshal(bytes, &archived.Fragments)
+	}
+	if jsonErr != nil {
+		return archived, fmt.Errorf("error while archiving managed object %s: %s", managedObject.Id, jsonErr.Error())
+	}
+	for _, field := range platformFields {
+		delete(archived.Fragments, field)
+	}
+
+	externalIds, err := e.identityApi.GetExternalIDs(managedObject.Id)
+	if err != nil {
+		return archived, fmt.Errorf("error while getting the external ids of %s: %s", managedObject.Id, err.Error())
+	}
+	for _, externalId := range externalIds.ExternalIds {
+		archived.ExternalIds = append(archived.ExternalIds, ExternalId{Type: externalId.Type, ExternalId: externalId.ExternalId})
+	}
+	return archived, nil
+}
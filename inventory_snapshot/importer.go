@@ -0,0 +1,83 @@
+package inventory_snapshot
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+)
+
+type ImportOptions struct {
+	SkipExternalIds bool // Does not create the external ids, ex. when importing into the tenant of the export.
+}
+
+/*
+Importer recreates the managed objects of an archive with new ids.
+The managed objects are created first, followed by the references and the external ids.
+*/
+type Importer struct {
+	inventoryApi inventory.InventoryApi
+	referenceApi inventory.InventoryReferenceApi
+	identityApi  identity.IdentityAPI
+}
+
+// Creates a new importer.
+func NewImporter(inventoryApi inventory.InventoryApi, referenceApi inventory.InventoryReferenceApi, identityApi identity.IdentityAPI) *Importer {
+	return &Importer{inventoryApi: inventoryApi, referenceApi: referenceApi, identityApi: identityApi}
+}
+
+/*
+Imports the archive and returns the ids of the created managed objects by their archived ids.
+On failure the import stops and the ids of the managed objects created so far are returned with the error.
+*/
+func (i *Importer) Import(archive *Archive, options ImportOptions) (map[string]string, error) {
+	ids := map[string]string{}
+	if err := archive.validate(); err != nil {
+		return ids, err
+	}
+
+	for _, archived := range archive.ManagedObjects {
+		managedObject, err := i.inventoryApi.Create(newManagedObjectOf(archived))
+		if err != nil {
+			return ids, fmt.Errorf("error while creating managed object %s: %s", archived.Id, err.Error())
+		}
+		ids[archived.Id] = managedObject.Id
+	}
+
+	for _, reference := range archive.References {
+		parentId, childId := ids[reference.ParentId], ids[reference.ChildId]
+		if _, err := i.referenceApi.Create(parentId, reference.ReferenceType, childId); err != nil {
+			return ids, fmt.Errorf("error while creating reference %s -> %s: %s", parentId, childId, err.Error())
+		}
+	}
+
+	if options.SkipExternalIds {
+		return ids, nil
+	}
+	for _, archived := range archive.ManagedObjects {
+		for _, externalId := range archived.ExternalIds {
+			newExternalId := identity.NewExternalID{ExternalId: externalId.ExternalId, Type: externalId.Type}
+			if _, err := i.identityApi.CreateExternalID(newExternalId, ids[archived.Id]); err != nil {
+				return ids, fmt.Errorf("error while creating external id %s/%s: %s", externalId.Type, externalId.ExternalId, err.Error())
+			}
+		}
+	}
+	return ids, nil
+}
+
+// -- internal
+
+func newManagedObjectOf(archived ArchivedObject) *inventory.NewManagedObject {
+	newManagedObject := &inventory.NewManagedObject{
+		Type:             archived.Type,
+		Name:             archived.Name,
+		AdditionalFields: map[string]interface{}{},
+	}
+	for name, value := range archived.Fragments {
+		if name == "c8y_IsDevice" {
+			newManagedObject.C8y_IsDevice = value
+			continue
+		}
+		newManagedObject.AdditionalFields[name] = value
+	}
+	return newManagedObject
+}
@@ -0,0 +1,226 @@
+package inventory_snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A group 1 with the members 2 and 3. The device 2 is also a member of the subgroup 3.
+var managedObjects = map[string]string{
+	"1": `{"id": "1", "name": "Plant", "type": "c8y_DeviceGroup", "owner": "admin", "creationTime": "2020-07-01T08:00:00Z", "c8y_IsDeviceGroup": {}}`,
+	"2": `{"id": "2", "name": "Sensor", "type": "c8y_Sensor", "c8y_IsDevice": {}, "c8y_Hardware": {"model": "RPi"}, "c8y_Availability": {"status": "AVAILABLE"}, "c8y_Connection": {"status": "CONNECTED"}}`,
+	"3": `{"id": "3", "name": "Line", "type": "c8y_DeviceSubgroup", "c8y_IsDeviceGroup": {}}`,
+}
+var childAssets = map[string][]string{"1": {"2", "3"}, "3": {"2"}}
+
+func buildClient(ts *httptest.Server) *generic.Client {
+	return &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+}
+
+func buildExportServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case path[0] == "identity" && path[2] == "2":
+			_, _ = w.Write([]byte(`{"externalIds": [{"externalId": "4711", "type": "c8y_Serial"}]}`))
+		case path[0] == "identity":
+			_, _ = w.Write([]byte(`{"externalIds": []}`))
+		case len(path) == 2:
+			// the platform does not keep the order of the requested ids
+			ids := strings.Split(r.URL.Query().Get("ids"), ",")
+			var found []string
+			for i := len(ids) - 1; i >= 0; i-- {
+				if managedObject, ok := managedObjects[ids[i]]; ok {
+					found = append(found, managedObject)
+				}
+			}
+			_, _ = fmt.Fprintf(w, `{"managedObjects": [%s]}`, strings.Join(found, ","))
+		case len(path) == 3:
+			_, _ = w.Write([]byte(managedObjects[path[2]]))
+		default:
+			var references []string
+			if path[3] == string(inventory.CHILD_ASSETS) {
+				for _, id := range childAssets[path[2]] {
+					references = append(references, fmt.Sprintf(`{"managedObject": {"id": "%s"}}`, id))
+				}
+			}
+			_, _ = fmt.Fprintf(w, `{"references": [%s]}`, strings.Join(references, ","))
+		}
+	}))
+}
+
+// Creates managed objects with the ids 100, 101, ... and records all requests with their bodies.
+func buildImportServer(requests *[]string, failingPath string) *httptest.Server {
+	nextId := 100
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*requests = append(*requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+
+		if r.URL.Path == failingPath {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error": "inventory/Error", "message": "failed"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		if r.URL.Path == "/inventory/managedObjects" {
+			_, _ = fmt.Fprintf(w, `{"id": "%d"}`, nextId)
+			nextId++
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+}
+
+func export(t *testing.T) *Archive {
+	ts := buildExportServer()
+	defer ts.Close()
+	client := buildClient(ts)
+
+	archive, err := NewExporter(inventory.NewInventoryApi(client), inventory.NewInventoryReferenceApi(client), identity.NewIdentityAPI(client)).Export("1")
+	if err != nil {
+		t.Fatalf("Export() got an unexpected error: %s", err)
+	}
+	return archive
+}
+
+func TestExporter_Export(t *testing.T) {
+	// when
+	archive := export(t)
+
+	// then
+	if archive.Version != ARCHIVE_VERSION || archive.RootId != "1" {
+		t.Errorf("Export() = version %d, root %s", archive.Version, archive.RootId)
+	}
+	expectedObjects := []ArchivedObject{
+		{Id: "1", Type: "c8y_DeviceGroup", Name: "Plant", Fragments: map[string]interface{}{"c8y_IsDeviceGroup": map[string]interface{}{}}, ExternalIds: []ExternalId{}},
+		{Id: "2", Type: "c8y_Sensor", Name: "Sensor", Fragments: map[string]interface{}{
+			"c8y_IsDevice": map[string]interface{}{}, "c8y_Hardware": map[string]interface{}{"model": "RPi"},
+		}, ExternalIds: []ExternalId{{Type: "c8y_Serial", ExternalId: "4711"}}},
+		{Id: "3", Type: "c8y_DeviceSubgroup", Name: "Line", Fragments: map[string]interface{}{"c8y_IsDeviceGroup": map[string]interface{}{}}, ExternalIds: []ExternalId{}},
+	}
+	if !reflect.DeepEqual(archive.ManagedObjects, expectedObjects) {
+		t.Errorf("Export() managed objects = %v, want %v", archive.ManagedObjects, expectedObjects)
+	}
+	expectedReferences := []Reference{
+		{"1", "2", inventory.CHILD_ASSETS},
+		{"1", "3", inventory.CHILD_ASSETS},
+		{"3", "2", inventory.CHILD_ASSETS},
+	}
+	if !reflect.DeepEqual(archive.References, expectedReferences) {
+		t.Errorf("Export() references = %v, want %v", archive.References, expectedReferences)
+	}
+}
+
+func TestExporter_Export_SkipsDeletedManagedObjects(t *testing.T) {
+	// given: a child 4, which is deleted before its managed object is fetched
+	childAssets["3"] = []string{"2", "4"}
+	defer func() { childAssets["3"] = []string{"2"} }()
+
+	// when
+	archive := export(t)
+
+	// then
+	if len(archive.ManagedObjects) != 3 {
+		t.Errorf("Export() managed objects = %v, want 1, 2 and 3", archive.ManagedObjects)
+	}
+	for _, reference := range archive.References {
+		if reference.ChildId == "4" {
+			t.Errorf("Export() references = %v, want no reference to 4", archive.References)
+		}
+	}
+}
+
+func TestImporter_Import_FromWrittenArchive(t *testing.T) {
+	// given: an archive written and read again
+	var buffer bytes.Buffer
+	if err := WriteArchive(&buffer, export(t)); err != nil {
+		t.Fatalf("WriteArchive() got an unexpected error: %s", err)
+	}
+	archive, err := ReadArchive(&buffer)
+	if err != nil {
+		t.Fatalf("ReadArchive() got an unexpected error: %s", err)
+	}
+
+	// and: another tenant
+	var requests []string
+	ts := buildImportServer(&requests, "")
+	defer ts.Close()
+	client := buildClient(ts)
+	importer := NewImporter(inventory.NewInventoryApi(client), inventory.NewInventoryReferenceApi(client), identity.NewIdentityAPI(client))
+
+	// when
+	ids, err := importer.Import(archive, ImportOptions{})
+
+	// then
+	if err != nil {
+		t.Fatalf("Import() got an unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ids, map[string]string{"1": "100", "2": "101", "3": "102"}) {
+		t.Errorf("Import() ids = %v", ids)
+	}
+	expected := []string{
+		`POST /inventory/managedObjects {"c8y_IsDeviceGroup":{},"name":"Plant","type":"c8y_DeviceGroup"}`,
+		`POST /inventory/managedObjects {"c8y_Hardware":{"model":"RPi"},"c8y_IsDevice":{},"name":"Sensor","type":"c8y_Sensor"}`,
+		`POST /inventory/managedObjects {"c8y_IsDeviceGroup":{},"name":"Line","type":"c8y_DeviceSubgroup"}`,
+		`POST /inventory/managedObjects/100/childAssets {"managedObject":{"id":"101"}}`,
+		`POST /inventory/managedObjects/100/childAssets {"managedObject":{"id":"102"}}`,
+		`POST /inventory/managedObjects/102/childAssets {"managedObject":{"id":"101"}}`,
+		`POST /identity/globalIds/101/externalIds {"externalId":"4711","type":"c8y_Serial"}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Requests = \n%s\nwant\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestImporter_Import_ReturnsCreatedIdsOnFailure(t *testing.T) {
+	// given: a tenant failing to create references
+	var requests []string
+	ts := buildImportServer(&requests, "/inventory/managedObjects/100/childAssets")
+	defer ts.Close()
+	client := buildClient(ts)
+	importer := NewImporter(inventory.NewInventoryApi(client), inventory.NewInventoryReferenceApi(client), identity.NewIdentityAPI(client))
+
+	// when
+	ids, err := importer.Import(export(t), ImportOptions{SkipExternalIds: true})
+
+	// then
+	if err == nil || !strings.HasPrefix(err.Error(), "error while creating reference 100 -> 101") {
+		t.Errorf("Import() got an unexpected error: %v", err)
+	}
+	if len(ids) != 3 {
+		t.Errorf("Import() ids = %v, want all created managed objects", ids)
+	}
+}
+
+func TestReadArchive_Invalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		archive       string
+		expectedError string
+	}{
+		{"No JSON", `[`, "error while reading the archive"},
+		{"Unknown version", `{"version": 2}`, "invalid archive: unsupported version 2"},
+		{"Duplicate id", `{"version": 1, "managedObjects": [{"id": "1"}, {"id": "1"}]}`, "invalid archive: managed object id '1' is empty or not unique"},
+		{"Dangling reference", `{"version": 1, "managedObjects": [{"id": "1"}], "references": [{"parentId": "1", "childId": "2"}]}`,
+			"invalid archive: reference 1 -> 2 points outside of the archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadArchive(strings.NewReader(tt.archive))
+
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expectedError) {
+				t.Errorf("ReadArchive() error = %v, want %s", err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package inventory_sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+)
+
+// The external id type of desired objects without ExternalIdType.
+const DEFAULT_EXTERNAL_ID_TYPE = "c8y_Serial"
+
+// Fields of a managed object, which can not be set as fragment of a desired object.
+var reservedFields = map[string]bool{
+	"id": true, "self": true, "name": true, "type": true, "owner": true, "creationTime": true, "lastUpdated": true,
+	"childDevices": true, "childAssets": true, "childAdditions": true,
+	"deviceParents": true, "assetParents": true, "additionParents": true,
+	"c8y_IsDevice": true, SYNC_FRAGMENT: true,
+}
+
+/*
+DesiredObject is the desired state of a managed object, identified by its external id.
+
+Empty Name and Type and a false IsDevice leave the managed object untouched. Fragments are
+compared and updated one by one, fragments of the managed object, which are not desired, are kept.
+*/
+type DesiredObject struct {
+	ExternalIdType string                 `json:"externalIdType,omitempty"`
+	ExternalId     string                 `json:"externalId"`
+	Type           string                 `json:"type,omitempty"`
+	Name           string                 `json:"name,omitempty"`
+	IsDevice       bool                   `json:"isDevice,omitempty"`
+	Fragments      map[string]interface{} `json:"fragments,omitempty"`
+}
+
+func (o DesiredObject) externalIdType() string {
+	if len(o.ExternalIdType) == 0 {
+		return DEFAULT_EXTERNAL_ID_TYPE
+	}
+	return o.ExternalIdType
+}
+
+func (o DesiredObject) key() string {
+	return o.externalIdType() + "/" + o.ExternalId
+}
+
+/*
+Reads a catalogue of desired objects from a YAML or JSON array. Ex.:
+
+	[{"externalId": "4711", "name": "Sensor A", "type": "c8y_Sensor", "isDevice": true, "fragments": {"c8y_Hardware": {"model": "RPi"}}}]
+
+A YAML sequence of the same objects is read as well.
+*/
+func ReadCatalogue(reader io.Reader) ([]DesiredObject, error) {
+	objects, err := decodeCatalogue(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the catalogue: %s", err.Error())
+	}
+	if err := validateCatalogue(objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Decodes YAML, which includes JSON, and maps it to the desired objects by their JSON field names.
+func decodeCatalogue(reader io.Reader) ([]DesiredObject, error) {
+	bytes, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var document []interface{}
+	if err := yaml.Unmarshal(bytes, &document); err != nil {
+		return nil, err
+	}
+	bytes, err = json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []DesiredObject
+	err = json.Unmarshal(bytes, &objects)
+	return objects, err
+}
+
+func validateCatalogue(objects []DesiredObject) error {
+	keys := map[string]bool{}
+	for i, object := range objects {
+		if len(object.ExternalId) == 0 {
+			return fmt.Errorf("invalid catalogue: object %d has no external id", i)
+		}
+		if keys[object.key()] {
+			return fmt.Errorf("invalid catalogue: external id '%s' is not unique", object.key())
+		}
+		keys[object.key()] = true
+
+		for name := range object.Fragments {
+			if reservedFields[name] {
+				return fmt.Errorf("invalid catalogue: fragment '%s' of '%s' is reserved", name, object.key())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package inventory_sync
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Action string
+
+const (
+	CREATE    Action = "create"
+	UPDATE    Action = "update"
+	DELETE    Action = "delete"
+	UNCHANGED Action = "unchanged"
+)
+
+var actionSymbols = map[Action]string{CREATE: "+", UPDATE: "~", DELETE: "-", UNCHANGED: "="}
+
+// A change of a single managed object.
+type Change struct {
+	Action          Action
+	ExternalIdType  string
+	ExternalId      string // Empty for deletions of managed objects without external id
+	ManagedObjectId string // Empty for creations, until the plan is applied
+	Name            string
+	Differences     []string // The differing fields of an update
+
+	desired   *DesiredObject
+	catalogue string // The catalogue to mark an updated managed object with, if any
+}
+
+func (c Change) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s %-9s", actionSymbols[c.Action], c.Action))
+	if len(c.ExternalId) > 0 {
+		b.WriteString(fmt.Sprintf(" %s/%s", c.ExternalIdType, c.ExternalId))
+	}
+	if len(c.ManagedObjectId) > 0 {
+		b.WriteString(fmt.Sprintf(" (%s)", c.ManagedObjectId))
+	}
+	if len(c.Name) > 0 {
+		b.WriteString(fmt.Sprintf(" %q", c.Name))
+	}
+	if len(c.Differences) > 0 {
+		b.WriteString(": " + strings.Join(c.Differences, ", "))
+	}
+	return b.String()
+}
+
+// The changes to converge the inventory to the desired objects. Creations and updates are in the order of the desired objects, deletions follow.
+type Plan struct {
+	Changes []Change
+}
+
+// Returns true, when at least one managed object is created, updated or deleted.
+func (p *Plan) HasChanges() bool {
+	return p.Count(CREATE)+p.Count(UPDATE)+p.Count(DELETE) > 0
+}
+
+// Returns the number of changes with the action.
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Writes one line per change, followed by a summary. Unchanged managed objects are only counted.
+func (p *Plan) Write(writer io.Writer) error {
+	for _, change := range p.Changes {
+		if change.Action == UNCHANGED {
+			continue
+		}
+		if _, err := fmt.Fprintln(writer, change.String()); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(writer, "Plan: %d to create, %d to update, %d to delete, %d unchanged.\n",
+		p.Count(CREATE), p.Count(UPDATE), p.Count(DELETE), p.Count(UNCHANGED))
+	return err
+}
@@ -0,0 +1,302 @@
+package inventory_sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"reflect"
+	"sort"
+)
+
+// The fragment marking the managed objects of a catalogue: {"gomulocity_Sync": {"catalogue": "<name>"}}
+const SYNC_FRAGMENT = "gomulocity_Sync"
+
+const pageSize = 2000
+
+type Options struct {
+	Catalogue string // Name of the catalogue, required. Marks all managed objects created by the reconciler.
+	Delete    bool   // Deletes managed objects of the catalogue, which are not desired anymore.
+	// Marks existing managed objects of desired objects with the catalogue as well, so that they
+	// are deleted once they are not desired anymore. By default only created managed objects are marked.
+	Adopt bool
+}
+
+/*
+Reconciler converges the inventory to a catalogue of desired objects. The desired objects are
+looked up by their external ids, missing managed objects are created, differing ones updated.
+
+Only managed objects marked with the catalogue in the SYNC_FRAGMENT are deleted. The reconciler marks
+the managed objects it creates. Existing managed objects found by their external id keep their marking,
+unless Options.Adopt is set. So managed objects created by other means are never deleted, unless they
+were adopted.
+*/
+type Reconciler struct {
+	identityApi  identity.IdentityAPI
+	inventoryApi inventory.InventoryApi
+	options      Options
+}
+
+// Creates a new reconciler.
+// identityApi - used to look up and create the external ids.
+// inventoryApi - used to create, update and delete the managed objects.
+func NewReconciler(identityApi identity.IdentityAPI, inventoryApi inventory.InventoryApi, options Options) (*Reconciler, error) {
+	if len(options.Catalogue) == 0 {
+		return nil, fmt.Errorf("the catalogue name must be set")
+	}
+	return &Reconciler{identityApi: identityApi, inventoryApi: inventoryApi, options: options}, nil
+}
+
+// Compares the desired objects with the inventory and returns the changes without applying them.
+func (r *Reconciler) Plan(desired []DesiredObject) (*Plan, error) {
+	if err := validateCatalogue(desired); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	desiredIds := map[string]bool{}
+	for i := range desired {
+		change, err := r.planObject(&desired[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(change.ManagedObjectId) > 0 {
+			desiredIds[change.ManagedObjectId] = true
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if r.options.Delete {
+		deletions, err := r.planDeletions(desiredIds)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, deletions...)
+	}
+	return plan, nil
+}
+
+/*
+Applies the changes of the plan in order and stops at the first failure. The ids of created
+managed objects are set in the plan. When the external id of a created managed object can not
+be created, the managed object is deleted again.
+*/
+func (r *Reconciler) Apply(plan *Plan) error {
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+
+		var err *generic.Error
+		switch change.Action {
+		case CREATE:
+			err = r.create(change)
+		case UPDATE:
+			_, err = r.inventoryApi.Update(change.ManagedObjectId, updateOf(change.desired, change.catalogue))
+		case DELETE:
+			err = r.inventoryApi.Delete(change.ManagedObjectId)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to apply '%s': %s", change.String(), err.Error())
+		}
+	}
+	return nil
+}
+
+// Plans and applies the changes. When dryRun is true, the plan is returned without applying it.
+func (r *Reconciler) Sync(desired []DesiredObject, dryRun bool) (*Plan, error) {
+	plan, err := r.Plan(desired)
+	if err != nil || dryRun {
+		return plan, err
+	}
+	return plan, r.Apply(plan)
+}
+
+// -- internal
+
+func (r *Reconciler) planObject(desired *DesiredObject) (Change, error) {
+	change := Change{
+		ExternalIdType: desired.externalIdType(),
+		ExternalId:     desired.ExternalId,
+		Name:           desired.Name,
+		desired:        desired,
+	}
+
+	externalId, err := r.identityApi.GetExternalID(change.ExternalIdType, change.ExternalId)
+	if err != nil {
+		return change, fmt.Errorf("error while getting the external id '%s': %s", desired.key(), err.Error())
+	}
+	if externalId == nil {
+		change.Action = CREATE
+		return change, nil
+	}
+
+	managedObject, err := r.inventoryApi.Get(externalId.ManagedObject.Id)
+	if err != nil {
+		return change, fmt.Errorf("error while getting the managed object of '%s': %s", desired.key(), err.Error())
+	}
+	if managedObject == nil {
+		return change, fmt.Errorf("the external id '%s' references the missing managed object %s", desired.key(), externalId.ManagedObject.Id)
+	}
+
+	if r.options.Adopt || isMarked(managedObject, r.options.Catalogue) {
+		change.catalogue = r.options.Catalogue
+	}
+	differences, diffErr := differencesOf(desired, managedObject, change.catalogue)
+	if diffErr != nil {
+		return change, fmt.Errorf("error while comparing '%s': %s", desired.key(), diffErr.Error())
+	}
+
+	change.ManagedObjectId = managedObject.Id
+	change.Differences = differences
+	change.Action = UNCHANGED
+	if len(differences) > 0 {
+		change.Action = UPDATE
+	}
+	return change, nil
+}
+
+func (r *Reconciler) planDeletions(desiredIds map[string]bool) ([]Change, error) {
+	query, buildErr := inventory.NewInventoryQuery(inventory.Eq(SYNC_FRAGMENT+".catalogue", r.options.Catalogue)).Build()
+	if buildErr != nil {
+		return nil, buildErr
+	}
+
+	var deletions []Change
+	collection, err := r.inventoryApi.FindByQuery(query, pageSize)
+	for collection != nil && err == nil {
+		for _, managedObject := range collection.ManagedObjects {
+			if desiredIds[managedObject.Id] {
+				continue
+			}
+
+			change := Change{Action: DELETE, ManagedObjectId: managedObject.Id, Name: managedObject.Name}
+			externalIds, err := r.identityApi.GetExternalIDs(managedObject.Id)
+			if err != nil {
+				return nil, fmt.Errorf("error while getting the external ids of %s: %s", managedObject.Id, err.Error())
+			}
+			if len(externalIds.ExternalIds) > 0 {
+				change.ExternalIdType = externalIds.ExternalIds[0].Type
+				change.ExternalId = externalIds.ExternalIds[0].ExternalId
+			}
+			deletions = append(deletions, change)
+		}
+
+		if len(collection.ManagedObjects) < pageSize {
+			break
+		}
+		collection, err = r.inventoryApi.NextPage(collection)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding the managed objects of catalogue '%s': %s", r.options.Catalogue, err.Error())
+	}
+
+	sort.Slice(deletions, func(i, j int) bool {
+		return deletions[i].ManagedObjectId < deletions[j].ManagedObjectId
+	})
+	return deletions, nil
+}
+
+func (r *Reconciler) create(change *Change) *generic.Error {
+	desired := change.desired
+	newManagedObject := &inventory.NewManagedObject{
+		Type:             desired.Type,
+		Name:             desired.Name,
+		AdditionalFields: fragmentsOf(desired, r.options.Catalogue),
+	}
+	if desired.IsDevice {
+		newManagedObject.C8y_IsDevice = map[string]interface{}{}
+	}
+
+	managedObject, err := r.inventoryApi.Create(newManagedObject)
+	if err != nil {
+		return err
+	}
+
+	newExternalId := identity.NewExternalID{ExternalId: desired.ExternalId, Type: desired.externalIdType()}
+	if _, err := r.identityApi.CreateExternalID(newExternalId, managedObject.Id); err != nil {
+		_ = r.inventoryApi.Delete(managedObject.Id)
+		return err
+	}
+
+	change.ManagedObjectId = managedObject.Id
+	return nil
+}
+
+func updateOf(desired *DesiredObject, catalogue string) *inventory.ManagedObjectUpdate {
+	fragments := fragmentsOf(desired, catalogue)
+	if desired.IsDevice {
+		fragments["c8y_IsDevice"] = map[string]interface{}{}
+	}
+	return &inventory.ManagedObjectUpdate{Type: desired.Type, Name: desired.Name, AdditionalFields: fragments}
+}
+
+// Returns the desired fragments and the SYNC_FRAGMENT, if a catalogue is given.
+func fragmentsOf(desired *DesiredObject, catalogue string) map[string]interface{} {
+	fragments := map[string]interface{}{}
+	if len(catalogue) > 0 {
+		fragments[SYNC_FRAGMENT] = map[string]interface{}{"catalogue": catalogue}
+	}
+	for name, value := range desired.Fragments {
+		fragments[name] = value
+	}
+	return fragments
+}
+
+func isMarked(managedObject *inventory.ManagedObject, catalogue string) bool {
+	marker, ok := managedObject.AdditionalFields[SYNC_FRAGMENT].(map[string]interface{})
+	return ok && marker["catalogue"] == catalogue
+}
+
+// Compares the desired fields with the JSON representation of the managed object.
+func differencesOf(desired *DesiredObject, managedObject *inventory.ManagedObject, catalogue string) ([]string, error) {
+	current := map[string]interface{}{}
+	bytes, err := generic.JsonFromObject(managedObject)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bytes, &current); err != nil {
+		return nil, err
+	}
+
+	var differences []string
+	if len(desired.Name) > 0 && desired.Name != managedObject.Name {
+		differences = append(differences, "name")
+	}
+	if len(desired.Type) > 0 && desired.Type != managedObject.Type {
+		differences = append(differences, "type")
+	}
+	if desired.IsDevice && current["c8y_IsDevice"] == nil {
+		differences = append(differences, "c8y_IsDevice")
+	}
+
+	fragments := fragmentsOf(desired, catalogue)
+	names := make([]string, 0, len(fragments))
+	for name := range fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, err := normalize(fragments[name])
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, current[name]) {
+			differences = append(differences, name)
+		}
+	}
+	return differences, nil
+}
+
+// Converts a value to its generic JSON representation, ex. structs to maps and ints to float64.
+func normalize(value interface{}) (interface{}, error) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	err = json.Unmarshal(bytes, &normalized)
+	return normalized, err
+}
@@ -0,0 +1,267 @@
+package inventory_sync
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// An inventory with the external ids 4711 -> 101, 4712 -> 102 and 4714 -> 103.
+// 101 and 103 are marked with the catalogue "devices".
+var managedObjects = map[string]string{
+	"101": `{"id": "101", "name": "Sensor A", "type": "c8y_Sensor", "c8y_IsDevice": {}, "c8y_Hardware": {"model": "RPi"}, "gomulocity_Sync": {"catalogue": "devices"}}`,
+	"102": `{"id": "102", "name": "Old name", "type": "c8y_Sensor", "c8y_Hardware": {"model": "RPi 3"}}`,
+	"103": `{"id": "103", "name": "Retired", "gomulocity_Sync": {"catalogue": "devices"}}`,
+}
+var externalIds = map[string]string{"4711": "101", "4712": "102", "4714": "103"}
+
+var catalogue = `[
+	{"externalId": "4711", "name": "Sensor A", "type": "c8y_Sensor", "isDevice": true, "fragments": {"c8y_Hardware": {"model": "RPi"}}},
+	{"externalId": "4712", "name": "Sensor B", "type": "c8y_Sensor", "isDevice": true, "fragments": {"c8y_Hardware": {"model": "RPi"}}},
+	{"externalId": "4713", "name": "Sensor C", "isDevice": true}
+]`
+
+// Serves the inventory and records all modifying requests with their bodies.
+func buildReconciler(t *testing.T, options Options, externalIdStatus int, requests *[]string) (*Reconciler, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodGet {
+			*requests = append(*requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		}
+
+		path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.Method == http.MethodGet && len(path) == 4 && path[1] == "externalIds":
+			id, ok := externalIds[path[3]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"externalId": "%s", "type": "%s", "managedObject": {"id": "%s"}}`, path[3], path[2], id)
+		case r.Method == http.MethodGet && len(path) == 4 && path[1] == "globalIds":
+			_, _ = fmt.Fprintf(w, `{"externalIds": [{"externalId": "4714", "type": "c8y_Serial", "managedObject": {"id": "%s"}}]}`, path[2])
+		case r.Method == http.MethodPost && path[0] == "identity":
+			w.WriteHeader(externalIdStatus)
+			_, _ = w.Write([]byte(`{"externalId": "4713", "type": "c8y_Serial"}`))
+		case r.Method == http.MethodGet && len(path) == 3:
+			_, _ = w.Write([]byte(managedObjects[path[2]]))
+		case r.Method == http.MethodGet:
+			if query := r.URL.Query().Get("query"); query != "$filter=gomulocity_Sync.catalogue eq 'devices'" {
+				t.Errorf("Unexpected query %s", query)
+			}
+			_, _ = fmt.Fprintf(w, `{"managedObjects": [%s, %s]}`, managedObjects["101"], managedObjects["103"])
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": "104"}`))
+		case r.Method == http.MethodPut:
+			_, _ = w.Write([]byte(managedObjects[path[2]]))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	reconciler, err := NewReconciler(identity.NewIdentityAPI(client), inventory.NewInventoryApi(client), options)
+	if err != nil {
+		t.Fatalf("NewReconciler() got an unexpected error: %s", err)
+	}
+	return reconciler, ts
+}
+
+func readCatalogue(t *testing.T) []DesiredObject {
+	desired, err := ReadCatalogue(strings.NewReader(catalogue))
+	if err != nil {
+		t.Fatalf("ReadCatalogue() got an unexpected error: %s", err)
+	}
+	return desired
+}
+
+func TestReconciler_Plan(t *testing.T) {
+	// given
+	var requests []string
+	reconciler, ts := buildReconciler(t, Options{Catalogue: "devices", Delete: true}, http.StatusCreated, &requests)
+	defer ts.Close()
+
+	// when
+	plan, err := reconciler.Plan(readCatalogue(t))
+
+	// then
+	if err != nil {
+		t.Fatalf("Plan() got an unexpected error: %s", err)
+	}
+	var output bytes.Buffer
+	_ = plan.Write(&output)
+	expected := `~ update    c8y_Serial/4712 (102) "Sensor B": name, c8y_IsDevice, c8y_Hardware
++ create    c8y_Serial/4713 "Sensor C"
+- delete    c8y_Serial/4714 (103) "Retired"
+Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged.
+`
+	if output.String() != expected {
+		t.Errorf("Plan() = \n%s\nwant\n%s", output.String(), expected)
+	}
+	if !plan.HasChanges() {
+		t.Errorf("HasChanges() = false, want true")
+	}
+	if len(requests) != 0 {
+		t.Errorf("Plan() modified the inventory: %v", requests)
+	}
+}
+
+func TestReconciler_Plan_WithoutDelete(t *testing.T) {
+	var requests []string
+	reconciler, ts := buildReconciler(t, Options{Catalogue: "devices"}, http.StatusCreated, &requests)
+	defer ts.Close()
+
+	plan, err := reconciler.Plan(readCatalogue(t)[:1])
+
+	if err != nil {
+		t.Fatalf("Plan() got an unexpected error: %s", err)
+	}
+	if plan.HasChanges() || plan.Count(UNCHANGED) != 1 || plan.Changes[0].ManagedObjectId != "101" {
+		t.Errorf("Plan() = %v, want 101 unchanged", plan.Changes)
+	}
+}
+
+func TestReconciler_Sync_DryRun(t *testing.T) {
+	var requests []string
+	reconciler, ts := buildReconciler(t, Options{Catalogue: "devices", Delete: true}, http.StatusCreated, &requests)
+	defer ts.Close()
+
+	plan, err := reconciler.Sync(readCatalogue(t), true)
+
+	if err != nil || plan.Count(DELETE) != 1 {
+		t.Fatalf("Sync() = %v, %v", plan, err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("Sync() modified the inventory in a dry run: %v", requests)
+	}
+}
+
+func TestReconciler_Sync(t *testing.T) {
+	// given
+	var requests []string
+	reconciler, ts := buildReconciler(t, Options{Catalogue: "devices", Delete: true}, http.StatusCreated, &requests)
+	defer ts.Close()
+
+	// when
+	plan, err := reconciler.Sync(readCatalogue(t), false)
+
+	// then
+	if err != nil {
+		t.Fatalf("Sync() got an unexpected error: %s", err)
+	}
+	// then: the existing managed object 102 was not marked with the catalogue
+	expected := []string{
+		`PUT /inventory/managedObjects/102 {"c8y_Hardware":{"model":"RPi"},"c8y_IsDevice":{},"name":"Sensor B","type":"c8y_Sensor"}`,
+		`POST /inventory/managedObjects {"c8y_IsDevice":{},"gomulocity_Sync":{"catalogue":"devices"},"name":"Sensor C"}`,
+		`POST /identity/globalIds/104/externalIds {"externalId":"4713","type":"c8y_Serial"}`,
+		`DELETE /inventory/managedObjects/103`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Requests = \n%s\nwant\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+	if plan.Changes[2].ManagedObjectId != "104" {
+		t.Errorf("Created managed object id = %s, want 104", plan.Changes[2].ManagedObjectId)
+	}
+}
+
+func TestReconciler_Sync_Adopt(t *testing.T) {
+	// given: the unmarked managed object 102 and the marked 101 are desired
+	var requests []string
+	reconciler, ts := buildReconciler(t, Options{Catalogue: "devices", Adopt: true}, http.StatusCreated, &requests)
+	defer ts.Close()
+
+	// when
+	plan, err := reconciler.Sync(readCatalogue(t)[:2], false)
+
+	// then: 102 was marked with the catalogue, 101 was kept
+	if err != nil {
+		t.Fatalf("Sync() got an unexpected error: %s", err)
+	}
+	if plan.Changes[0].Action != UNCHANGED || strings.Join(plan.Changes[1].Differences, ", ") != "name, c8y_IsDevice, c8y_Hardware, gomulocity_Sync" {
+		t.Errorf("Sync() = %v, want 101 unchanged and 102 to be adopted", plan.Changes)
+	}
+	expected := `PUT /inventory/managedObjects/102 {"c8y_Hardware":{"model":"RPi"},"c8y_IsDevice":{},"gomulocity_Sync":{"catalogue":"devices"},"name":"Sensor B","type":"c8y_Sensor"}`
+	if len(requests) != 1 || requests[0] != expected {
+		t.Errorf("Requests = %v, want %s", requests, expected)
+	}
+}
+
+func TestReconciler_Sync_DeletesManagedObjectWithoutExternalId(t *testing.T) {
+	// given: the external id can not be created
+	var requests []string
+	reconciler, ts := buildReconciler(t, Options{Catalogue: "devices"}, http.StatusConflict, &requests)
+	defer ts.Close()
+
+	// when
+	_, err := reconciler.Sync(readCatalogue(t)[2:], false)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), `failed to apply '+ create    c8y_Serial/4713 "Sensor C"'`) {
+		t.Errorf("Sync() got an unexpected error: %v", err)
+	}
+	if len(requests) != 3 || requests[2] != "DELETE /inventory/managedObjects/104" {
+		t.Errorf("Requests = %v, want the created managed object to be deleted", requests)
+	}
+}
+
+func TestReadCatalogue_Yaml(t *testing.T) {
+	yamlCatalogue := `
+- externalId: "4711"
+  name: Sensor A
+  isDevice: true
+  fragments:
+    c8y_Hardware:
+      model: RPi
+      revision: 3
+- externalIdType: c8y_Imei
+  externalId: "4712"
+`
+
+	desired, err := ReadCatalogue(strings.NewReader(yamlCatalogue))
+
+	if err != nil {
+		t.Fatalf("ReadCatalogue() got an unexpected error: %s", err)
+	}
+	expected := `[{ 4711  Sensor A true map[c8y_Hardware:map[model:RPi revision:3]]} {c8y_Imei 4712   false map[]}]`
+	if fmt.Sprint(desired) != expected {
+		t.Errorf("ReadCatalogue() = %v, want %s", desired, expected)
+	}
+}
+
+func TestReadCatalogue_Invalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		catalogue     string
+		expectedError string
+	}{
+		{"No JSON", `{`, "error while reading the catalogue"},
+		{"No array", `externalId: "1"`, "error while reading the catalogue"},
+		{"Without external id", `[{"name": "Sensor"}]`, "invalid catalogue: object 0 has no external id"},
+		{"Duplicate", `[{"externalId": "1"}, {"externalIdType": "c8y_Serial", "externalId": "1"}]`, "invalid catalogue: external id 'c8y_Serial/1' is not unique"},
+		{"Reserved fragment", `[{"externalId": "1", "fragments": {"name": "x"}}]`, "invalid catalogue: fragment 'name' of 'c8y_Serial/1' is reserved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCatalogue(strings.NewReader(tt.catalogue))
+
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expectedError) {
+				t.Errorf("ReadCatalogue() error = %v, want %s", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestNewReconciler_WithoutCatalogue(t *testing.T) {
+	if _, err := NewReconciler(nil, nil, Options{}); err == nil {
+		t.Errorf("NewReconciler() without catalogue got no error")
+	}
+}
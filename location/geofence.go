@@ -0,0 +1,39 @@
+package location
+
+// A geographic area.
+type Geofence interface {
+	Contains(p Position) bool
+}
+
+// A circular area around a center with a radius in meters.
+type CircleGeofence struct {
+	Center Position
+	Radius float64
+}
+
+func (c CircleGeofence) Contains(p Position) bool {
+	return c.Center.DistanceTo(p) <= c.Radius
+}
+
+/*
+A polygon area given by its vertices. The polygon is closed implicitly.
+The edges are treated as straight lines in the lat/lng plane, which is precise enough for
+areas of some kilometers, but not for polygons crossing the 180th meridian.
+*/
+type PolygonGeofence struct {
+	Vertices []Position
+}
+
+// Uses the ray casting algorithm. Points exactly on an edge may be inside or outside.
+func (g PolygonGeofence) Contains(p Position) bool {
+	inside := false
+	n := len(g.Vertices)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := g.Vertices[i], g.Vertices[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lng < (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
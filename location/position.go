@@ -0,0 +1,115 @@
+package location
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	POSITION_FRAGMENT    = "c8y_Position"
+	LOCATION_UPDATE_TYPE = "c8y_LocationUpdate"
+
+	EARTH_RADIUS = 6371008.8 // Mean earth radius in meters.
+)
+
+/*
+Position of a device in WGS84 coordinates, as used by the c8y_Position fragment.
+See: https://cumulocity.com/guides/reference/device-management/#location
+*/
+type Position struct {
+	Lat      float64  `json:"lat"`
+	Lng      float64  `json:"lng"`
+	Alt      *float64 `json:"alt,omitempty"`      // Altitude in meters.
+	Accuracy *float64 `json:"accuracy,omitempty"` // Accuracy in meters.
+}
+
+// Validates latitude and longitude.
+func (p Position) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v must be between -90 and 90", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("longitude %v must be between -180 and 180", p.Lng)
+	}
+	return nil
+}
+
+// Returns the great-circle distance to the other position in meters. The altitude is ignored.
+func (p Position) DistanceTo(other Position) float64 {
+	lat1 := p.Lat * math.Pi / 180
+	lat2 := other.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (other.Lng - p.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EARTH_RADIUS * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+/*
+Reads the c8y_Position fragment of the given additional fields, ex. of an event or a managed object.
+Returns false, when there is no valid position.
+*/
+func PositionFromFragments(fragments map[string]interface{}) (*Position, bool) {
+	fragment, ok := fragments[POSITION_FRAGMENT]
+	if !ok || fragment == nil {
+		return nil, false
+	}
+
+	bytes, err := json.Marshal(fragment)
+	if err != nil {
+		return nil, false
+	}
+	var position Position
+	if err := json.Unmarshal(bytes, &position); err != nil || position.Validate() != nil {
+		return nil, false
+	}
+	return &position, true
+}
+
+// A position of a device at a point in time.
+type TrackPoint struct {
+	Time     time.Time
+	EventId  string // Id of the location update event.
+	Position Position
+}
+
+// The positions of a device, the oldest first.
+type Track []TrackPoint
+
+// Returns the distance along the track in meters.
+func (t Track) Distance() float64 {
+	var distance float64
+	for i := 1; i < len(t); i++ {
+		distance += t[i-1].Position.DistanceTo(t[i].Position)
+	}
+	return distance
+}
+
+// Returns the first and the last point of the track. Returns false for an empty track.
+func (t Track) Bounds() (TrackPoint, TrackPoint, bool) {
+	if len(t) == 0 {
+		return TrackPoint{}, TrackPoint{}, false
+	}
+	return t[0], t[len(t)-1], true
+}
+
+// A transition of a track into or out of a geofence.
+type Transition struct {
+	Point   TrackPoint // The first point inside respectively outside of the geofence.
+	Entered bool       // True if the track entered the geofence, false if it left.
+}
+
+// Returns the points, at which the track enters or leaves the geofence.
+func (t Track) Transitions(fence Geofence) []Transition {
+	var transitions []Transition
+	for i := 1; i < len(t); i++ {
+		before := fence.Contains(t[i-1].Position)
+		after := fence.Contains(t[i].Position)
+		if before != after {
+			transitions = append(transitions, Transition{Point: t[i], Entered: after})
+		}
+	}
+	return transitions
+}
@@ -0,0 +1,142 @@
+package location
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	berlin  = Position{Lat: 52.5200, Lng: 13.4050}
+	hamburg = Position{Lat: 53.5511, Lng: 9.9937}
+	bonn    = Position{Lat: 50.7374, Lng: 7.0982}
+)
+
+func TestPosition_DistanceTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     Position
+		to       Position
+		expected float64 // km
+	}{
+		{"Same position", berlin, berlin, 0},
+		{"Berlin to Hamburg", berlin, hamburg, 255.3},
+		{"Hamburg to Berlin", hamburg, berlin, 255.3},
+		{"Berlin to Bonn", berlin, bonn, 478.4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.from.DistanceTo(tt.to) / 1000
+			if math.Abs(got-tt.expected) > 0.5 {
+				t.Errorf("DistanceTo() = %.1f km, want %.1f km", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPosition_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		position Position
+		valid    bool
+	}{
+		{"Valid", berlin, true},
+		{"Poles and date line", Position{Lat: -90, Lng: 180}, true},
+		{"Latitude too large", Position{Lat: 90.1}, false},
+		{"Longitude too small", Position{Lng: -180.1}, false},
+		{"NaN", Position{Lat: math.NaN()}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.position.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestPositionFromFragments(t *testing.T) {
+	tests := []struct {
+		name      string
+		fragments map[string]interface{}
+		expected  *Position
+	}{
+		{"Parsed JSON", map[string]interface{}{"c8y_Position": map[string]interface{}{"lat": 52.52, "lng": 13.405}}, &Position{Lat: 52.52, Lng: 13.405}},
+		{"Typed", map[string]interface{}{"c8y_Position": berlin}, &berlin},
+		{"Missing", map[string]interface{}{"c8y_Other": true}, nil},
+		{"Invalid", map[string]interface{}{"c8y_Position": map[string]interface{}{"lat": 100, "lng": 0}}, nil},
+		{"Malformed", map[string]interface{}{"c8y_Position": "here"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := PositionFromFragments(tt.fragments)
+			if tt.expected == nil {
+				if ok || got != nil {
+					t.Errorf("PositionFromFragments() = %v, want none", got)
+				}
+				return
+			}
+			if !ok || got.Lat != tt.expected.Lat || got.Lng != tt.expected.Lng {
+				t.Errorf("PositionFromFragments() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGeofence_Contains(t *testing.T) {
+	circle := CircleGeofence{Center: berlin, Radius: 300000}
+	polygon := PolygonGeofence{Vertices: []Position{{Lat: 52, Lng: 13}, {Lat: 52, Lng: 14}, {Lat: 53, Lng: 14}, {Lat: 53, Lng: 13}}}
+
+	tests := []struct {
+		name     string
+		fence    Geofence
+		position Position
+		expected bool
+	}{
+		{"Circle center", circle, berlin, true},
+		{"Circle inside", circle, hamburg, true},
+		{"Circle outside", circle, bonn, false},
+		{"Polygon inside", polygon, berlin, true},
+		{"Polygon outside", polygon, hamburg, false},
+		{"Empty polygon", PolygonGeofence{}, berlin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fence.Contains(tt.position); got != tt.expected {
+				t.Errorf("Contains() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	start := time.Date(2020, 6, 30, 8, 0, 0, 0, time.UTC)
+	track := Track{
+		{Time: start, EventId: "1", Position: bonn},
+		{Time: start.Add(time.Hour), EventId: "2", Position: berlin},
+		{Time: start.Add(2 * time.Hour), EventId: "3", Position: hamburg},
+	}
+	fence := CircleGeofence{Center: berlin, Radius: 10000}
+
+	if distance := track.Distance() / 1000; math.Abs(distance-733.7) > 1 {
+		t.Errorf("Distance() = %.1f km, want 733.7 km", distance)
+	}
+
+	transitions := track.Transitions(fence)
+	if len(transitions) != 2 || !transitions[0].Entered || transitions[0].Point.EventId != "2" ||
+		transitions[1].Entered || transitions[1].Point.EventId != "3" {
+		t.Errorf("Transitions() = %v", transitions)
+	}
+
+	first, last, ok := track.Bounds()
+	if !ok || first.EventId != "1" || last.EventId != "3" {
+		t.Errorf("Bounds() = %v, %v, %v", first, last, ok)
+	}
+	if _, _, ok := (Track{}).Bounds(); ok {
+		t.Errorf("Bounds() of an empty track is ok")
+	}
+}
@@ -0,0 +1,114 @@
+package location
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/inventory"
+	"sort"
+	"time"
+)
+
+const (
+	DEFAULT_LOCATION_TEXT = "Location updated"
+
+	pageSize = 2000
+)
+
+/*
+Tracker reports and reads the locations of devices.
+A location update is stored as c8y_LocationUpdate event and as c8y_Position fragment of the managed object.
+*/
+type Tracker struct {
+	eventsApi    events.Events
+	inventoryApi inventory.InventoryApi
+}
+
+// Creates a new tracker.
+// eventsApi - used to create and find location update events.
+// inventoryApi - used to update and get the current position of devices.
+func NewTracker(eventsApi events.Events, inventoryApi inventory.InventoryApi) *Tracker {
+	return &Tracker{eventsApi: eventsApi, inventoryApi: inventoryApi}
+}
+
+/*
+Reports the position of a device at the given time. Creates a c8y_LocationUpdate event and
+updates the c8y_Position fragment of the managed object. When no text is given, DEFAULT_LOCATION_TEXT is used.
+
+See: https://cumulocity.com/guides/reference/device-management/#location
+*/
+func (t *Tracker) UpdateLocation(source string, position Position, at time.Time, text string) (*events.Event, error) {
+	if len(source) == 0 {
+		return nil, fmt.Errorf("updating a location without source is not allowed")
+	}
+	if err := position.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid position: %s", err.Error())
+	}
+	if len(text) == 0 {
+		text = DEFAULT_LOCATION_TEXT
+	}
+
+	event, err := t.eventsApi.CreateEvent(&events.CreateEvent{
+		Type:             LOCATION_UPDATE_TYPE,
+		Time:             at,
+		Text:             text,
+		Source:           events.Source{Id: source},
+		AdditionalFields: map[string]interface{}{POSITION_FRAGMENT: position},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the location update of %s: %s", source, err.Error())
+	}
+
+	_, err = t.inventoryApi.Update(source, &inventory.ManagedObjectUpdate{
+		AdditionalFields: map[string]interface{}{POSITION_FRAGMENT: position},
+	})
+	if err != nil {
+		return event, fmt.Errorf("error while updating the position of %s: %s", source, err.Error())
+	}
+	return event, nil
+}
+
+// Returns the current position of a device from its managed object. Returns nil, if the device has no position.
+func (t *Tracker) CurrentPosition(source string) (*Position, error) {
+	managedObject, err := t.inventoryApi.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the position of %s: %s", source, err.Error())
+	}
+	if managedObject == nil {
+		return nil, fmt.Errorf("managed object %s does not exist", source)
+	}
+
+	position, _ := PositionFromFragments(managedObject.AdditionalFields)
+	return position, nil
+}
+
+/*
+Returns the track of a device from its location update events between from and to, the oldest position first.
+Events without a valid c8y_Position are skipped.
+*/
+func (t *Tracker) Track(source string, from time.Time, to time.Time) (Track, error) {
+	if len(source) == 0 {
+		return nil, fmt.Errorf("getting a track without source is not allowed")
+	}
+
+	track := Track{}
+	query := &events.EventQuery{Source: source, Type: LOCATION_UPDATE_TYPE, DateFrom: &from, DateTo: &to}
+	collection, err := t.eventsApi.Find(query, pageSize)
+	for ; collection != nil; collection, err = t.eventsApi.NextPage(collection) {
+		for _, event := range collection.Events {
+			if position, ok := PositionFromFragments(event.AdditionalFields); ok {
+				track = append(track, TrackPoint{Time: event.Time, EventId: event.Id, Position: *position})
+			}
+		}
+		if len(collection.Events) < pageSize {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while finding the location updates of %s: %s", source, err.Error())
+	}
+
+	sort.SliceStable(track, func(i, j int) bool {
+		return track[i].Time.Before(track[j].Time)
+	})
+	return track, nil
+}
@@ -0,0 +1,112 @@
+package location
+
+import (
+	"github.com/tarent/gomulocity/events"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/inventory"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const locationEvents = `{"events": [
+	{"id": "2", "type": "c8y_LocationUpdate", "time": "2020-06-30T09:00:00Z", "source": {"id": "4711"}, "c8y_Position": {"lat": 53.5511, "lng": 9.9937}},
+	{"id": "1", "type": "c8y_LocationUpdate", "time": "2020-06-30T08:00:00Z", "source": {"id": "4711"}, "c8y_Position": {"lat": 52.52, "lng": 13.405, "alt": 34}},
+	{"id": "3", "type": "c8y_LocationUpdate", "time": "2020-06-30T08:30:00Z", "source": {"id": "4711"}}
+]}`
+
+// Records all requests with their bodies and responds with the given status and body per method.
+func buildTracker(responses map[string]string, requests *[]string) (*Tracker, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*requests = append(*requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_, _ = w.Write([]byte(responses[r.Method]))
+	}))
+
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	return NewTracker(events.NewEventsApi(client), inventory.NewInventoryApi(client)), ts
+}
+
+func TestTracker_UpdateLocation(t *testing.T) {
+	// given
+	var requests []string
+	tracker, ts := buildTracker(map[string]string{
+		http.MethodPost: `{"id": "1", "type": "c8y_LocationUpdate", "source": {"id": "4711"}}`,
+		http.MethodPut:  `{"id": "4711"}`,
+	}, &requests)
+	defer ts.Close()
+	alt := 34.0
+
+	// when
+	event, err := tracker.UpdateLocation("4711", Position{Lat: 52.52, Lng: 13.405, Alt: &alt}, time.Date(2020, 6, 30, 8, 0, 0, 0, time.UTC), "")
+
+	// then
+	if err != nil {
+		t.Fatalf("UpdateLocation() got an unexpected error: %s", err)
+	}
+	if event.Id != "1" {
+		t.Errorf("UpdateLocation() = %v", event)
+	}
+	expected := []string{
+		`POST /event/events {"c8y_Position":{"lat":52.52,"lng":13.405,"alt":34},"source":{"id":"4711"},"text":"Location updated","time":"2020-06-30T08:00:00Z","type":"c8y_LocationUpdate"}`,
+		`PUT /inventory/managedObjects/4711 {"c8y_Position":{"lat":52.52,"lng":13.405,"alt":34}}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Requests = %v, want %v", requests, expected)
+	}
+}
+
+func TestTracker_UpdateLocation_Invalid(t *testing.T) {
+	var requests []string
+	tracker, ts := buildTracker(map[string]string{}, &requests)
+	defer ts.Close()
+
+	if _, err := tracker.UpdateLocation("", berlin, time.Now(), ""); err == nil {
+		t.Errorf("UpdateLocation() without source got no error")
+	}
+	if _, err := tracker.UpdateLocation("4711", Position{Lat: 91}, time.Now(), ""); err == nil {
+		t.Errorf("UpdateLocation() with invalid position got no error")
+	}
+	if len(requests) != 0 {
+		t.Errorf("Requests = %v, want none", requests)
+	}
+}
+
+func TestTracker_Track(t *testing.T) {
+	var requests []string
+	tracker, ts := buildTracker(map[string]string{http.MethodGet: locationEvents}, &requests)
+	defer ts.Close()
+	from := time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	track, err := tracker.Track("4711", from, from.Add(24*time.Hour))
+
+	if err != nil {
+		t.Fatalf("Track() got an unexpected error: %s", err)
+	}
+	if len(track) != 2 || track[0].EventId != "1" || track[1].EventId != "2" || *track[0].Position.Alt != 34 {
+		t.Errorf("Track() = %v, want the events 1 and 2", track)
+	}
+	expected := "GET /event/events?dateFrom=2020-06-30T00%3A00%3A00Z&dateTo=2020-07-01T00%3A00%3A00Z&pageSize=2000&source=4711&type=c8y_LocationUpdate"
+	if len(requests) != 1 || requests[0] != expected {
+		t.Errorf("Requests = %v, want %s", requests, expected)
+	}
+}
+
+func TestTracker_CurrentPosition(t *testing.T) {
+	var requests []string
+	tracker, ts := buildTracker(map[string]string{http.MethodGet: `{"id": "4711", "c8y_Position": {"lat": 52.52, "lng": 13.405}}`}, &requests)
+	defer ts.Close()
+
+	position, err := tracker.CurrentPosition("4711")
+
+	if err != nil || position == nil || position.Lat != 52.52 || position.Lng != 13.405 {
+		t.Errorf("CurrentPosition() = %v, %v", position, err)
+	}
+}
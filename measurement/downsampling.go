@@ -0,0 +1,154 @@
+package measurement
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"sort"
+	"time"
+)
+
+type DownsampleOptions struct {
+	Fragment     string        // Value fragment of the series, e.g. "c8y_TemperatureMeasurement".
+	Series       string        // Series name within the fragment, e.g. "T".
+	Interval     time.Duration // Length of a bucket. Buckets are aligned to multiples of the interval since the zero time.
+	GapThreshold time.Duration // Distances between two values larger than this are reported as gaps. Zero disables gap detection.
+}
+
+// Aggregated values of a series within [Start, End).
+type Bucket struct {
+	Start time.Time
+	End   time.Time
+	Count int
+	Avg   float64
+	Min   float64
+	Max   float64
+	Last  float64 // The value with the latest time within the bucket.
+	Unit  string
+}
+
+// A period without any values of a series.
+type Gap struct {
+	From     time.Time // Time of the last value before the gap.
+	To       time.Time // Time of the first value after the gap.
+	Duration time.Duration
+}
+
+type DownsampleResult struct {
+	Buckets []Bucket // Ordered by time. Intervals without values have no bucket.
+	Gaps    []Gap    // Ordered by time.
+}
+
+/*
+Consumes the iterator and aggregates the values of one series into buckets and detects gaps, in a single pass.
+
+Intended for raw data where the platform's series aggregation is not available or too coarse.
+Measurements without the series are skipped. The iterator may deliver the measurements
+in ascending or descending order (see MeasurementQuery.Revert).
+*/
+func Downsample(it *MeasurementIterator, options DownsampleOptions) (*DownsampleResult, *generic.Error) {
+	if options.Interval <= 0 {
+		return nil, generic.ClientError(fmt.Sprintf("The interval must be positive. Was %s", options.Interval), "Downsample")
+	}
+	if len(options.Fragment) == 0 || len(options.Series) == 0 {
+		return nil, generic.ClientError("Fragment and series must not be empty", "Downsample")
+	}
+
+	buckets := map[int64]*bucketState{}
+	var gaps []Gap
+	var previous *time.Time
+
+	for it.Next() {
+		m := it.Measurement()
+		if m.Time == nil {
+			continue
+		}
+		valueFragment, ok := m.ValueFragment(options.Fragment, options.Series)
+		if !ok {
+			continue
+		}
+
+		start := m.Time.Truncate(options.Interval)
+		state, ok := buckets[start.UnixNano()]
+		if !ok {
+			state = &bucketState{Bucket: Bucket{Start: start, End: start.Add(options.Interval), Min: valueFragment.Value, Max: valueFragment.Value}}
+			buckets[start.UnixNano()] = state
+		}
+		state.add(*m.Time, valueFragment)
+
+		if options.GapThreshold > 0 && previous != nil {
+			if gap, ok := gapBetween(*previous, *m.Time, options.GapThreshold); ok {
+				gaps = append(gaps, gap)
+			}
+		}
+		current := *m.Time
+		previous = &current
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &DownsampleResult{Buckets: make([]Bucket, 0, len(buckets)), Gaps: gaps}
+	for _, state := range buckets {
+		state.Avg = state.sum / float64(state.Count)
+		result.Buckets = append(result.Buckets, state.Bucket)
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		return result.Buckets[i].Start.Before(result.Buckets[j].Start)
+	})
+	sort.Slice(result.Gaps, func(i, j int) bool {
+		return result.Gaps[i].From.Before(result.Gaps[j].From)
+	})
+
+	return result, nil
+}
+
+// Consumes the iterator and reports all gaps of the series larger than the threshold.
+func FindGaps(it *MeasurementIterator, fragment string, series string, threshold time.Duration) ([]Gap, *generic.Error) {
+	if threshold <= 0 {
+		return nil, generic.ClientError(fmt.Sprintf("The threshold must be positive. Was %s", threshold), "FindGaps")
+	}
+
+	// The interval is irrelevant for the gaps, one bucket per threshold keeps the memory footprint small.
+	result, err := Downsample(it, DownsampleOptions{Fragment: fragment, Series: series, Interval: threshold, GapThreshold: threshold})
+	if err != nil {
+		return nil, err
+	}
+	return result.Gaps, nil
+}
+
+// -- internal
+
+type bucketState struct {
+	Bucket
+	sum      float64
+	lastTime time.Time
+}
+
+func (state *bucketState) add(t time.Time, valueFragment *ValueFragment) {
+	state.Count++
+	state.sum += valueFragment.Value
+	if valueFragment.Value < state.Min {
+		state.Min = valueFragment.Value
+	}
+	if valueFragment.Value > state.Max {
+		state.Max = valueFragment.Value
+	}
+	if state.Count == 1 || !t.Before(state.lastTime) {
+		state.lastTime = t
+		state.Last = valueFragment.Value
+		state.Unit = valueFragment.Unit
+	}
+}
+
+func gapBetween(a time.Time, b time.Time, threshold time.Duration) (Gap, bool) {
+	from, to := a, b
+	if to.Before(from) {
+		from, to = to, from
+	}
+
+	duration := to.Sub(from)
+	if duration <= threshold {
+		return Gap{}, false
+	}
+	return Gap{From: from, To: to, Duration: duration}, true
+}
@@ -0,0 +1,140 @@
+package measurement
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var seriesStart, _ = time.Parse(time.RFC3339, "2020-07-01T10:00:00Z")
+
+func seriesIterator(measurements []string) (*MeasurementIterator, func()) {
+	requests := 0
+	ts := buildPagingHttpServer(measurements, &requests)
+	return NewMeasurementIterator(buildMeasurementApi(ts.URL), &MeasurementQuery{}, 3), ts.Close
+}
+
+func TestDownsample(t *testing.T) {
+	// given: values every 20 seconds with a gap between 10:01:00 and 10:04:00 and a measurement of another type
+	measurements := []string{
+		temperatureAt(seriesStart, 20),
+		temperatureAt(seriesStart.Add(20*time.Second), 22),
+		temperatureAt(seriesStart.Add(40*time.Second), 21),
+		`{"id": "99", "time": "2020-07-01T10:00:50Z", "source": {"id": "4711"}, "c8y_Battery": {"level": {"value": 80, "unit": "%"}}}`,
+		temperatureAt(seriesStart.Add(60*time.Second), 25),
+		temperatureAt(seriesStart.Add(240*time.Second), 19),
+	}
+	it, closeServer := seriesIterator(measurements)
+	defer closeServer()
+
+	// when
+	result, err := Downsample(it, DownsampleOptions{
+		Fragment:     "c8y_TemperatureMeasurement",
+		Series:       "T",
+		Interval:     time.Minute,
+		GapThreshold: time.Minute,
+	})
+
+	// then
+	if err != nil {
+		t.Fatalf("Downsample() got an unexpected error: %s", err)
+	}
+
+	expectedBuckets := []Bucket{
+		{Start: seriesStart, End: seriesStart.Add(time.Minute), Count: 3, Avg: 21, Min: 20, Max: 22, Last: 21, Unit: "C"},
+		{Start: seriesStart.Add(time.Minute), End: seriesStart.Add(2 * time.Minute), Count: 1, Avg: 25, Min: 25, Max: 25, Last: 25, Unit: "C"},
+		{Start: seriesStart.Add(4 * time.Minute), End: seriesStart.Add(5 * time.Minute), Count: 1, Avg: 19, Min: 19, Max: 19, Last: 19, Unit: "C"},
+	}
+	if !reflect.DeepEqual(result.Buckets, expectedBuckets) {
+		t.Errorf("Downsample() buckets = %+v\nwant %+v", result.Buckets, expectedBuckets)
+	}
+
+	expectedGaps := []Gap{
+		{From: seriesStart.Add(time.Minute), To: seriesStart.Add(4 * time.Minute), Duration: 3 * time.Minute},
+	}
+	if !reflect.DeepEqual(result.Gaps, expectedGaps) {
+		t.Errorf("Downsample() gaps = %+v\nwant %+v", result.Gaps, expectedGaps)
+	}
+}
+
+func TestDownsample_DescendingOrder(t *testing.T) {
+	// given: values in descending order, as delivered with `revert=true`
+	measurements := []string{
+		temperatureAt(seriesStart.Add(50*time.Second), 30),
+		temperatureAt(seriesStart.Add(10*time.Second), 10),
+	}
+	it, closeServer := seriesIterator(measurements)
+	defer closeServer()
+
+	// when
+	result, err := Downsample(it, DownsampleOptions{Fragment: "c8y_TemperatureMeasurement", Series: "T", Interval: time.Minute, GapThreshold: 30 * time.Second})
+
+	// then
+	if err != nil {
+		t.Fatalf("Downsample() got an unexpected error: %s", err)
+	}
+	if len(result.Buckets) != 1 || result.Buckets[0].Last != 30 || result.Buckets[0].Avg != 20 {
+		t.Errorf("Downsample() buckets = %+v, want one bucket with last value 30 and avg 20", result.Buckets)
+	}
+	if len(result.Gaps) != 1 || !result.Gaps[0].From.Equal(seriesStart.Add(10*time.Second)) || result.Gaps[0].Duration != 40*time.Second {
+		t.Errorf("Downsample() gaps = %+v, want one gap of 40s starting at 10:00:10", result.Gaps)
+	}
+}
+
+func TestDownsample_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DownsampleOptions
+	}{
+		{"No interval", DownsampleOptions{Fragment: "c8y_TemperatureMeasurement", Series: "T"}},
+		{"No fragment", DownsampleOptions{Series: "T", Interval: time.Minute}},
+		{"No series", DownsampleOptions{Fragment: "c8y_TemperatureMeasurement", Interval: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Downsample(NewMeasurementIterator(nil, nil, 1), tt.options)
+			if err == nil || result != nil {
+				t.Errorf("Downsample() = %v, %v, want an error", result, err)
+			}
+		})
+	}
+}
+
+func TestFindGaps(t *testing.T) {
+	// given: values with two gaps larger than five minutes
+	measurements := []string{
+		temperatureAt(seriesStart, 20),
+		temperatureAt(seriesStart.Add(10*time.Minute), 20),
+		temperatureAt(seriesStart.Add(12*time.Minute), 20),
+		temperatureAt(seriesStart.Add(30*time.Minute), 20),
+	}
+	it, closeServer := seriesIterator(measurements)
+	defer closeServer()
+
+	// when
+	gaps, err := FindGaps(it, "c8y_TemperatureMeasurement", "T", 5*time.Minute)
+
+	// then
+	if err != nil {
+		t.Fatalf("FindGaps() got an unexpected error: %s", err)
+	}
+	if len(gaps) != 2 || gaps[0].Duration != 10*time.Minute || gaps[1].Duration != 18*time.Minute {
+		t.Errorf("FindGaps() = %+v, want gaps of 10m and 18m", gaps)
+	}
+}
+
+func TestDownsample_Error(t *testing.T) {
+	// given: a failing server
+	ts := buildHttpServer(500, `{"error": "undefined/validationError", "message": "broken"}`)
+	defer ts.Close()
+
+	// when
+	result, err := Downsample(NewMeasurementIterator(buildMeasurementApi(ts.URL), &MeasurementQuery{}, 2),
+		DownsampleOptions{Fragment: "c8y_TemperatureMeasurement", Series: "T", Interval: time.Minute})
+
+	// then
+	if err == nil || result != nil {
+		t.Errorf("Downsample() = %v, %v, want an error", result, err)
+	}
+}
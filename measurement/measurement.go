@@ -1,6 +1,7 @@
 package measurement
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/tarent/gomulocity/generic"
 	"net/url"
@@ -96,3 +97,82 @@ func (q MeasurementQuery) QueryParams(params *url.Values) error {
 	}
 	return nil
 }
+
+// Returns the value fragment of the given fragment and series name, e.g. "c8y_TemperatureMeasurement" and "T".
+// The second return value is false, if the measurement does not contain a numeric value for this series.
+func (m *Measurement) ValueFragment(fragment string, series string) (*ValueFragment, bool) {
+	seriesMap, ok := toMap(m.Metrics[fragment])
+	if !ok {
+		return nil, false
+	}
+
+	return toValueFragment(seriesMap[series])
+}
+
+// Returns all value fragments of the measurement. The outer map is keyed by the fragment name,
+// the inner one by the series name. Metrics, which do not follow the value fragment structure, are skipped.
+func (m *Measurement) ValueFragments() map[string]map[string]ValueFragment {
+	result := map[string]map[string]ValueFragment{}
+	for fragment, value := range m.Metrics {
+		seriesMap, ok := toMap(value)
+		if !ok {
+			continue
+		}
+
+		for series, seriesValue := range seriesMap {
+			valueFragment, ok := toValueFragment(seriesValue)
+			if !ok {
+				continue
+			}
+			if _, ok := result[fragment]; !ok {
+				result[fragment] = map[string]ValueFragment{}
+			}
+			result[fragment][series] = *valueFragment
+		}
+	}
+
+	return result
+}
+
+// Metrics are plain maps when unmarshalled from JSON, but can be any struct when built by hand.
+// Everything which is not a map is converted via a JSON round trip.
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(bytes, &m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+func toValueFragment(value interface{}) (*ValueFragment, bool) {
+	if valueFragment, ok := value.(ValueFragment); ok {
+		return &valueFragment, true
+	}
+	if valueFragment, ok := value.(*ValueFragment); ok && valueFragment != nil {
+		return valueFragment, true
+	}
+
+	fields, ok := toMap(value)
+	if !ok {
+		return nil, false
+	}
+
+	number, ok := fields["value"].(float64)
+	if !ok {
+		return nil, false
+	}
+	unit, _ := fields["unit"].(string)
+
+	return &ValueFragment{Value: number, Unit: unit}, true
+}
@@ -0,0 +1,80 @@
+package measurement
+
+import (
+	"github.com/tarent/gomulocity/generic"
+)
+
+/*
+MeasurementIterator walks through all measurements found by a query, fetching the pages lazily.
+
+	it := NewMeasurementIterator(measurementApi, &MeasurementQuery{SourceId: "4711"}, 2000)
+	for it.Next() {
+		m := it.Measurement()
+		...
+	}
+	if err := it.Err(); err != nil {
+		...
+	}
+*/
+type MeasurementIterator struct {
+	api        MeasurementApi
+	query      *MeasurementQuery
+	pageSize   int
+	collection *MeasurementCollection
+	index      int
+	started    bool
+	done       bool
+	err        *generic.Error
+}
+
+// Creates a new iterator. No request is sent before the first call of Next().
+func NewMeasurementIterator(api MeasurementApi, query *MeasurementQuery, pageSize int) *MeasurementIterator {
+	return &MeasurementIterator{api: api, query: query, pageSize: pageSize}
+}
+
+// Advances to the next measurement. Returns false when all measurements were consumed or an error occurred.
+func (it *MeasurementIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		it.collection, it.err = it.api.Find(it.query, it.pageSize)
+		it.index = -1
+	}
+
+	for {
+		if it.err != nil || it.collection == nil {
+			it.done = true
+			return false
+		}
+
+		it.index++
+		if it.index < len(it.collection.Measurements) {
+			return true
+		}
+
+		// A page which is not full is the last one. Saves a request returning an empty page.
+		if len(it.collection.Measurements) < it.pageSize {
+			it.done = true
+			return false
+		}
+
+		it.collection, it.err = it.api.NextPage(it.collection)
+		it.index = -1
+	}
+}
+
+// Returns the current measurement. Only valid after Next() returned true.
+func (it *MeasurementIterator) Measurement() *Measurement {
+	if it.collection == nil || it.index < 0 || it.index >= len(it.collection.Measurements) {
+		return nil
+	}
+	return &it.collection.Measurements[it.index]
+}
+
+// Returns the error which stopped the iteration, if any.
+func (it *MeasurementIterator) Err() *generic.Error {
+	return it.err
+}
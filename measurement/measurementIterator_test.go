@@ -0,0 +1,123 @@
+package measurement
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Serves the given measurements in pages of the requested page size, like cumulocity does.
+func buildPagingHttpServer(measurements []string, requests *int) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		currentPage, _ := strconv.Atoi(r.URL.Query().Get("currentPage"))
+		if currentPage == 0 {
+			currentPage = 1
+		}
+
+		from := (currentPage - 1) * pageSize
+		to := from + pageSize
+		if from > len(measurements) {
+			from = len(measurements)
+		}
+		if to > len(measurements) {
+			to = len(measurements)
+		}
+
+		next := fmt.Sprintf("%s/measurement/measurements?pageSize=%d&currentPage=%d", ts.URL, pageSize, currentPage+1)
+		_, _ = fmt.Fprintf(w, `{"next": "%s", "measurements": [%s], "statistics": {"currentPage": %d, "pageSize": %d}}`,
+			next, strings.Join(measurements[from:to], ","), currentPage, pageSize)
+	}))
+	return ts
+}
+
+func temperatureAt(t time.Time, value float64) string {
+	return fmt.Sprintf(`{"id": "%d", "time": "%s", "type": "c8y_TemperatureMeasurement", "source": {"id": "4711"}, "c8y_TemperatureMeasurement": {"T": {"value": %v, "unit": "C"}}}`,
+		t.Unix(), t.Format(time.RFC3339), value)
+}
+
+func TestMeasurementIterator_IteratesOverAllPages(t *testing.T) {
+	// given: five measurements served in pages of two
+	var measurements []string
+	for i := 0; i < 5; i++ {
+		measurements = append(measurements, temperatureAt(dateFrom.Add(time.Duration(i)*time.Minute), float64(i)))
+	}
+	requests := 0
+	ts := buildPagingHttpServer(measurements, &requests)
+	defer ts.Close()
+
+	it := NewMeasurementIterator(buildMeasurementApi(ts.URL), &MeasurementQuery{SourceId: deviceId}, 2)
+
+	// when
+	var values []float64
+	for it.Next() {
+		valueFragment, _ := it.Measurement().ValueFragment("c8y_TemperatureMeasurement", "T")
+		values = append(values, valueFragment.Value)
+	}
+
+	// then
+	if it.Err() != nil {
+		t.Fatalf("Err() = %v, want nil", it.Err())
+	}
+	if fmt.Sprint(values) != "[0 1 2 3 4]" {
+		t.Errorf("Iterated values = %v, want [0 1 2 3 4]", values)
+	}
+	if requests != 3 {
+		t.Errorf("Requests = %d, want 3", requests)
+	}
+	if it.Next() {
+		t.Errorf("Next() after end = true, want false")
+	}
+}
+
+func TestMeasurementIterator_FullLastPage(t *testing.T) {
+	// given: four measurements served in pages of two
+	var measurements []string
+	for i := 0; i < 4; i++ {
+		measurements = append(measurements, temperatureAt(dateFrom.Add(time.Duration(i)*time.Minute), float64(i)))
+	}
+	requests := 0
+	ts := buildPagingHttpServer(measurements, &requests)
+	defer ts.Close()
+
+	it := NewMeasurementIterator(buildMeasurementApi(ts.URL), &MeasurementQuery{}, 2)
+
+	// when
+	count := 0
+	for it.Next() {
+		count++
+	}
+
+	// then
+	if count != 4 || it.Err() != nil {
+		t.Errorf("Iterated %d measurements with error %v, want 4 without error", count, it.Err())
+	}
+}
+
+func TestMeasurementIterator_Error(t *testing.T) {
+	// given: a failing server
+	ts := buildHttpServer(500, `{"error": "undefined/validationError", "message": "broken"}`)
+	defer ts.Close()
+
+	it := NewMeasurementIterator(buildMeasurementApi(ts.URL), &MeasurementQuery{}, 2)
+
+	// when
+	next := it.Next()
+
+	// then
+	if next {
+		t.Errorf("Next() = true, want false")
+	}
+	if it.Err() == nil || it.Err().Message != "broken" {
+		t.Errorf("Err() = %v, want server error", it.Err())
+	}
+	if it.Measurement() != nil {
+		t.Errorf("Measurement() = %v, want nil", it.Measurement())
+	}
+}
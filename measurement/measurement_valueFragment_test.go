@@ -0,0 +1,94 @@
+package measurement
+
+import (
+	"github.com/tarent/gomulocity/generic"
+	"reflect"
+	"testing"
+)
+
+var temperatureMeasurement = `{
+	"id": "2222222",
+	"type": "c8y_TemperatureMeasurement",
+	"time": "2020-06-30T08:32:04.261Z",
+	"source": {
+		"id": "1111111"
+	},
+	"c8y_TemperatureMeasurement": {
+		"T": {"value": 23.45, "unit": "C"}
+	},
+	"c8y_Battery": {
+		"level": {"value": 87, "unit": "%"},
+		"status": "charging"
+	},
+	"custom": "no value fragment"
+}`
+
+func TestMeasurement_ValueFragment(t *testing.T) {
+	// given: a measurement parsed from JSON
+	var m Measurement
+	if err := generic.ObjectFromJson([]byte(temperatureMeasurement), &m); err != nil {
+		t.Fatalf("Unexpected error while parsing measurement: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		fragment string
+		series   string
+		expected *ValueFragment
+	}{
+		{"Existing series", "c8y_TemperatureMeasurement", "T", &ValueFragment{Value: 23.45, Unit: "C"}},
+		{"Series without unit", "c8y_Battery", "level", &ValueFragment{Value: 87, Unit: "%"}},
+		{"Non numeric series", "c8y_Battery", "status", nil},
+		{"Unknown series", "c8y_TemperatureMeasurement", "X", nil},
+		{"Unknown fragment", "c8y_Unknown", "T", nil},
+		{"Fragment is no map", "custom", "T", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueFragment, ok := m.ValueFragment(tt.fragment, tt.series)
+
+			if tt.expected == nil {
+				if ok {
+					t.Errorf("ValueFragment() = %v, want none", valueFragment)
+				}
+				return
+			}
+			if !ok || !reflect.DeepEqual(valueFragment, tt.expected) {
+				t.Errorf("ValueFragment() = %v, want %v", valueFragment, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMeasurement_ValueFragment_WithStructMetrics(t *testing.T) {
+	m := Measurement{
+		Metrics: map[string]interface{}{
+			"c8y_TemperatureMeasurement": map[string]ValueFragment{
+				"T": {Value: 21, Unit: "C"},
+			},
+		},
+	}
+
+	valueFragment, ok := m.ValueFragment("c8y_TemperatureMeasurement", "T")
+
+	if !ok || valueFragment.Value != 21 || valueFragment.Unit != "C" {
+		t.Errorf("ValueFragment() = %v, want {21 C}", valueFragment)
+	}
+}
+
+func TestMeasurement_ValueFragments(t *testing.T) {
+	var m Measurement
+	if err := generic.ObjectFromJson([]byte(temperatureMeasurement), &m); err != nil {
+		t.Fatalf("Unexpected error while parsing measurement: %s", err)
+	}
+
+	expected := map[string]map[string]ValueFragment{
+		"c8y_TemperatureMeasurement": {"T": {Value: 23.45, Unit: "C"}},
+		"c8y_Battery":                {"level": {Value: 87, Unit: "%"}},
+	}
+
+	if valueFragments := m.ValueFragments(); !reflect.DeepEqual(valueFragments, expected) {
+		t.Errorf("ValueFragments() = %v, want %v", valueFragments, expected)
+	}
+}
@@ -0,0 +1,130 @@
+package measurement
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+)
+
+// The unit a series is normalised to.
+type UnitTarget struct {
+	Fragment string
+	Series   string
+	Unit     string
+}
+
+type normalizingMeasurementApi struct {
+	MeasurementApi
+	registry *UnitRegistry
+	targets  []UnitTarget
+}
+
+// Wraps a measurement api, so that the values of the given series are converted into their target unit
+// before they are created and after they are read. Like for a single measurement, reading a page with
+// a series, which can not be converted, returns an error. The page is returned along with the error,
+// its other series are converted and the failing series keep their original unit.
+// api - The measurement api to wrap.
+// registry - The known units, e.g. NewDefaultUnitRegistry().
+// targets - The series to normalise and their target units.
+// returns - The normalising `measurement`-api object
+func NewNormalizingMeasurementApi(api MeasurementApi, registry *UnitRegistry, targets ...UnitTarget) MeasurementApi {
+	return &normalizingMeasurementApi{api, registry, targets}
+}
+
+func (n *normalizingMeasurementApi) Create(measurement *NewMeasurement) (*Measurement, *generic.Error) {
+	normalized, err := n.normalizeNew(measurement)
+	if err != nil {
+		return nil, generic.ClientError(err.Error(), "CreateMeasurement")
+	}
+
+	created, genErr := n.MeasurementApi.Create(normalized)
+	if genErr != nil || created == nil {
+		return created, genErr
+	}
+	return created, n.normalize(created, "CreateMeasurement")
+}
+
+func (n *normalizingMeasurementApi) CreateMany(measurements *NewMeasurements) (*MeasurementCollection, *generic.Error) {
+	normalized := &NewMeasurements{Measurements: make([]NewMeasurement, len(measurements.Measurements))}
+	for i := range measurements.Measurements {
+		m, err := n.normalizeNew(&measurements.Measurements[i])
+		if err != nil {
+			return nil, generic.ClientError(err.Error(), "CreateManyMeasurement")
+		}
+		normalized.Measurements[i] = *m
+	}
+
+	collection, genErr := n.MeasurementApi.CreateMany(normalized)
+	return n.normalizeCollection(collection, genErr, "CreateManyMeasurement")
+}
+
+func (n *normalizingMeasurementApi) Get(measurementId string) (*Measurement, *generic.Error) {
+	measurement, genErr := n.MeasurementApi.Get(measurementId)
+	if genErr != nil || measurement == nil {
+		return measurement, genErr
+	}
+	return measurement, n.normalize(measurement, "GetMeasurement")
+}
+
+func (n *normalizingMeasurementApi) GetForDevice(sourceId string, pageSize int) (*MeasurementCollection, *generic.Error) {
+	collection, genErr := n.MeasurementApi.GetForDevice(sourceId, pageSize)
+	return n.normalizeCollection(collection, genErr, "GetForDevice")
+}
+
+func (n *normalizingMeasurementApi) Find(measurementQuery *MeasurementQuery, pageSize int) (*MeasurementCollection, *generic.Error) {
+	collection, genErr := n.MeasurementApi.Find(measurementQuery, pageSize)
+	return n.normalizeCollection(collection, genErr, "FindMeasurements")
+}
+
+func (n *normalizingMeasurementApi) NextPage(c *MeasurementCollection) (*MeasurementCollection, *generic.Error) {
+	collection, genErr := n.MeasurementApi.NextPage(c)
+	return n.normalizeCollection(collection, genErr, "NextPage")
+}
+
+func (n *normalizingMeasurementApi) PreviousPage(c *MeasurementCollection) (*MeasurementCollection, *generic.Error) {
+	collection, genErr := n.MeasurementApi.PreviousPage(c)
+	return n.normalizeCollection(collection, genErr, "PreviousPage")
+}
+
+// -- internal
+
+// Works on a copy, the metrics of the caller stay untouched.
+func (n *normalizingMeasurementApi) normalizeNew(measurement *NewMeasurement) (*NewMeasurement, error) {
+	normalized := *measurement
+	normalized.Metrics = make(map[string]interface{}, len(measurement.Metrics))
+	for key, value := range measurement.Metrics {
+		normalized.Metrics[key] = value
+	}
+
+	for _, target := range n.targets {
+		if err := n.registry.NormalizeNewMeasurement(&normalized, target.Fragment, target.Series, target.Unit); err != nil {
+			return nil, err
+		}
+	}
+	return &normalized, nil
+}
+
+// Normalizes all targets, also after a failing one, and returns the first error.
+func (n *normalizingMeasurementApi) normalize(measurement *Measurement, info string) *generic.Error {
+	var firstErr *generic.Error
+	for _, target := range n.targets {
+		if err := n.registry.NormalizeMeasurement(measurement, target.Fragment, target.Series, target.Unit); err != nil && firstErr == nil {
+			firstErr = generic.ClientError(fmt.Sprintf("Error while normalizing measurement [%s]: %s", measurement.Id, err.Error()), info)
+		}
+	}
+	return firstErr
+}
+
+// Normalizes all measurements of the page, also after a failing one, and returns the first error.
+func (n *normalizingMeasurementApi) normalizeCollection(collection *MeasurementCollection, genErr *generic.Error, info string) (*MeasurementCollection, *generic.Error) {
+	if genErr != nil || collection == nil {
+		return collection, genErr
+	}
+
+	var firstErr *generic.Error
+	for i := range collection.Measurements {
+		if err := n.normalize(&collection.Measurements[i], info); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return collection, firstErr
+}
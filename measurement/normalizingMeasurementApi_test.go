@@ -0,0 +1,155 @@
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var fahrenheitMeasurement = `{
+	"id": "2222222",
+	"time": "2020-06-30T08:32:04.261Z",
+	"type": "c8y_TemperatureMeasurement",
+	"source": {"id": "1111111"},
+	"c8y_TemperatureMeasurement": {"T": {"value": 212, "unit": "F"}}
+}`
+
+var barMeasurement = `{
+	"id": "3333333",
+	"time": "2020-06-30T08:32:04.261Z",
+	"type": "c8y_TemperatureMeasurement",
+	"source": {"id": "1111111"},
+	"c8y_TemperatureMeasurement": {"T": {"value": 1, "unit": "bar"}}
+}`
+
+func buildNormalizingMeasurementApi(url string) MeasurementApi {
+	return NewNormalizingMeasurementApi(buildMeasurementApi(url), NewDefaultUnitRegistry(),
+		UnitTarget{Fragment: "c8y_TemperatureMeasurement", Series: "T", Unit: "C"})
+}
+
+func assertCelsius(t *testing.T, m *Measurement, expected float64) {
+	valueFragment, ok := m.ValueFragment("c8y_TemperatureMeasurement", "T")
+	if !ok || valueFragment.Unit != "C" || fmt.Sprintf("%.2f", valueFragment.Value) != fmt.Sprintf("%.2f", expected) {
+		t.Errorf("Normalized value = %v, want {%.2f C}", valueFragment, expected)
+	}
+}
+
+func TestNormalizingMeasurementApi_Create(t *testing.T) {
+	// given: a server capturing the request body and responding with the normalized measurement
+	var capturedBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedBody, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(strings.Replace(strings.Replace(fahrenheitMeasurement, "212", "100", 1), `"F"`, `"C"`, 1)))
+	}))
+	defer ts.Close()
+
+	api := buildNormalizingMeasurementApi(ts.URL)
+	metrics := map[string]interface{}{
+		"c8y_TemperatureMeasurement": map[string]ValueFragment{"T": {Value: 212, Unit: "F"}},
+	}
+
+	// when
+	created, err := api.Create(&NewMeasurement{Time: &measurementTime, MeasurementType: "c8y_TemperatureMeasurement", Source: Source{Id: deviceId}, Metrics: metrics})
+
+	// then
+	if err != nil {
+		t.Fatalf("Create() got an unexpected error: %s", err)
+	}
+
+	var sent map[string]interface{}
+	_ = json.Unmarshal(capturedBody, &sent)
+	sentSeries := sent["c8y_TemperatureMeasurement"].(map[string]interface{})["T"].(map[string]interface{})
+	if fmt.Sprintf("%.2f", sentSeries["value"]) != "100.00" || sentSeries["unit"] != "C" {
+		t.Errorf("Create() sent %v, want {100 C}", sentSeries)
+	}
+	if metrics["c8y_TemperatureMeasurement"].(map[string]ValueFragment)["T"].Unit != "F" {
+		t.Errorf("Create() changed the metrics of the caller: %v", metrics)
+	}
+	assertCelsius(t, created, 100)
+}
+
+func TestNormalizingMeasurementApi_Create_Incompatible(t *testing.T) {
+	api := buildNormalizingMeasurementApi("http://localhost")
+
+	_, err := api.Create(&NewMeasurement{Metrics: map[string]interface{}{
+		"c8y_TemperatureMeasurement": map[string]ValueFragment{"T": {Value: 1, Unit: "bar"}},
+	}})
+
+	if err == nil || !strings.Contains(err.Message, IncompatibleUnitsErr.Error()) {
+		t.Errorf("Create() error = %v, want incompatible units", err)
+	}
+}
+
+func TestNormalizingMeasurementApi_CreateMany(t *testing.T) {
+	var captured NewMeasurements
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = generic.ObjectFromJson(body, &captured)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(fmt.Sprintf(measurementCollectionTemplate, "")))
+	}))
+	defer ts.Close()
+
+	_, err := buildNormalizingMeasurementApi(ts.URL).CreateMany(&NewMeasurements{Measurements: []NewMeasurement{
+		{Time: &measurementTime, Metrics: map[string]interface{}{"c8y_TemperatureMeasurement": map[string]ValueFragment{"T": {Value: 300, Unit: "K"}}}},
+	}})
+
+	if err != nil {
+		t.Fatalf("CreateMany() got an unexpected error: %s", err)
+	}
+	series := captured.Measurements[0].Metrics["c8y_TemperatureMeasurement"].(map[string]interface{})["T"].(map[string]interface{})
+	if fmt.Sprintf("%.2f", series["value"]) != "26.85" || series["unit"] != "C" {
+		t.Errorf("CreateMany() sent %v, want {26.85 C}", series)
+	}
+}
+
+func TestNormalizingMeasurementApi_Get(t *testing.T) {
+	ts := buildHttpServer(200, fahrenheitMeasurement)
+	defer ts.Close()
+
+	m, err := buildNormalizingMeasurementApi(ts.URL).Get(measurementId)
+
+	if err != nil {
+		t.Fatalf("Get() got an unexpected error: %s", err)
+	}
+	assertCelsius(t, m, 100)
+}
+
+func TestNormalizingMeasurementApi_Find(t *testing.T) {
+	ts := buildHttpServer(200, fmt.Sprintf(measurementCollectionTemplate, fahrenheitMeasurement+","+measurement))
+	defer ts.Close()
+
+	collection, err := buildNormalizingMeasurementApi(ts.URL).Find(&MeasurementQuery{}, 5)
+
+	if err != nil {
+		t.Fatalf("Find() got an unexpected error: %s", err)
+	}
+	assertCelsius(t, &collection.Measurements[0], 100)
+	if collection.Measurements[1].Id != measurementId {
+		t.Errorf("Find() did not return the measurement without the series")
+	}
+}
+
+func TestNormalizingMeasurementApi_Find_Incompatible(t *testing.T) {
+	// given: a page with an incompatible and a convertible measurement
+	ts := buildHttpServer(200, fmt.Sprintf(measurementCollectionTemplate, barMeasurement+","+fahrenheitMeasurement))
+	defer ts.Close()
+
+	// when
+	collection, err := buildNormalizingMeasurementApi(ts.URL).Find(&MeasurementQuery{}, 5)
+
+	// then: the incompatible series is reported and kept, the other one is converted
+	if err == nil || !strings.Contains(err.Message, "Error while normalizing measurement") {
+		t.Fatalf("Find() error = %v, want the incompatible series", err)
+	}
+	if valueFragment, _ := collection.Measurements[0].ValueFragment("c8y_TemperatureMeasurement", "T"); valueFragment.Value != 1 || valueFragment.Unit != "bar" {
+		t.Errorf("Find() changed the incompatible series: %v", valueFragment)
+	}
+	assertCelsius(t, &collection.Measurements[1], 100)
+}
@@ -0,0 +1,204 @@
+package measurement
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var UnknownUnitErr = errors.New("unknown unit")
+var IncompatibleUnitsErr = errors.New("incompatible units")
+
+type Dimension string
+
+const (
+	TEMPERATURE Dimension = "temperature"
+	PRESSURE    Dimension = "pressure"
+	LENGTH      Dimension = "length"
+	SPEED       Dimension = "speed"
+	ENERGY      Dimension = "energy"
+	POWER       Dimension = "power"
+	VOLTAGE     Dimension = "voltage"
+	CURRENT     Dimension = "current"
+	RATIO       Dimension = "ratio"
+)
+
+/*
+Unit describes how to convert a value from and to the base unit of its dimension:
+
+	base = value * Scale + Offset
+*/
+type Unit struct {
+	Symbol    string
+	Dimension Dimension
+	Scale     float64
+	Offset    float64
+}
+
+func (u Unit) toBase(value float64) float64 {
+	return value*u.Scale + u.Offset
+}
+
+func (u Unit) fromBase(value float64) float64 {
+	return (value - u.Offset) / u.Scale
+}
+
+// UnitRegistry resolves unit symbols as found in the free-text `unit` of a ValueFragment.
+// It is safe for concurrent use.
+type UnitRegistry struct {
+	mutex sync.RWMutex
+	units map[string]Unit
+}
+
+// Creates a registry containing the given units.
+func NewUnitRegistry(units ...Unit) *UnitRegistry {
+	registry := &UnitRegistry{units: map[string]Unit{}}
+	for _, unit := range units {
+		registry.Register(unit)
+	}
+	return registry
+}
+
+// Creates a registry with common units of temperature, pressure, length, speed, energy, power, voltage, current and ratio.
+// Base units are K, Pa, m, m/s, J, W, V, A and 1.
+func NewDefaultUnitRegistry() *UnitRegistry {
+	return NewUnitRegistry(
+		Unit{"K", TEMPERATURE, 1, 0},
+		Unit{"C", TEMPERATURE, 1, 273.15},
+		Unit{"°C", TEMPERATURE, 1, 273.15},
+		Unit{"degC", TEMPERATURE, 1, 273.15},
+		Unit{"F", TEMPERATURE, 5.0 / 9.0, 459.67 * 5.0 / 9.0},
+		Unit{"°F", TEMPERATURE, 5.0 / 9.0, 459.67 * 5.0 / 9.0},
+		Unit{"degF", TEMPERATURE, 5.0 / 9.0, 459.67 * 5.0 / 9.0},
+
+		Unit{"Pa", PRESSURE, 1, 0},
+		Unit{"hPa", PRESSURE, 100, 0},
+		Unit{"kPa", PRESSURE, 1000, 0},
+		Unit{"MPa", PRESSURE, 1e6, 0},
+		Unit{"mbar", PRESSURE, 100, 0},
+		Unit{"bar", PRESSURE, 1e5, 0},
+		Unit{"psi", PRESSURE, 6894.757293168, 0},
+		Unit{"atm", PRESSURE, 101325, 0},
+
+		Unit{"mm", LENGTH, 0.001, 0},
+		Unit{"cm", LENGTH, 0.01, 0},
+		Unit{"m", LENGTH, 1, 0},
+		Unit{"km", LENGTH, 1000, 0},
+		Unit{"in", LENGTH, 0.0254, 0},
+		Unit{"ft", LENGTH, 0.3048, 0},
+		Unit{"mi", LENGTH, 1609.344, 0},
+
+		Unit{"m/s", SPEED, 1, 0},
+		Unit{"km/h", SPEED, 1 / 3.6, 0},
+		Unit{"mph", SPEED, 0.44704, 0},
+		Unit{"kn", SPEED, 1852.0 / 3600.0, 0},
+
+		Unit{"J", ENERGY, 1, 0},
+		Unit{"kJ", ENERGY, 1000, 0},
+		Unit{"Wh", ENERGY, 3600, 0},
+		Unit{"kWh", ENERGY, 3.6e6, 0},
+		Unit{"MWh", ENERGY, 3.6e9, 0},
+
+		Unit{"mW", POWER, 0.001, 0},
+		Unit{"W", POWER, 1, 0},
+		Unit{"kW", POWER, 1000, 0},
+		Unit{"MW", POWER, 1e6, 0},
+
+		Unit{"mV", VOLTAGE, 0.001, 0},
+		Unit{"V", VOLTAGE, 1, 0},
+		Unit{"kV", VOLTAGE, 1000, 0},
+
+		Unit{"mA", CURRENT, 0.001, 0},
+		Unit{"A", CURRENT, 1, 0},
+
+		Unit{"%", RATIO, 0.01, 0},
+		Unit{"‰", RATIO, 0.001, 0},
+		Unit{"ppm", RATIO, 1e-6, 0},
+	)
+}
+
+// Adds a unit or replaces the unit with the same symbol.
+func (r *UnitRegistry) Register(unit Unit) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.units[unit.Symbol] = unit
+}
+
+// Returns the unit with the given symbol. Symbols are case sensitive, e.g. "mW" and "MW" differ.
+func (r *UnitRegistry) Lookup(symbol string) (Unit, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	unit, ok := r.units[symbol]
+	return unit, ok
+}
+
+// Converts a value from one unit into another one.
+// Returns an error wrapping UnknownUnitErr or IncompatibleUnitsErr if the conversion is not possible.
+func (r *UnitRegistry) Convert(value float64, from string, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+
+	fromUnit, ok := r.Lookup(from)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", UnknownUnitErr, from)
+	}
+	toUnit, ok := r.Lookup(to)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", UnknownUnitErr, to)
+	}
+	if fromUnit.Dimension != toUnit.Dimension {
+		return 0, fmt.Errorf("%w: can not convert %s (%s) into %s (%s)", IncompatibleUnitsErr, from, fromUnit.Dimension, to, toUnit.Dimension)
+	}
+
+	return toUnit.fromBase(fromUnit.toBase(value)), nil
+}
+
+// Converts the value of the given series into the target unit.
+// Measurements without this series are left untouched.
+func (r *UnitRegistry) NormalizeMeasurement(m *Measurement, fragment string, series string, targetUnit string) error {
+	return r.normalizeMetrics(m.Metrics, fragment, series, targetUnit)
+}
+
+// Converts the value of the given series into the target unit.
+// New measurements without this series are left untouched.
+func (r *UnitRegistry) NormalizeNewMeasurement(m *NewMeasurement, fragment string, series string, targetUnit string) error {
+	return r.normalizeMetrics(m.Metrics, fragment, series, targetUnit)
+}
+
+// -- internal
+
+func (r *UnitRegistry) normalizeMetrics(metrics map[string]interface{}, fragment string, series string, targetUnit string) error {
+	seriesMap, ok := toMap(metrics[fragment])
+	if !ok {
+		return nil
+	}
+	valueFragment, ok := toValueFragment(seriesMap[series])
+	if !ok {
+		return nil
+	}
+
+	value, err := r.Convert(valueFragment.Value, valueFragment.Unit, targetUnit)
+	if err != nil {
+		return fmt.Errorf("failed to normalize %s.%s: %w", fragment, series, err)
+	}
+
+	// Copy the fragment and the series, they might be shared with the caller.
+	// Further fields of the series, besides value and unit, are kept.
+	seriesFields, _ := toMap(seriesMap[series])
+	normalizedSeries := make(map[string]interface{}, len(seriesFields))
+	for key, value := range seriesFields {
+		normalizedSeries[key] = value
+	}
+	normalizedSeries["value"] = value
+	normalizedSeries["unit"] = targetUnit
+
+	normalized := make(map[string]interface{}, len(seriesMap))
+	for key, value := range seriesMap {
+		normalized[key] = value
+	}
+	normalized[series] = normalizedSeries
+	metrics[fragment] = normalized
+
+	return nil
+}
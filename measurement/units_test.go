@@ -0,0 +1,131 @@
+package measurement
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUnitRegistry_Convert(t *testing.T) {
+	registry := NewDefaultUnitRegistry()
+
+	tests := []struct {
+		name     string
+		value    float64
+		from     string
+		to       string
+		expected float64
+	}{
+		{"Same unit", 42, "C", "C", 42},
+		{"Celsius to Fahrenheit", 100, "C", "F", 212},
+		{"Fahrenheit to Celsius", 32, "°F", "°C", 0},
+		{"Celsius to Kelvin", -273.15, "C", "K", 0},
+		{"Bar to Pascal", 1.5, "bar", "Pa", 150000},
+		{"Pascal to hectopascal", 101325, "Pa", "hPa", 1013.25},
+		{"psi to bar", 14.503773773, "psi", "bar", 1},
+		{"kWh to Wh", 1.2, "kWh", "Wh", 1200},
+		{"km/h to m/s", 36, "km/h", "m/s", 10},
+		{"Percent to ratio", 50, "%", "ppm", 500000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := registry.Convert(tt.value, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Convert() got an unexpected error: %s", err)
+			}
+			if math.Abs(value-tt.expected) > 1e-6 {
+				t.Errorf("Convert(%v, %s, %s) = %v, want %v", tt.value, tt.from, tt.to, value, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnitRegistry_Convert_Errors(t *testing.T) {
+	registry := NewDefaultUnitRegistry()
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected error
+	}{
+		{"Incompatible", "C", "bar", IncompatibleUnitsErr},
+		{"Unknown source unit", "furlong", "m", UnknownUnitErr},
+		{"Unknown target unit", "m", "furlong", UnknownUnitErr},
+		{"Empty unit", "", "C", UnknownUnitErr},
+		{"Case sensitive", "mw", "W", UnknownUnitErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.Convert(1, tt.from, tt.to)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Convert() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnitRegistry_Register(t *testing.T) {
+	registry := NewUnitRegistry(Unit{"m", LENGTH, 1, 0})
+	registry.Register(Unit{"furlong", LENGTH, 201.168, 0})
+
+	value, err := registry.Convert(2, "furlong", "m")
+
+	if err != nil || value != 402.336 {
+		t.Errorf("Convert() = %v, %v, want 402.336", value, err)
+	}
+}
+
+func TestUnitRegistry_NormalizeNewMeasurement(t *testing.T) {
+	// given: a measurement in Fahrenheit with a further series
+	fragment := map[string]interface{}{
+		"T":   map[string]interface{}{"value": 212.0, "unit": "F", "quality": "good"},
+		"Max": map[string]interface{}{"value": 220.0, "unit": "F"},
+	}
+	m := &NewMeasurement{Metrics: map[string]interface{}{"c8y_TemperatureMeasurement": fragment}}
+
+	// when
+	err := NewDefaultUnitRegistry().NormalizeNewMeasurement(m, "c8y_TemperatureMeasurement", "T", "C")
+
+	// then
+	if err != nil {
+		t.Fatalf("NormalizeNewMeasurement() got an unexpected error: %s", err)
+	}
+
+	normalized := m.Metrics["c8y_TemperatureMeasurement"].(map[string]interface{})
+	series := normalized["T"].(map[string]interface{})
+	if math.Abs(series["value"].(float64)-100) > 1e-9 || series["unit"] != "C" {
+		t.Errorf("Normalized series = %v, want {100 C}", series)
+	}
+	if series["quality"] != "good" {
+		t.Errorf("Normalized series lost further fields: %v", series)
+	}
+	if normalized["Max"].(map[string]interface{})["unit"] != "F" {
+		t.Errorf("Other series was changed: %v", normalized["Max"])
+	}
+	if fragment["T"].(map[string]interface{})["unit"] != "F" {
+		t.Errorf("The given fragment was changed: %v", fragment)
+	}
+}
+
+func TestUnitRegistry_NormalizeMeasurement_WithoutSeries(t *testing.T) {
+	m := &Measurement{Metrics: map[string]interface{}{"c8y_Battery": map[string]interface{}{"level": map[string]interface{}{"value": 50.0, "unit": "%"}}}}
+
+	err := NewDefaultUnitRegistry().NormalizeMeasurement(m, "c8y_TemperatureMeasurement", "T", "C")
+
+	if err != nil {
+		t.Errorf("NormalizeMeasurement() got an unexpected error: %s", err)
+	}
+}
+
+func TestUnitRegistry_NormalizeMeasurement_Incompatible(t *testing.T) {
+	m := &Measurement{Metrics: map[string]interface{}{"c8y_TemperatureMeasurement": map[string]interface{}{"T": map[string]interface{}{"value": 1.0, "unit": "bar"}}}}
+
+	err := NewDefaultUnitRegistry().NormalizeMeasurement(m, "c8y_TemperatureMeasurement", "T", "C")
+
+	if !errors.Is(err, IncompatibleUnitsErr) {
+		t.Errorf("NormalizeMeasurement() error = %v, want %v", err, IncompatibleUnitsErr)
+	}
+}
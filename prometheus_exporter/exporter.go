@@ -0,0 +1,388 @@
+package prometheus_exporter
+
+import (
+	"context"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/inventory"
+	"github.com/tarent/gomulocity/measurement"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_INTERVAL  = time.Minute
+	DEFAULT_LOOKBACK  = time.Hour
+	DEFAULT_NAMESPACE = "c8y"
+
+	METRICS_PATH         = "/metrics"
+	METRICS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+	inventoryPageSize = 2000
+)
+
+// Series identifies a measurement series by its value fragment and series name,
+// e.g. "c8y_TemperatureMeasurement" and "T".
+type Series struct {
+	Fragment string
+	Series   string
+}
+
+func (s Series) String() string {
+	return s.Fragment + "." + s.Series
+}
+
+type Config struct {
+	DeviceIds      []string      // Ids of the devices (managed objects) to export measurements for.
+	InventoryQuery string        // Optional inventory query selecting further devices. See inventory.InventoryApi.FindByQuery
+	Series         []Series      // Series to export. When empty, all series supported by a device are exported.
+	Interval       time.Duration // Time between two collections. Defaults to DEFAULT_INTERVAL.
+	Lookback       time.Duration // Measurements older than this are not exported. Defaults to DEFAULT_LOOKBACK.
+	Namespace      string        // Prefix of all metric names. Defaults to DEFAULT_NAMESPACE.
+}
+
+/*
+Exporter periodically reads the latest measurements of a set of devices and exposes them as
+Prometheus gauges in the text exposition format.
+
+Each series becomes a metric named `<namespace>_<fragment>_<series>` with the labels
+`source_id`, `device_name` and `unit`. Characters, which are not allowed in metric names, are
+replaced by `_`. When two series get the same metric name this way, ex. `a.b` and `a_b`,
+only the first one is exported and the other one is logged.
+See: https://prometheus.io/docs/instrumenting/exposition_formats/
+*/
+type Exporter struct {
+	measurementApi measurement.MeasurementApi
+	inventoryApi   inventory.InventoryApi
+	config         Config
+	now            func() time.Time
+
+	mutex   sync.RWMutex
+	samples []sample
+	errors  int
+}
+
+type device struct {
+	id   string
+	name string
+}
+
+type sample struct {
+	metric string
+	labels map[string]string
+	value  float64
+}
+
+// Creates a new exporter.
+// measurementApi - used to read the latest measurements.
+// inventoryApi - used to resolve device names and the inventory query.
+// config - the devices and series to export.
+func NewExporter(measurementApi measurement.MeasurementApi, inventoryApi inventory.InventoryApi, config Config) *Exporter {
+	if config.Interval <= 0 {
+		config.Interval = DEFAULT_INTERVAL
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = DEFAULT_LOOKBACK
+	}
+	if len(config.Namespace) == 0 {
+		config.Namespace = DEFAULT_NAMESPACE
+	}
+
+	return &Exporter{
+		measurementApi: measurementApi,
+		inventoryApi:   inventoryApi,
+		config:         config,
+		now:            time.Now,
+	}
+}
+
+/*
+Reads the latest measurements of all configured devices and replaces the exposed samples.
+
+Failing requests do not stop the collection. They are counted and exposed as
+`<namespace>_exporter_collection_errors`. The first error is returned.
+*/
+func (e *Exporter) Collect() error {
+	var firstErr error
+	errorCount := 0
+	handleErr := func(err error) {
+		errorCount++
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	devices := e.devices(handleErr)
+
+	now := e.now()
+	dateFrom := now.Add(-e.config.Lookback)
+	// Allow for some clock skew between the devices and us.
+	dateTo := now.Add(time.Minute)
+
+	var samples []sample
+	// metric name -> series, to detect series with the same metric name
+	metrics := map[string]Series{}
+	for _, d := range devices {
+		series := e.config.Series
+		if len(series) == 0 {
+			supported, genErr := e.supportedSeries(d)
+			if genErr != nil {
+				handleErr(genErr)
+				continue
+			}
+			series = supported
+		}
+
+		for _, s := range series {
+			metric := metricName(e.config.Namespace, s.Fragment, s.Series)
+			if other, ok := metrics[metric]; ok && other != s {
+				log.Printf("The series %s and %s are both exported as %s. Skipping %s.", other, s, metric, s)
+				continue
+			}
+			metrics[metric] = s
+
+			latest, genErr := e.latest(&measurement.MeasurementQuery{
+				SourceId:            d.id,
+				ValueFragmentType:   s.Fragment,
+				ValueFragmentSeries: s.Series,
+				DateFrom:            &dateFrom,
+				DateTo:              &dateTo,
+				Revert:              true,
+			})
+			if genErr != nil {
+				handleErr(genErr)
+				continue
+			}
+			if latest == nil {
+				continue
+			}
+			if valueFragment, ok := latest.ValueFragment(s.Fragment, s.Series); ok {
+				samples = append(samples, e.sample(d, s, *valueFragment))
+			}
+		}
+	}
+
+	e.mutex.Lock()
+	e.samples = samples
+	e.errors = errorCount
+	e.mutex.Unlock()
+
+	return firstErr
+}
+
+// Collects immediately and then every configured interval until the context is done.
+// Collection errors are logged.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Collect(); err != nil {
+			log.Printf("Error while collecting measurements: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Serves the collected samples in the Prometheus text exposition format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", METRICS_CONTENT_TYPE)
+	_, _ = w.Write([]byte(e.Render()))
+}
+
+// Starts collecting in the background and serves the samples on METRICS_PATH of the given address
+// until the context is done.
+func (e *Exporter) ListenAndServe(ctx context.Context, address string) error {
+	mux := http.NewServeMux()
+	mux.Handle(METRICS_PATH, e)
+	server := &http.Server{Addr: address, Handler: mux}
+
+	go e.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Renders the collected samples in the Prometheus text exposition format.
+func (e *Exporter) Render() string {
+	e.mutex.RLock()
+	samples := make([]sample, len(e.samples))
+	copy(samples, e.samples)
+	errorCount := e.errors
+	e.mutex.RUnlock()
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		if samples[i].metric != samples[j].metric {
+			return samples[i].metric < samples[j].metric
+		}
+		return formatLabels(samples[i].labels) < formatLabels(samples[j].labels)
+	})
+
+	var builder strings.Builder
+	lastMetric := ""
+	for _, s := range samples {
+		if s.metric != lastMetric {
+			_, _ = fmt.Fprintf(&builder, "# HELP %s Latest value of the Cumulocity measurement series.\n", s.metric)
+			_, _ = fmt.Fprintf(&builder, "# TYPE %s gauge\n", s.metric)
+			lastMetric = s.metric
+		}
+		_, _ = fmt.Fprintf(&builder, "%s{%s} %v\n", s.metric, formatLabels(s.labels), s.value)
+	}
+
+	errorMetric := metricName(e.config.Namespace, "exporter", "collection_errors")
+	_, _ = fmt.Fprintf(&builder, "# HELP %s Number of failed requests during the last collection.\n", errorMetric)
+	_, _ = fmt.Fprintf(&builder, "# TYPE %s gauge\n", errorMetric)
+	_, _ = fmt.Fprintf(&builder, "%s %d\n", errorMetric, errorCount)
+
+	return builder.String()
+}
+
+// -- internal
+
+func (e *Exporter) sample(d device, s Series, valueFragment measurement.ValueFragment) sample {
+	return sample{
+		metric: metricName(e.config.Namespace, s.Fragment, s.Series),
+		labels: map[string]string{
+			"source_id":   d.id,
+			"device_name": d.name,
+			"unit":        valueFragment.Unit,
+		},
+		value: valueFragment.Value,
+	}
+}
+
+// Returns the series of the device. Entries, which are no "<fragment>.<series>", are skipped.
+func (e *Exporter) supportedSeries(d device) ([]Series, *generic.Error) {
+	supported, err := e.inventoryApi.SupportedSeries(d.id)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]Series, 0, len(supported))
+	for _, name := range supported {
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			log.Printf("Unexpected series %q of device %s. Skipping it.", name, d.id)
+			continue
+		}
+		series = append(series, Series{parts[0], parts[1]})
+	}
+	return series, nil
+}
+
+func (e *Exporter) latest(query *measurement.MeasurementQuery) (*measurement.Measurement, *generic.Error) {
+	collection, err := e.measurementApi.Find(query, 1)
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil || len(collection.Measurements) == 0 {
+		return nil, nil
+	}
+	return &collection.Measurements[0], nil
+}
+
+// Returns the configured devices. Failing requests are passed to handleErr and skipped.
+func (e *Exporter) devices(handleErr func(error)) []device {
+	var devices []device
+	known := map[string]bool{}
+
+	for _, id := range e.config.DeviceIds {
+		if known[id] {
+			continue
+		}
+		known[id] = true
+
+		managedObject, err := e.inventoryApi.Get(id)
+		if err != nil {
+			handleErr(err)
+			continue
+		}
+		if managedObject == nil {
+			log.Printf("Device %s does not exist. Skipping it.", id)
+			continue
+		}
+		devices = append(devices, device{managedObject.Id, managedObject.Name})
+	}
+
+	if len(e.config.InventoryQuery) == 0 {
+		return devices
+	}
+
+	collection, err := e.inventoryApi.FindByQuery(e.config.InventoryQuery, inventoryPageSize)
+	for ; collection != nil; collection, err = e.inventoryApi.NextPage(collection) {
+		for _, managedObject := range collection.ManagedObjects {
+			if known[managedObject.Id] {
+				continue
+			}
+			known[managedObject.Id] = true
+			devices = append(devices, device{managedObject.Id, managedObject.Name})
+		}
+		if len(collection.ManagedObjects) < inventoryPageSize {
+			break
+		}
+	}
+	if err != nil {
+		handleErr(err)
+	}
+
+	return devices
+}
+
+// Builds a valid metric name matching [a-zA-Z_:][a-zA-Z0-9_:]*
+func metricName(parts ...string) string {
+	var builder strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			builder.WriteRune('_')
+		}
+		for _, r := range part {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+				builder.WriteRune(r)
+			} else {
+				builder.WriteRune('_')
+			}
+		}
+	}
+
+	name := builder.String()
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatted := make([]string, len(names))
+	for i, name := range names {
+		formatted[i] = fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(labels[name]))
+	}
+	return strings.Join(formatted, ",")
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
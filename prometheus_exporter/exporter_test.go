@@ -0,0 +1,301 @@
+package prometheus_exporter
+
+import (
+	"context"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/inventory"
+	"github.com/tarent/gomulocity/measurement"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now, _ = time.Parse(time.RFC3339, "2020-07-01T12:00:00Z")
+
+var managedObjectTemplate = `{"id": "%s", "name": "%s", "self": "https://t0815.cumulocity.com/inventory/managedObjects/%s"}`
+
+var managedObjectCollectionTemplate = `{
+	"self": "https://t0815.cumulocity.com/inventory/managedObjects?pageSize=2000&currentPage=1",
+	"managedObjects": [%s],
+	"statistics": {"currentPage": 1, "pageSize": 2000}
+}`
+
+var measurementCollectionTemplate = `{
+	"self": "https://t0815.cumulocity.com/measurement/measurements?pageSize=1&currentPage=1",
+	"measurements": [%s],
+	"statistics": {"currentPage": 1, "pageSize": 1}
+}`
+
+var temperatureMeasurement = `{
+	"id": "1", "type": "c8y_TemperatureMeasurement", "time": "2020-07-01T11:59:00Z", "source": {"id": "4711"},
+	"c8y_TemperatureMeasurement": {"T": {"value": 21.5, "unit": "C"}}
+}`
+
+var energyMeasurement = `{
+	"id": "2", "type": "c8y_EnergyMeasurement", "time": "2020-07-01T11:59:00Z", "source": {"id": "4712"},
+	"c8y_EnergyMeasurement": {"E": {"value": 1200, "unit": "kWh"}, "P": {"value": 3.5, "unit": "kW"}}
+}`
+
+type capturedRequests struct {
+	measurementQueries []string
+}
+
+func buildTestServer(captured *capturedRequests) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/inventory/managedObjects/4711/supportedSeries":
+			_, _ = w.Write([]byte(`{"c8y_SupportedSeries": ["c8y_TemperatureMeasurement.T"]}`))
+		case r.URL.Path == "/inventory/managedObjects/4712/supportedSeries":
+			_, _ = w.Write([]byte(`{"c8y_SupportedSeries": ["c8y_EnergyMeasurement.E", "c8y_EnergyMeasurement.P", "invalid"]}`))
+		case r.URL.Path == "/inventory/managedObjects/4711":
+			_, _ = fmt.Fprintf(w, managedObjectTemplate, "4711", "Kitchen", "4711")
+		case r.URL.Path == "/inventory/managedObjects/9999":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/inventory/managedObjects":
+			_, _ = fmt.Fprintf(w, managedObjectCollectionTemplate,
+				fmt.Sprintf(managedObjectTemplate, "4711", "Kitchen", "4711")+","+
+					fmt.Sprintf(managedObjectTemplate, "4712", "Meter", "4712"))
+		case r.URL.Path == "/measurement/measurements":
+			captured.measurementQueries = append(captured.measurementQueries, r.URL.RawQuery)
+			switch r.URL.Query().Get("source") {
+			case "4711":
+				_, _ = fmt.Fprintf(w, measurementCollectionTemplate, temperatureMeasurement)
+			case "4712":
+				_, _ = fmt.Fprintf(w, measurementCollectionTemplate, energyMeasurement)
+			default:
+				_, _ = fmt.Fprintf(w, measurementCollectionTemplate, "")
+			}
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error": "unexpected", "message": "unexpected request"}`))
+		}
+	}))
+}
+
+func buildExporter(url string, config Config) *Exporter {
+	client := &generic.Client{
+		HTTPClient: http.DefaultClient,
+		BaseURL:    url,
+		Username:   "foo",
+		Password:   "bar",
+	}
+	exporter := NewExporter(measurement.NewMeasurementApi(client), inventory.NewInventoryApi(client), config)
+	exporter.now = func() time.Time { return now }
+	return exporter
+}
+
+func TestExporter_Collect_ConfiguredSeries(t *testing.T) {
+	// given: a test server and an exporter for one device and series
+	captured := &capturedRequests{}
+	ts := buildTestServer(captured)
+	defer ts.Close()
+
+	exporter := buildExporter(ts.URL, Config{
+		DeviceIds: []string{"4711"},
+		Series:    []Series{{"c8y_TemperatureMeasurement", "T"}},
+	})
+
+	// when
+	err := exporter.Collect()
+
+	// then
+	if err != nil {
+		t.Fatalf("Collect() got an unexpected error: %s", err)
+	}
+
+	expectedQuery := "dateFrom=2020-07-01T11%3A00%3A00Z&dateTo=2020-07-01T12%3A01%3A00Z&pageSize=1&revert=true&source=4711&valueFragmentSeries=T&valueFragmentType=c8y_TemperatureMeasurement"
+	if len(captured.measurementQueries) != 1 || captured.measurementQueries[0] != expectedQuery {
+		t.Errorf("Collect() measurement queries = %v, want [%s]", captured.measurementQueries, expectedQuery)
+	}
+
+	expected := `# HELP c8y_c8y_TemperatureMeasurement_T Latest value of the Cumulocity measurement series.
+# TYPE c8y_c8y_TemperatureMeasurement_T gauge
+c8y_c8y_TemperatureMeasurement_T{device_name="Kitchen",source_id="4711",unit="C"} 21.5
+# HELP c8y_exporter_collection_errors Number of failed requests during the last collection.
+# TYPE c8y_exporter_collection_errors gauge
+c8y_exporter_collection_errors 0
+`
+	if rendered := exporter.Render(); rendered != expected {
+		t.Errorf("Render() = \n%s\nwant\n%s", rendered, expected)
+	}
+}
+
+func TestExporter_Collect_AllSeriesOfInventoryQuery(t *testing.T) {
+	// given: a test server and an exporter for an inventory query without configured series
+	captured := &capturedRequests{}
+	ts := buildTestServer(captured)
+	defer ts.Close()
+
+	exporter := buildExporter(ts.URL, Config{
+		DeviceIds:      []string{"4711", "9999"},
+		InventoryQuery: "has(c8y_IsDevice)",
+		Namespace:      "cumulocity",
+	})
+
+	// when
+	err := exporter.Collect()
+
+	// then
+	if err != nil {
+		t.Fatalf("Collect() got an unexpected error: %s", err)
+	}
+	expectedQuery := "dateFrom=2020-07-01T11%3A00%3A00Z&dateTo=2020-07-01T12%3A01%3A00Z&pageSize=1&revert=true&source=4712&valueFragmentSeries=P&valueFragmentType=c8y_EnergyMeasurement"
+	if len(captured.measurementQueries) != 3 || captured.measurementQueries[2] != expectedQuery {
+		t.Errorf("Collect() measurement queries = %v, want one per series", captured.measurementQueries)
+	}
+
+	rendered := exporter.Render()
+	for _, line := range []string{
+		`cumulocity_c8y_EnergyMeasurement_E{device_name="Meter",source_id="4712",unit="kWh"} 1200`,
+		`cumulocity_c8y_EnergyMeasurement_P{device_name="Meter",source_id="4712",unit="kW"} 3.5`,
+		`cumulocity_c8y_TemperatureMeasurement_T{device_name="Kitchen",source_id="4711",unit="C"} 21.5`,
+		`cumulocity_exporter_collection_errors 0`,
+	} {
+		if !strings.Contains(rendered, line+"\n") {
+			t.Errorf("Render() = \n%s\nmissing line %s", rendered, line)
+		}
+	}
+}
+
+func TestExporter_Collect_CountsErrors(t *testing.T) {
+	// given: a test server and a series which leads to a server error
+	captured := &capturedRequests{}
+	ts := buildTestServer(captured)
+	defer ts.Close()
+
+	exporter := buildExporter(ts.URL, Config{
+		DeviceIds: []string{"4711", "1234"},
+		Series:    []Series{{"c8y_TemperatureMeasurement", "T"}},
+	})
+
+	// when
+	err := exporter.Collect()
+
+	// then
+	if err == nil {
+		t.Fatalf("Collect() expected an error")
+	}
+	if !strings.Contains(exporter.Render(), "c8y_exporter_collection_errors 1\n") {
+		t.Errorf("Render() = \n%s\nwant one collection error", exporter.Render())
+	}
+}
+
+func TestExporter_Collect_ContinuesAfterFailingDevice(t *testing.T) {
+	// given: a device which can not be loaded before a valid device
+	captured := &capturedRequests{}
+	ts := buildTestServer(captured)
+	defer ts.Close()
+
+	exporter := buildExporter(ts.URL, Config{
+		DeviceIds: []string{"1234", "4711"},
+		Series:    []Series{{"c8y_TemperatureMeasurement", "T"}},
+	})
+
+	// when
+	err := exporter.Collect()
+
+	// then
+	if err == nil {
+		t.Fatalf("Collect() expected an error")
+	}
+	rendered := exporter.Render()
+	for _, line := range []string{
+		`c8y_c8y_TemperatureMeasurement_T{device_name="Kitchen",source_id="4711",unit="C"} 21.5`,
+		`c8y_exporter_collection_errors 1`,
+	} {
+		if !strings.Contains(rendered, line+"\n") {
+			t.Errorf("Render() = \n%s\nmissing line %s", rendered, line)
+		}
+	}
+}
+
+func TestExporter_Collect_SkipsSeriesWithSameMetricName(t *testing.T) {
+	// given: two series with the same metric name
+	captured := &capturedRequests{}
+	ts := buildTestServer(captured)
+	defer ts.Close()
+
+	exporter := buildExporter(ts.URL, Config{
+		DeviceIds: []string{"4711"},
+		Series:    []Series{{"c8y_Power", "L1.avg"}, {"c8y_Power", "L1_avg"}, {"c8y_Power", "L1.avg"}},
+	})
+
+	// when
+	err := exporter.Collect()
+
+	// then: only the first series was queried
+	if err != nil {
+		t.Fatalf("Collect() got an unexpected error: %s", err)
+	}
+	if len(captured.measurementQueries) != 2 || !strings.Contains(captured.measurementQueries[1], "valueFragmentSeries=L1.avg") {
+		t.Errorf("Collect() measurement queries = %v, want the first series only", captured.measurementQueries)
+	}
+}
+
+func TestExporter_ServeHTTP(t *testing.T) {
+	// given: a collected exporter
+	captured := &capturedRequests{}
+	ts := buildTestServer(captured)
+	defer ts.Close()
+
+	exporter := buildExporter(ts.URL, Config{
+		DeviceIds: []string{"4711"},
+		Series:    []Series{{"c8y_TemperatureMeasurement", "T"}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exporter.Run(ctx)
+
+	metrics := httptest.NewServer(exporter)
+	defer metrics.Close()
+
+	// when
+	response, err := http.Get(metrics.URL + METRICS_PATH)
+
+	// then
+	if err != nil {
+		t.Fatalf("GET %s got an unexpected error: %s", METRICS_PATH, err)
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+
+	if response.Header.Get("Content-Type") != METRICS_CONTENT_TYPE {
+		t.Errorf("Content-Type = %s, want %s", response.Header.Get("Content-Type"), METRICS_CONTENT_TYPE)
+	}
+	if !strings.Contains(string(body), `source_id="4711"`) {
+		t.Errorf("Body = %s, want samples of device 4711", body)
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		parts    []string
+		expected string
+	}{
+		{[]string{"c8y", "c8y_Temperature", "T"}, "c8y_c8y_Temperature_T"},
+		{[]string{"c8y", "Power-Meter", "L1.avg"}, "c8y_Power_Meter_L1_avg"},
+		{[]string{"1st", "series"}, "_1st_series"},
+	}
+
+	for _, tt := range tests {
+		if name := metricName(tt.parts...); name != tt.expected {
+			t.Errorf("metricName(%v) = %s, want %s", tt.parts, name, tt.expected)
+		}
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := map[string]string{
+		"unit":        "°C",
+		"device_name": "Thermometer \"kitchen\"\nC:\\",
+	}
+
+	expected := `device_name="Thermometer \"kitchen\"\nC:\\",unit="°C"`
+	if formatted := formatLabels(labels); formatted != expected {
+		t.Errorf("formatLabels() = %s, want %s", formatted, expected)
+	}
+}
@@ -0,0 +1,189 @@
+package provisioning
+
+import (
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"sync"
+)
+
+const DEFAULT_CONCURRENCY = 8
+
+// The steps of provisioning an item in their order.
+type Step string
+
+const (
+	CREATE      Step = "create"
+	UPDATE      Step = "update"
+	EXTERNAL_ID Step = "externalId"
+	PARENT      Step = "parent"
+)
+
+/*
+Item describes a managed object to provision. Without Id a new managed object is created,
+otherwise the existing managed object is updated with Type, Name, C8y_IsDevice and the additional fields.
+*/
+type Item struct {
+	Id                  string
+	ManagedObject       inventory.NewManagedObject
+	ExternalIds         []identity.NewExternalID
+	ParentId            string                  // Adds the managed object to this parent, when set.
+	ParentReferenceType inventory.ReferenceType // Defaults to CHILD_DEVICES.
+}
+
+// The result of a single item. Error and FailedStep are set, when the provisioning failed.
+type Result struct {
+	Item               Item
+	ManagedObjectId    string // Set as soon as the managed object is created or updated
+	CreatedExternalIds int    // Number of external ids created before the failure
+	ParentAdded        bool
+	FailedStep         Step
+	Error              *generic.Error
+}
+
+func (r Result) Failed() bool {
+	return r.Error != nil
+}
+
+// The results in the order of the provisioned items.
+type Report struct {
+	Results []Result
+}
+
+// Returns the number of completely provisioned items.
+func (r *Report) Succeeded() int {
+	return len(r.Results) - len(r.Failures())
+}
+
+// Returns the results of the failed items.
+func (r *Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if result.Failed() {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+/*
+Returns the failed items for a retry. Completed steps are not repeated: Created managed objects
+are updated instead and external ids, which were already created, are left out.
+*/
+func (r *Report) RetryItems() []Item {
+	var items []Item
+	for _, result := range r.Failures() {
+		item := result.Item
+		if len(result.ManagedObjectId) > 0 {
+			item.Id = result.ManagedObjectId
+		}
+		item.ExternalIds = item.ExternalIds[result.CreatedExternalIds:]
+		items = append(items, item)
+	}
+	return items
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%d of %d items provisioned, %d failed", r.Succeeded(), len(r.Results), len(r.Failures()))
+}
+
+/*
+Provisioner creates or updates many managed objects concurrently, creates their external ids and
+adds them to their parents. A failing item does not stop the provisioning of the other items.
+*/
+type Provisioner struct {
+	inventoryApi inventory.InventoryApi
+	referenceApi inventory.InventoryReferenceApi
+	identityApi  identity.IdentityAPI
+	concurrency  int
+}
+
+// Creates a new provisioner.
+// concurrency - number of items provisioned in parallel. Defaults to DEFAULT_CONCURRENCY, if not positive.
+func NewProvisioner(inventoryApi inventory.InventoryApi, referenceApi inventory.InventoryReferenceApi, identityApi identity.IdentityAPI, concurrency int) *Provisioner {
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+	return &Provisioner{inventoryApi: inventoryApi, referenceApi: referenceApi, identityApi: identityApi, concurrency: concurrency}
+}
+
+// Provisions all items and returns a result per item.
+func (p *Provisioner) Provision(items []Item) *Report {
+	report := &Report{Results: make([]Result, len(items))}
+
+	semaphore := make(chan struct{}, p.concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, item Item) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			report.Results[i] = p.provision(item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// -- internal
+
+func (p *Provisioner) provision(item Item) Result {
+	result := Result{Item: item}
+
+	managedObjectId, step, err := p.createOrUpdate(item)
+	if err != nil {
+		result.FailedStep, result.Error = step, err
+		return result
+	}
+	result.ManagedObjectId = managedObjectId
+
+	for _, externalId := range item.ExternalIds {
+		if _, err := p.identityApi.CreateExternalID(externalId, managedObjectId); err != nil {
+			result.FailedStep, result.Error = EXTERNAL_ID, err
+			return result
+		}
+		result.CreatedExternalIds++
+	}
+
+	if len(item.ParentId) > 0 {
+		referenceType := item.ParentReferenceType
+		if len(referenceType) == 0 {
+			referenceType = inventory.CHILD_DEVICES
+		}
+		if _, err := p.referenceApi.Create(item.ParentId, referenceType, managedObjectId); err != nil {
+			result.FailedStep, result.Error = PARENT, err
+			return result
+		}
+		result.ParentAdded = true
+	}
+	return result
+}
+
+// Returns the id of the created or updated managed object.
+func (p *Provisioner) createOrUpdate(item Item) (string, Step, *generic.Error) {
+	if len(item.Id) == 0 {
+		managedObject, err := p.inventoryApi.Create(&item.ManagedObject)
+		if err != nil {
+			return "", CREATE, err
+		}
+		return managedObject.Id, CREATE, nil
+	}
+
+	fields := map[string]interface{}{}
+	for name, value := range item.ManagedObject.AdditionalFields {
+		fields[name] = value
+	}
+	if item.ManagedObject.C8y_IsDevice != nil {
+		fields["c8y_IsDevice"] = item.ManagedObject.C8y_IsDevice
+	}
+
+	update := &inventory.ManagedObjectUpdate{Type: item.ManagedObject.Type, Name: item.ManagedObject.Name, AdditionalFields: fields}
+	if _, err := p.inventoryApi.Update(item.Id, update); err != nil {
+		return "", UPDATE, err
+	}
+	return item.Id, UPDATE, nil
+}
@@ -0,0 +1,181 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tarent/gomulocity/generic"
+	"github.com/tarent/gomulocity/identity"
+	"github.com/tarent/gomulocity/inventory"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testTenant struct {
+	mutex          sync.Mutex
+	nextId         int
+	inFlight       int
+	maxInFlight    int
+	failingPaths   map[string]bool
+	failingSerials map[string]bool
+	requests       []string
+}
+
+// Creates managed objects with the ids 100, 101, ... and fails for the failing paths and external ids.
+func (tenant *testTenant) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	tenant.mutex.Lock()
+	tenant.inFlight++
+	if tenant.inFlight > tenant.maxInFlight {
+		tenant.maxInFlight = tenant.inFlight
+	}
+	tenant.requests = append(tenant.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+	id := tenant.nextId
+	if r.Method == http.MethodPost && r.URL.Path == "/inventory/managedObjects" {
+		tenant.nextId++
+	}
+	var externalId identity.NewExternalID
+	_ = json.Unmarshal(body, &externalId)
+	failing := tenant.failingPaths[r.URL.Path] || tenant.failingSerials[externalId.ExternalId]
+	tenant.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	tenant.mutex.Lock()
+	tenant.inFlight--
+	tenant.mutex.Unlock()
+
+	switch {
+	case failing:
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error": "identity/Conflict", "message": "conflict"}`))
+	case r.Method == http.MethodPut:
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"id": "%d"}`, id)
+	}
+}
+
+func buildProvisioner(tenant *testTenant, concurrency int) (*Provisioner, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(tenant.serve))
+	client := &generic.Client{HTTPClient: http.DefaultClient, BaseURL: ts.URL, Username: "foo", Password: "bar"}
+	return NewProvisioner(inventory.NewInventoryApi(client), inventory.NewInventoryReferenceApi(client), identity.NewIdentityAPI(client), concurrency), ts
+}
+
+func buildItems(count int) []Item {
+	items := make([]Item, count)
+	for i := range items {
+		serial := fmt.Sprintf("sn-%d", i)
+		items[i] = Item{
+			ManagedObject: inventory.NewManagedObject{Name: serial, C8y_IsDevice: map[string]interface{}{}},
+			ExternalIds:   []identity.NewExternalID{{ExternalId: serial, Type: "c8y_Serial"}},
+			ParentId:      "1",
+		}
+	}
+	return items
+}
+
+func TestProvisioner_Provision(t *testing.T) {
+	// given
+	tenant := &testTenant{nextId: 100}
+	provisioner, ts := buildProvisioner(tenant, 4)
+	defer ts.Close()
+
+	// when
+	report := provisioner.Provision(buildItems(20))
+
+	// then
+	if report.Succeeded() != 20 || len(report.Failures()) != 0 {
+		t.Errorf("Provision() = %s", report)
+	}
+	if len(tenant.requests) != 60 {
+		t.Errorf("Provision() sent %d requests, want 60", len(tenant.requests))
+	}
+	if tenant.maxInFlight > 4 || tenant.maxInFlight < 2 {
+		t.Errorf("Provision() sent %d requests in parallel, want 2 to 4", tenant.maxInFlight)
+	}
+
+	ids := map[string]bool{}
+	for i, result := range report.Results {
+		if result.Item.ManagedObject.Name != fmt.Sprintf("sn-%d", i) || !result.ParentAdded || result.CreatedExternalIds != 1 {
+			t.Errorf("Result %d = %v", i, result)
+		}
+		ids[result.ManagedObjectId] = true
+	}
+	if len(ids) != 20 {
+		t.Errorf("Provision() created %d different managed objects, want 20", len(ids))
+	}
+}
+
+func TestProvisioner_Provision_RetriesFailures(t *testing.T) {
+	// given: a tenant failing to create the external id sn-1 and to add sn-2 to the parent 999
+	tenant := &testTenant{
+		nextId:         100,
+		failingPaths:   map[string]bool{"/inventory/managedObjects/999/childDevices": true},
+		failingSerials: map[string]bool{"sn-1": true},
+	}
+	provisioner, ts := buildProvisioner(tenant, 1)
+	defer ts.Close()
+	items := buildItems(3)
+	items[2].ParentId = "999"
+
+	// when
+	report := provisioner.Provision(items)
+
+	// then
+	if report.String() != "1 of 3 items provisioned, 2 failed" {
+		t.Errorf("Provision() = %s", report)
+	}
+	failures := report.Failures()
+	if failures[0].FailedStep != EXTERNAL_ID || failures[0].ManagedObjectId != "101" || failures[0].Error.ErrorType != "409: identity/Conflict" {
+		t.Errorf("Failure of sn-1 = %v", failures[0])
+	}
+	if failures[1].FailedStep != PARENT || failures[1].CreatedExternalIds != 1 || failures[1].ParentAdded {
+		t.Errorf("Failure of sn-2 = %v", failures[1])
+	}
+
+	// when: the failures are retried
+	tenant.failingPaths, tenant.failingSerials, tenant.requests = nil, nil, nil
+	retry := provisioner.Provision(report.RetryItems())
+
+	// then: the created managed objects are updated and only the missing steps are repeated
+	if retry.Succeeded() != 2 {
+		t.Errorf("Provision() of retry items = %s", retry)
+	}
+	sort.Strings(tenant.requests)
+	expected := []string{
+		`POST /identity/globalIds/101/externalIds {"externalId":"sn-1","type":"c8y_Serial"}`,
+		`POST /inventory/managedObjects/1/childDevices {"managedObject":{"id":"101"}}`,
+		`POST /inventory/managedObjects/999/childDevices {"managedObject":{"id":"102"}}`,
+		`PUT /inventory/managedObjects/101 {"c8y_IsDevice":{},"name":"sn-1"}`,
+		`PUT /inventory/managedObjects/102 {"c8y_IsDevice":{},"name":"sn-2"}`,
+	}
+	if strings.Join(tenant.requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Requests = \n%s\nwant\n%s", strings.Join(tenant.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestProvisioner_Provision_CreateFailure(t *testing.T) {
+	tenant := &testTenant{nextId: 100, failingPaths: map[string]bool{"/inventory/managedObjects": true}}
+	provisioner, ts := buildProvisioner(tenant, 0)
+	defer ts.Close()
+
+	report := provisioner.Provision(buildItems(2))
+
+	if len(report.Failures()) != 2 || report.Results[0].FailedStep != CREATE || len(report.Results[0].ManagedObjectId) != 0 {
+		t.Errorf("Provision() = %v", report.Results)
+	}
+	if retryItems := report.RetryItems(); len(retryItems) != 2 || len(retryItems[0].Id) != 0 || len(retryItems[0].ExternalIds) != 1 {
+		t.Errorf("RetryItems() = %v, want the unchanged items", retryItems)
+	}
+	if len(tenant.requests) != 2 {
+		t.Errorf("Provision() sent %v, want the creations only", tenant.requests)
+	}
+}
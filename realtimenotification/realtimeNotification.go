@@ -388,3 +388,18 @@ func withBufferLength(bufferlength int) APIOption {
 		api.bufferLength = bufferlength
 	}
 }
+
+// Notification is the payload of a message received on a subscribed channel, ex. a created measurement on "/measurements/*".
+type Notification struct {
+	RealtimeAction string          `json:"realtimeAction"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// ParseNotification parses a message of ResponseFromPolling into a Notification.
+func ParseNotification(message json.RawMessage) (*Notification, error) {
+	notification := Notification{}
+	if err := json.Unmarshal(message, &notification); err != nil {
+		return nil, fmt.Errorf("error while parsing realtime notification: %s", err.Error())
+	}
+	return &notification, nil
+}
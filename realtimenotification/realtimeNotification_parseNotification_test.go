@@ -0,0 +1,25 @@
+package realtimenotification
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RealtimeNotification_parseNotification(t *testing.T) {
+	message := json.RawMessage(`{"realtimeAction":"CREATE","data":{"id":"4711","type":"c8y_TemperatureMeasurement"}}`)
+
+	notification, err := ParseNotification(message)
+
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE", notification.RealtimeAction)
+	assert.JSONEq(t, `{"id":"4711","type":"c8y_TemperatureMeasurement"}`, string(notification.Data))
+}
+
+func Test_RealtimeNotification_parseNotification_invalid(t *testing.T) {
+	_, err := ParseNotification(json.RawMessage(`[1, 2]`))
+
+	assert.Error(t, err)
+}